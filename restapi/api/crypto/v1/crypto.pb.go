@@ -0,0 +1,706 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: crypto/v1/crypto.proto
+
+package cryptov1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CryptoEvent_Type int32
+
+const (
+	CryptoEvent_TYPE_UNSPECIFIED CryptoEvent_Type = 0
+	CryptoEvent_TYPE_CREATED     CryptoEvent_Type = 1
+	CryptoEvent_TYPE_UPDATED     CryptoEvent_Type = 2
+	CryptoEvent_TYPE_DELETED     CryptoEvent_Type = 3
+)
+
+// Enum value maps for CryptoEvent_Type.
+var (
+	CryptoEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "TYPE_CREATED",
+		2: "TYPE_UPDATED",
+		3: "TYPE_DELETED",
+	}
+	CryptoEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"TYPE_CREATED":     1,
+		"TYPE_UPDATED":     2,
+		"TYPE_DELETED":     3,
+	}
+)
+
+func (x CryptoEvent_Type) Enum() *CryptoEvent_Type {
+	p := new(CryptoEvent_Type)
+	*p = x
+	return p
+}
+
+func (x CryptoEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CryptoEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_crypto_v1_crypto_proto_enumTypes[0].Descriptor()
+}
+
+func (CryptoEvent_Type) Type() protoreflect.EnumType {
+	return &file_crypto_v1_crypto_proto_enumTypes[0]
+}
+
+func (x CryptoEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CryptoEvent_Type.Descriptor instead.
+func (CryptoEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{9, 0}
+}
+
+// Author of a cryptocurrency
+type Author struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Firstname     string                 `protobuf:"bytes,1,opt,name=firstname,proto3" json:"firstname,omitempty"`
+	Lastname      string                 `protobuf:"bytes,2,opt,name=lastname,proto3" json:"lastname,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Author) Reset() {
+	*x = Author{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Author) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Author) ProtoMessage() {}
+
+func (x *Author) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Author.ProtoReflect.Descriptor instead.
+func (*Author) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Author) GetFirstname() string {
+	if x != nil {
+		return x.Firstname
+	}
+	return ""
+}
+
+func (x *Author) GetLastname() string {
+	if x != nil {
+		return x.Lastname
+	}
+	return ""
+}
+
+// Cryptocurrency with its name, crypto_id, current price and authors/innovators
+type Cryptocurrency struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Name     string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	CryptoId string                 `protobuf:"bytes,2,opt,name=crypto_id,json=cryptoId,proto3" json:"crypto_id,omitempty"`
+	// Approximation of exact_price, used only when exact_price is empty
+	//
+	// Deprecated: Marked as deprecated in crypto/v1/crypto.proto.
+	Price   float64   `protobuf:"fixed64,3,opt,name=price,proto3" json:"price,omitempty"`
+	Authors []*Author `protobuf:"bytes,4,rep,name=authors,proto3" json:"authors,omitempty"`
+	// Exact decimal price, e.g. "0.00000001"
+	ExactPrice    string `protobuf:"bytes,5,opt,name=exact_price,json=exactPrice,proto3" json:"exact_price,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Cryptocurrency) Reset() {
+	*x = Cryptocurrency{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Cryptocurrency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Cryptocurrency) ProtoMessage() {}
+
+func (x *Cryptocurrency) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Cryptocurrency.ProtoReflect.Descriptor instead.
+func (*Cryptocurrency) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Cryptocurrency) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Cryptocurrency) GetCryptoId() string {
+	if x != nil {
+		return x.CryptoId
+	}
+	return ""
+}
+
+// Deprecated: Marked as deprecated in crypto/v1/crypto.proto.
+func (x *Cryptocurrency) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Cryptocurrency) GetAuthors() []*Author {
+	if x != nil {
+		return x.Authors
+	}
+	return nil
+}
+
+func (x *Cryptocurrency) GetExactPrice() string {
+	if x != nil {
+		return x.ExactPrice
+	}
+	return ""
+}
+
+type GetCryptoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CryptoId      string                 `protobuf:"bytes,1,opt,name=crypto_id,json=cryptoId,proto3" json:"crypto_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetCryptoRequest) Reset() {
+	*x = GetCryptoRequest{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetCryptoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetCryptoRequest) ProtoMessage() {}
+
+func (x *GetCryptoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetCryptoRequest.ProtoReflect.Descriptor instead.
+func (*GetCryptoRequest) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetCryptoRequest) GetCryptoId() string {
+	if x != nil {
+		return x.CryptoId
+	}
+	return ""
+}
+
+type ListCryptosRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListCryptosRequest) Reset() {
+	*x = ListCryptosRequest{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListCryptosRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListCryptosRequest) ProtoMessage() {}
+
+func (x *ListCryptosRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListCryptosRequest.ProtoReflect.Descriptor instead.
+func (*ListCryptosRequest) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{3}
+}
+
+type CreateCryptoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Crypto        *Cryptocurrency        `protobuf:"bytes,1,opt,name=crypto,proto3" json:"crypto,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateCryptoRequest) Reset() {
+	*x = CreateCryptoRequest{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateCryptoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateCryptoRequest) ProtoMessage() {}
+
+func (x *CreateCryptoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateCryptoRequest.ProtoReflect.Descriptor instead.
+func (*CreateCryptoRequest) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateCryptoRequest) GetCrypto() *Cryptocurrency {
+	if x != nil {
+		return x.Crypto
+	}
+	return nil
+}
+
+type UpdateCryptoRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// crypto_id of the cryptocurrency before the update, crypto may carry a new one
+	CryptoId      string          `protobuf:"bytes,1,opt,name=crypto_id,json=cryptoId,proto3" json:"crypto_id,omitempty"`
+	Crypto        *Cryptocurrency `protobuf:"bytes,2,opt,name=crypto,proto3" json:"crypto,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateCryptoRequest) Reset() {
+	*x = UpdateCryptoRequest{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateCryptoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateCryptoRequest) ProtoMessage() {}
+
+func (x *UpdateCryptoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateCryptoRequest.ProtoReflect.Descriptor instead.
+func (*UpdateCryptoRequest) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateCryptoRequest) GetCryptoId() string {
+	if x != nil {
+		return x.CryptoId
+	}
+	return ""
+}
+
+func (x *UpdateCryptoRequest) GetCrypto() *Cryptocurrency {
+	if x != nil {
+		return x.Crypto
+	}
+	return nil
+}
+
+type DeleteCryptoRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CryptoId      string                 `protobuf:"bytes,1,opt,name=crypto_id,json=cryptoId,proto3" json:"crypto_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteCryptoRequest) Reset() {
+	*x = DeleteCryptoRequest{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCryptoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCryptoRequest) ProtoMessage() {}
+
+func (x *DeleteCryptoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCryptoRequest.ProtoReflect.Descriptor instead.
+func (*DeleteCryptoRequest) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteCryptoRequest) GetCryptoId() string {
+	if x != nil {
+		return x.CryptoId
+	}
+	return ""
+}
+
+type DeleteCryptoResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteCryptoResponse) Reset() {
+	*x = DeleteCryptoResponse{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteCryptoResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCryptoResponse) ProtoMessage() {}
+
+func (x *DeleteCryptoResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCryptoResponse.ProtoReflect.Descriptor instead.
+func (*DeleteCryptoResponse) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{7}
+}
+
+type WatchCryptosRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only events for this crypto_id are sent when set
+	CryptoId      string `protobuf:"bytes,1,opt,name=crypto_id,json=cryptoId,proto3" json:"crypto_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchCryptosRequest) Reset() {
+	*x = WatchCryptosRequest{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchCryptosRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchCryptosRequest) ProtoMessage() {}
+
+func (x *WatchCryptosRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchCryptosRequest.ProtoReflect.Descriptor instead.
+func (*WatchCryptosRequest) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchCryptosRequest) GetCryptoId() string {
+	if x != nil {
+		return x.CryptoId
+	}
+	return ""
+}
+
+// CryptoEvent describes a change made to a cryptocurrency
+type CryptoEvent struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Type     CryptoEvent_Type       `protobuf:"varint,1,opt,name=type,proto3,enum=crypto.v1.CryptoEvent_Type" json:"type,omitempty"`
+	CryptoId string                 `protobuf:"bytes,2,opt,name=crypto_id,json=cryptoId,proto3" json:"crypto_id,omitempty"`
+	// Set for updates which renamed the cryptocurrency
+	OldCryptoId string `protobuf:"bytes,3,opt,name=old_crypto_id,json=oldCryptoId,proto3" json:"old_crypto_id,omitempty"`
+	// Not set for deletions
+	Crypto        *Cryptocurrency `protobuf:"bytes,4,opt,name=crypto,proto3" json:"crypto,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CryptoEvent) Reset() {
+	*x = CryptoEvent{}
+	mi := &file_crypto_v1_crypto_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CryptoEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CryptoEvent) ProtoMessage() {}
+
+func (x *CryptoEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_crypto_v1_crypto_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CryptoEvent.ProtoReflect.Descriptor instead.
+func (*CryptoEvent) Descriptor() ([]byte, []int) {
+	return file_crypto_v1_crypto_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *CryptoEvent) GetType() CryptoEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return CryptoEvent_TYPE_UNSPECIFIED
+}
+
+func (x *CryptoEvent) GetCryptoId() string {
+	if x != nil {
+		return x.CryptoId
+	}
+	return ""
+}
+
+func (x *CryptoEvent) GetOldCryptoId() string {
+	if x != nil {
+		return x.OldCryptoId
+	}
+	return ""
+}
+
+func (x *CryptoEvent) GetCrypto() *Cryptocurrency {
+	if x != nil {
+		return x.Crypto
+	}
+	return nil
+}
+
+var File_crypto_v1_crypto_proto protoreflect.FileDescriptor
+
+const file_crypto_v1_crypto_proto_rawDesc = "" +
+	"\n" +
+	"\x16crypto/v1/crypto.proto\x12\tcrypto.v1\"B\n" +
+	"\x06Author\x12\x1c\n" +
+	"\tfirstname\x18\x01 \x01(\tR\tfirstname\x12\x1a\n" +
+	"\blastname\x18\x02 \x01(\tR\blastname\"\xa9\x01\n" +
+	"\x0eCryptocurrency\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1b\n" +
+	"\tcrypto_id\x18\x02 \x01(\tR\bcryptoId\x12\x18\n" +
+	"\x05price\x18\x03 \x01(\x01B\x02\x18\x01R\x05price\x12+\n" +
+	"\aauthors\x18\x04 \x03(\v2\x11.crypto.v1.AuthorR\aauthors\x12\x1f\n" +
+	"\vexact_price\x18\x05 \x01(\tR\n" +
+	"exactPrice\"/\n" +
+	"\x10GetCryptoRequest\x12\x1b\n" +
+	"\tcrypto_id\x18\x01 \x01(\tR\bcryptoId\"\x14\n" +
+	"\x12ListCryptosRequest\"H\n" +
+	"\x13CreateCryptoRequest\x121\n" +
+	"\x06crypto\x18\x01 \x01(\v2\x19.crypto.v1.CryptocurrencyR\x06crypto\"e\n" +
+	"\x13UpdateCryptoRequest\x12\x1b\n" +
+	"\tcrypto_id\x18\x01 \x01(\tR\bcryptoId\x121\n" +
+	"\x06crypto\x18\x02 \x01(\v2\x19.crypto.v1.CryptocurrencyR\x06crypto\"2\n" +
+	"\x13DeleteCryptoRequest\x12\x1b\n" +
+	"\tcrypto_id\x18\x01 \x01(\tR\bcryptoId\"\x16\n" +
+	"\x14DeleteCryptoResponse\"2\n" +
+	"\x13WatchCryptosRequest\x12\x1b\n" +
+	"\tcrypto_id\x18\x01 \x01(\tR\bcryptoId\"\x86\x02\n" +
+	"\vCryptoEvent\x12/\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x1b.crypto.v1.CryptoEvent.TypeR\x04type\x12\x1b\n" +
+	"\tcrypto_id\x18\x02 \x01(\tR\bcryptoId\x12\"\n" +
+	"\rold_crypto_id\x18\x03 \x01(\tR\voldCryptoId\x121\n" +
+	"\x06crypto\x18\x04 \x01(\v2\x19.crypto.v1.CryptocurrencyR\x06crypto\"R\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fTYPE_CREATED\x10\x01\x12\x10\n" +
+	"\fTYPE_UPDATED\x10\x02\x12\x10\n" +
+	"\fTYPE_DELETED\x10\x032\xaa\x03\n" +
+	"\rCryptoService\x12=\n" +
+	"\x03Get\x12\x1b.crypto.v1.GetCryptoRequest\x1a\x19.crypto.v1.Cryptocurrency\x12B\n" +
+	"\x04List\x12\x1d.crypto.v1.ListCryptosRequest\x1a\x19.crypto.v1.Cryptocurrency0\x01\x12C\n" +
+	"\x06Create\x12\x1e.crypto.v1.CreateCryptoRequest\x1a\x19.crypto.v1.Cryptocurrency\x12C\n" +
+	"\x06Update\x12\x1e.crypto.v1.UpdateCryptoRequest\x1a\x19.crypto.v1.Cryptocurrency\x12I\n" +
+	"\x06Delete\x12\x1e.crypto.v1.DeleteCryptoRequest\x1a\x1f.crypto.v1.DeleteCryptoResponse\x12A\n" +
+	"\x05Watch\x12\x1e.crypto.v1.WatchCryptosRequest\x1a\x16.crypto.v1.CryptoEvent0\x01Bh\n" +
+	"$com.github.la4ezar.restapi.crypto.v1B\vCryptoProtoP\x01Z1github.com/la4ezar/restapi/api/crypto/v1;cryptov1b\x06proto3"
+
+var (
+	file_crypto_v1_crypto_proto_rawDescOnce sync.Once
+	file_crypto_v1_crypto_proto_rawDescData []byte
+)
+
+func file_crypto_v1_crypto_proto_rawDescGZIP() []byte {
+	file_crypto_v1_crypto_proto_rawDescOnce.Do(func() {
+		file_crypto_v1_crypto_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_crypto_v1_crypto_proto_rawDesc), len(file_crypto_v1_crypto_proto_rawDesc)))
+	})
+	return file_crypto_v1_crypto_proto_rawDescData
+}
+
+var file_crypto_v1_crypto_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_crypto_v1_crypto_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_crypto_v1_crypto_proto_goTypes = []any{
+	(CryptoEvent_Type)(0),        // 0: crypto.v1.CryptoEvent.Type
+	(*Author)(nil),               // 1: crypto.v1.Author
+	(*Cryptocurrency)(nil),       // 2: crypto.v1.Cryptocurrency
+	(*GetCryptoRequest)(nil),     // 3: crypto.v1.GetCryptoRequest
+	(*ListCryptosRequest)(nil),   // 4: crypto.v1.ListCryptosRequest
+	(*CreateCryptoRequest)(nil),  // 5: crypto.v1.CreateCryptoRequest
+	(*UpdateCryptoRequest)(nil),  // 6: crypto.v1.UpdateCryptoRequest
+	(*DeleteCryptoRequest)(nil),  // 7: crypto.v1.DeleteCryptoRequest
+	(*DeleteCryptoResponse)(nil), // 8: crypto.v1.DeleteCryptoResponse
+	(*WatchCryptosRequest)(nil),  // 9: crypto.v1.WatchCryptosRequest
+	(*CryptoEvent)(nil),          // 10: crypto.v1.CryptoEvent
+}
+var file_crypto_v1_crypto_proto_depIdxs = []int32{
+	1,  // 0: crypto.v1.Cryptocurrency.authors:type_name -> crypto.v1.Author
+	2,  // 1: crypto.v1.CreateCryptoRequest.crypto:type_name -> crypto.v1.Cryptocurrency
+	2,  // 2: crypto.v1.UpdateCryptoRequest.crypto:type_name -> crypto.v1.Cryptocurrency
+	0,  // 3: crypto.v1.CryptoEvent.type:type_name -> crypto.v1.CryptoEvent.Type
+	2,  // 4: crypto.v1.CryptoEvent.crypto:type_name -> crypto.v1.Cryptocurrency
+	3,  // 5: crypto.v1.CryptoService.Get:input_type -> crypto.v1.GetCryptoRequest
+	4,  // 6: crypto.v1.CryptoService.List:input_type -> crypto.v1.ListCryptosRequest
+	5,  // 7: crypto.v1.CryptoService.Create:input_type -> crypto.v1.CreateCryptoRequest
+	6,  // 8: crypto.v1.CryptoService.Update:input_type -> crypto.v1.UpdateCryptoRequest
+	7,  // 9: crypto.v1.CryptoService.Delete:input_type -> crypto.v1.DeleteCryptoRequest
+	9,  // 10: crypto.v1.CryptoService.Watch:input_type -> crypto.v1.WatchCryptosRequest
+	2,  // 11: crypto.v1.CryptoService.Get:output_type -> crypto.v1.Cryptocurrency
+	2,  // 12: crypto.v1.CryptoService.List:output_type -> crypto.v1.Cryptocurrency
+	2,  // 13: crypto.v1.CryptoService.Create:output_type -> crypto.v1.Cryptocurrency
+	2,  // 14: crypto.v1.CryptoService.Update:output_type -> crypto.v1.Cryptocurrency
+	8,  // 15: crypto.v1.CryptoService.Delete:output_type -> crypto.v1.DeleteCryptoResponse
+	10, // 16: crypto.v1.CryptoService.Watch:output_type -> crypto.v1.CryptoEvent
+	11, // [11:17] is the sub-list for method output_type
+	5,  // [5:11] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_crypto_v1_crypto_proto_init() }
+func file_crypto_v1_crypto_proto_init() {
+	if File_crypto_v1_crypto_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_crypto_v1_crypto_proto_rawDesc), len(file_crypto_v1_crypto_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_crypto_v1_crypto_proto_goTypes,
+		DependencyIndexes: file_crypto_v1_crypto_proto_depIdxs,
+		EnumInfos:         file_crypto_v1_crypto_proto_enumTypes,
+		MessageInfos:      file_crypto_v1_crypto_proto_msgTypes,
+	}.Build()
+	File_crypto_v1_crypto_proto = out.File
+	file_crypto_v1_crypto_proto_goTypes = nil
+	file_crypto_v1_crypto_proto_depIdxs = nil
+}
@@ -0,0 +1,322 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: crypto/v1/crypto.proto
+
+package cryptov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CryptoService_Get_FullMethodName    = "/crypto.v1.CryptoService/Get"
+	CryptoService_List_FullMethodName   = "/crypto.v1.CryptoService/List"
+	CryptoService_Create_FullMethodName = "/crypto.v1.CryptoService/Create"
+	CryptoService_Update_FullMethodName = "/crypto.v1.CryptoService/Update"
+	CryptoService_Delete_FullMethodName = "/crypto.v1.CryptoService/Delete"
+	CryptoService_Watch_FullMethodName  = "/crypto.v1.CryptoService/Watch"
+)
+
+// CryptoServiceClient is the client API for CryptoService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// CryptoService mirrors the cryptocurrency REST API
+type CryptoServiceClient interface {
+	Get(ctx context.Context, in *GetCryptoRequest, opts ...grpc.CallOption) (*Cryptocurrency, error)
+	List(ctx context.Context, in *ListCryptosRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Cryptocurrency], error)
+	Create(ctx context.Context, in *CreateCryptoRequest, opts ...grpc.CallOption) (*Cryptocurrency, error)
+	Update(ctx context.Context, in *UpdateCryptoRequest, opts ...grpc.CallOption) (*Cryptocurrency, error)
+	Delete(ctx context.Context, in *DeleteCryptoRequest, opts ...grpc.CallOption) (*DeleteCryptoResponse, error)
+	Watch(ctx context.Context, in *WatchCryptosRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CryptoEvent], error)
+}
+
+type cryptoServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCryptoServiceClient(cc grpc.ClientConnInterface) CryptoServiceClient {
+	return &cryptoServiceClient{cc}
+}
+
+func (c *cryptoServiceClient) Get(ctx context.Context, in *GetCryptoRequest, opts ...grpc.CallOption) (*Cryptocurrency, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cryptocurrency)
+	err := c.cc.Invoke(ctx, CryptoService_Get_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoServiceClient) List(ctx context.Context, in *ListCryptosRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Cryptocurrency], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CryptoService_ServiceDesc.Streams[0], CryptoService_List_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListCryptosRequest, Cryptocurrency]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CryptoService_ListClient = grpc.ServerStreamingClient[Cryptocurrency]
+
+func (c *cryptoServiceClient) Create(ctx context.Context, in *CreateCryptoRequest, opts ...grpc.CallOption) (*Cryptocurrency, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cryptocurrency)
+	err := c.cc.Invoke(ctx, CryptoService_Create_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoServiceClient) Update(ctx context.Context, in *UpdateCryptoRequest, opts ...grpc.CallOption) (*Cryptocurrency, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Cryptocurrency)
+	err := c.cc.Invoke(ctx, CryptoService_Update_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoServiceClient) Delete(ctx context.Context, in *DeleteCryptoRequest, opts ...grpc.CallOption) (*DeleteCryptoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteCryptoResponse)
+	err := c.cc.Invoke(ctx, CryptoService_Delete_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cryptoServiceClient) Watch(ctx context.Context, in *WatchCryptosRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[CryptoEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &CryptoService_ServiceDesc.Streams[1], CryptoService_Watch_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchCryptosRequest, CryptoEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CryptoService_WatchClient = grpc.ServerStreamingClient[CryptoEvent]
+
+// CryptoServiceServer is the server API for CryptoService service.
+// All implementations must embed UnimplementedCryptoServiceServer
+// for forward compatibility.
+//
+// CryptoService mirrors the cryptocurrency REST API
+type CryptoServiceServer interface {
+	Get(context.Context, *GetCryptoRequest) (*Cryptocurrency, error)
+	List(*ListCryptosRequest, grpc.ServerStreamingServer[Cryptocurrency]) error
+	Create(context.Context, *CreateCryptoRequest) (*Cryptocurrency, error)
+	Update(context.Context, *UpdateCryptoRequest) (*Cryptocurrency, error)
+	Delete(context.Context, *DeleteCryptoRequest) (*DeleteCryptoResponse, error)
+	Watch(*WatchCryptosRequest, grpc.ServerStreamingServer[CryptoEvent]) error
+	mustEmbedUnimplementedCryptoServiceServer()
+}
+
+// UnimplementedCryptoServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCryptoServiceServer struct{}
+
+func (UnimplementedCryptoServiceServer) Get(context.Context, *GetCryptoRequest) (*Cryptocurrency, error) {
+	return nil, status.Error(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedCryptoServiceServer) List(*ListCryptosRequest, grpc.ServerStreamingServer[Cryptocurrency]) error {
+	return status.Error(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedCryptoServiceServer) Create(context.Context, *CreateCryptoRequest) (*Cryptocurrency, error) {
+	return nil, status.Error(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedCryptoServiceServer) Update(context.Context, *UpdateCryptoRequest) (*Cryptocurrency, error) {
+	return nil, status.Error(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedCryptoServiceServer) Delete(context.Context, *DeleteCryptoRequest) (*DeleteCryptoResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedCryptoServiceServer) Watch(*WatchCryptosRequest, grpc.ServerStreamingServer[CryptoEvent]) error {
+	return status.Error(codes.Unimplemented, "method Watch not implemented")
+}
+func (UnimplementedCryptoServiceServer) mustEmbedUnimplementedCryptoServiceServer() {}
+func (UnimplementedCryptoServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeCryptoServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CryptoServiceServer will
+// result in compilation errors.
+type UnsafeCryptoServiceServer interface {
+	mustEmbedUnimplementedCryptoServiceServer()
+}
+
+func RegisterCryptoServiceServer(s grpc.ServiceRegistrar, srv CryptoServiceServer) {
+	// If the following call panics, it indicates UnimplementedCryptoServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CryptoService_ServiceDesc, srv)
+}
+
+func _CryptoService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCryptoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoServiceServer).Get(ctx, req.(*GetCryptoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoService_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListCryptosRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CryptoServiceServer).List(m, &grpc.GenericServerStream[ListCryptosRequest, Cryptocurrency]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CryptoService_ListServer = grpc.ServerStreamingServer[Cryptocurrency]
+
+func _CryptoService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCryptoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoServiceServer).Create(ctx, req.(*CreateCryptoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCryptoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoServiceServer).Update(ctx, req.(*UpdateCryptoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCryptoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CryptoServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CryptoService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CryptoServiceServer).Delete(ctx, req.(*DeleteCryptoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CryptoService_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchCryptosRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CryptoServiceServer).Watch(m, &grpc.GenericServerStream[WatchCryptosRequest, CryptoEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type CryptoService_WatchServer = grpc.ServerStreamingServer[CryptoEvent]
+
+// CryptoService_ServiceDesc is the grpc.ServiceDesc for CryptoService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CryptoService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "crypto.v1.CryptoService",
+	HandlerType: (*CryptoServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _CryptoService_Get_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _CryptoService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _CryptoService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _CryptoService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			Handler:       _CryptoService_List_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _CryptoService_Watch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "crypto/v1/crypto.proto",
+}
@@ -2,23 +2,46 @@
 package main // import "github.com/la4ezar/restapi"
 
 import (
+	"context"
+	"errors"
+
 	"github.com/la4ezar/restapi/internal/config"
 	"github.com/la4ezar/restapi/pkg/client"
 	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/tracing"
+
+	"github.com/shopspring/decimal"
 )
 
 func main() {
 	cfg, err := config.NewDefaultClientConfig()
+	if errors.Is(err, config.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.D().WithError(err).Error()
+		log.D().WithError(err).Fatal()
 	}
 
 	if err := cfg.Validate(); err != nil {
 		log.D().WithError(err).Error()
 	}
 
+	shutdownTracing, err := tracing.Configure(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.D().WithError(err).Error()
+	} else {
+		defer func() {
+			if err := shutdownTracing(context.Background()); err != nil {
+				log.D().WithError(err).Error()
+			}
+		}()
+	}
+
 	// Init Client
-	c := client.New(cfg.Client)
+	c, err := client.New(cfg.Client)
+	if err != nil {
+		log.D().WithError(err).Fatal()
+	}
 
 	// Health check
 	c.HealthCheck()
@@ -32,7 +55,7 @@ func main() {
 	crypto := client.Cryptocurrency{
 		Name:     "LachoCoin",
 		CryptoID: "LCN",
-		Price:    1.54,
+		Price:    decimal.RequireFromString("1.54"),
 		Authors:  client.CryptoAuthors{{Firstname: "Lachezar", Lastname: "Bogomilov"}},
 	}
 	c.PostCrypto(crypto)
@@ -41,7 +64,7 @@ func main() {
 	updatedCrypto := client.Cryptocurrency{
 		Name:     "Bitcoin",
 		CryptoID: "BTC",
-		Price:    45000.3,
+		Price:    decimal.RequireFromString("45000.3"),
 		Authors:  client.CryptoAuthors{{Firstname: "Satoshi", Lastname: "Nakamoto"}},
 	}
 	c.PutCrypto(updatedCrypto)
@@ -5,13 +5,35 @@ import (
 	"flag"
 	"net/http"
 	"os"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/tlsutil"
 )
 
 func main() {
 	port := flag.String("port", "8080", "port on localhost to check")
+	useTLS := flag.Bool("tls", false, "whether the server serves HTTPS")
+	caFile := flag.String("cacert", "", "PEM encoded CA which signed the server certificate, system CAs if empty")
+	certFile := flag.String("cert", "", "PEM encoded client certificate for mTLS")
+	keyFile := flag.String("key", "", "PEM encoded client private key for mTLS")
+	serverName := flag.String("servername", "", "name to verify the server certificate against")
+	insecure := flag.Bool("insecure", false, "skip the server certificate verification")
 	flag.Parse()
 
-	resp, err := http.Get("http://localhost:" + *port + "/api/health")
+	scheme := "http"
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	if *useTLS {
+		tlsConfig, err := tlsutil.ClientConfig(*caFile, *certFile, *keyFile, *serverName, *insecure)
+		if err != nil {
+			os.Exit(1)
+		}
+
+		scheme = "https"
+		client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
+	resp, err := client.Get(scheme + "://localhost:" + *port + "/api/health")
 
 	if err != nil || resp.StatusCode != http.StatusOK {
 		os.Exit(1)
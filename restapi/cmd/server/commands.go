@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/la4ezar/restapi/internal/config"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// command runs a subcommand of the server with the rest of the command-line arguments
+type command func(args []string) error
+
+// commands are the subcommands of the server, the server is started when none is given
+var commands = map[string]command{
+	"config":  configCommand,
+	"migrate": migrateCommand,
+	"doctor":  doctorCommand,
+}
+
+// configCommand runs "config print", which prints the configuration merged from all the layers
+// with the source of every setting and fails if the configuration is invalid
+func configCommand(args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return fmt.Errorf("usage: server config print [flags]")
+	}
+
+	cfg, err := config.NewServerConfig(args[1:])
+	if errors.Is(err, config.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Print(os.Stdout); err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return fmt.Errorf("invalid configuration")
+	}
+
+	return nil
+}
+
+// migrateUsage describes the arguments of the migrate command
+const migrateUsage = "usage: server migrate up|down [N]|goto N|version|force N [flags]"
+
+// migrateCommand applies or rolls back the embedded migrations of the configured storage
+// with "migrate up|down [N]|goto N|version|force N". down rolls back one migration unless N is given.
+func migrateCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(migrateUsage)
+	}
+	action, args := args[0], args[1:]
+
+	var n int
+	switch action {
+	case "up", "version":
+	case "down", "goto", "force":
+		var ok bool
+		if n, args, ok = numberArg(args); !ok {
+			if action != "down" {
+				return fmt.Errorf(migrateUsage)
+			}
+			n = 1
+		}
+	default:
+		return fmt.Errorf(migrateUsage)
+	}
+
+	cfg, err := config.NewServerConfig(args)
+	if errors.Is(err, config.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, err := log.Configure(context.Background(), cfg.Logger)
+	if err != nil {
+		return err
+	}
+
+	migrator, err := storage.NewMigrator(ctx, cfg.Storage)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := migrator.Close(); err != nil {
+			log.C(ctx).WithError(err).Error()
+		}
+	}()
+
+	switch action {
+	case "up":
+		err = migrator.Up()
+	case "down":
+		err = migrator.Down(n)
+	case "goto":
+		if n < 0 {
+			return fmt.Errorf(migrateUsage)
+		}
+		err = migrator.Goto(uint(n))
+	case "force":
+		err = migrator.Force(n)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	if dirty {
+		fmt.Printf("%d (dirty)\n", version)
+		return nil
+	}
+	fmt.Println(version)
+	return nil
+}
+
+// numberArg returns the number which args start with and the rest of them
+func numberArg(args []string) (int, []string, bool) {
+	if len(args) == 0 {
+		return 0, args, false
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, args, false
+	}
+	return n, args[1:], true
+}
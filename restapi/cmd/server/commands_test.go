@@ -0,0 +1,37 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateCommand(t *testing.T) {
+	flags := []string{"--storage.type=sqlite", "--storage.sqlite.path=" + filepath.Join(t.TempDir(), "test.db")}
+
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{name: "no action", wantErr: true},
+		{name: "unknown action", args: []string{"sideways"}, wantErr: true},
+		{name: "goto without version", args: []string{"goto"}, wantErr: true},
+		{name: "goto negative version", args: append([]string{"goto", "-1"}, flags...), wantErr: true},
+		{name: "force without version", args: []string{"force"}, wantErr: true},
+		{name: "up", args: append([]string{"up"}, flags...)},
+		{name: "version", args: append([]string{"version"}, flags...)},
+		{name: "down", args: append([]string{"down"}, flags...)},
+		{name: "goto", args: append([]string{"goto", "1"}, flags...)},
+		{name: "down N", args: append([]string{"down", "1"}, flags...)},
+		{name: "force", args: append([]string{"force", "1"}, flags...)},
+		{name: "memory storage", args: []string{"up", "--storage.type=memory"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := migrateCommand(tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/config"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// doctorConnectTimeout bounds how long the doctor waits for the database, whatever the startup timeout is
+const doctorConnectTimeout = 10 * time.Second
+
+// Statuses of the doctor checks
+const (
+	statusPass = "pass"
+	statusFail = "fail"
+	statusSkip = "skip"
+)
+
+// check is the outcome of a single diagnostic of the doctor
+type check struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Details []string `json:"details,omitempty"`
+}
+
+// report is the outcome of all the diagnostics of the doctor
+type report struct {
+	Passed bool    `json:"passed"`
+	Checks []check `json:"checks"`
+}
+
+func (r *report) add(name, status string, details ...string) {
+	r.Checks = append(r.Checks, check{Name: name, Status: status, Details: details})
+	if status == statusFail {
+		r.Passed = false
+	}
+}
+
+// fail adds a failed check with the lines of err as details
+func (r *report) fail(name string, err error) {
+	r.add(name, statusFail, strings.Split(err.Error(), "\n")...)
+}
+
+// skip adds the checks which can't run
+func (r *report) skip(reason string, names ...string) {
+	for _, name := range names {
+		r.add(name, statusSkip, reason)
+	}
+}
+
+// doctorCommand runs "doctor [--format text|json]", which validates the configuration, connects to the storage
+// and compares its schema and privileges with the ones expected by the server. It fails if any check fails.
+func doctorCommand(args []string) error {
+	format, args, err := formatArg(args)
+	if err != nil {
+		return err
+	}
+
+	r := &report{Passed: true}
+	cfg, err := config.NewServerConfig(args)
+	if errors.Is(err, config.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		r.fail("config", err)
+		r.skip("the configuration couldn't be loaded", "connection", "migrations", "schema", "privileges")
+	} else {
+		diagnose(context.Background(), cfg, r)
+	}
+
+	if err := r.write(os.Stdout, format); err != nil {
+		return err
+	}
+	if !r.Passed {
+		return fmt.Errorf("doctor found failed checks")
+	}
+	return nil
+}
+
+// diagnose adds the checks of the configuration and its storage to r
+func diagnose(ctx context.Context, cfg *config.ServerConfig, r *report) {
+	if err := cfg.Validate(); err != nil {
+		r.fail("config", err)
+	} else {
+		r.add("config", statusPass)
+	}
+
+	c := *cfg.Storage
+	if c.Type == storage.TypeMemory {
+		r.skip("the memory storage has no database", "connection", "migrations", "schema", "privileges")
+		return
+	}
+
+	c.StartupTimeout = min(c.StartupTimeout, doctorConnectTimeout)
+	db, err := storage.Open(ctx, &c)
+	if err != nil {
+		r.fail("connection", err)
+		r.skip("the database is not reachable", "migrations", "schema", "privileges")
+		return
+	}
+	defer db.Close()
+	r.add("connection", statusPass, connectionDetails(&c))
+
+	version := checkMigrations(ctx, &c, db, r)
+
+	if c.Type != storage.TypePostgres {
+		r.skip(fmt.Sprintf("the %s storage is checked only by its migration version", c.Type), "schema", "privileges")
+		return
+	}
+	checkSchema(ctx, db, version, r)
+	checkPrivileges(ctx, db, c.AutoMigrate, r)
+}
+
+func connectionDetails(c *storage.Config) string {
+	if c.Type == storage.TypeSQLite {
+		return "sqlite " + c.SQLite.Path
+	}
+	return c.DataSource.String()
+}
+
+// checkMigrations compares the applied migration version with the latest embedded one and returns the applied one
+func checkMigrations(ctx context.Context, c *storage.Config, db *sql.DB, r *report) uint {
+	latest, err := storage.LatestSchemaVersion(c.Type)
+	if err != nil {
+		r.fail("migrations", err)
+		return 0
+	}
+	version, dirty, err := storage.SchemaVersion(ctx, c.Type, db)
+	if err != nil {
+		r.fail("migrations", err)
+		return 0
+	}
+
+	switch {
+	case dirty:
+		r.add("migrations", statusFail, fmt.Sprintf("migration %d failed and left the schema dirty, fix it and run server migrate force N", version))
+	case version < latest:
+		r.add("migrations", statusFail, fmt.Sprintf("version %d is applied but the latest is %d, run server migrate up", version, latest))
+	case version > latest:
+		r.add("migrations", statusFail, fmt.Sprintf("version %d is applied but this server knows migrations only up to %d", version, latest))
+	default:
+		r.add("migrations", statusPass, fmt.Sprintf("version %d", version))
+	}
+	return version
+}
+
+// checkSchema compares the live schema with the one expected after the applied migrations
+func checkSchema(ctx context.Context, db *sql.DB, version uint, r *report) {
+	live, err := storage.InspectSchema(ctx, db)
+	if err != nil {
+		r.fail("schema", err)
+		return
+	}
+
+	missing, unexpected := storage.ExpectedSchema(version).Diff(live)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		r.add("schema", statusPass, fmt.Sprintf("matches version %d", version))
+		return
+	}
+
+	var details []string
+	for _, element := range missing {
+		details = append(details, "missing "+element)
+	}
+	for _, element := range unexpected {
+		details = append(details, "unexpected "+element)
+	}
+	r.add("schema", statusFail, details...)
+}
+
+// checkPrivileges checks the privileges of the user, including the ones to migrate the schema if auto migrating
+func checkPrivileges(ctx context.Context, db *sql.DB, autoMigrate bool, r *report) {
+	missing, err := storage.MissingPrivileges(ctx, db, autoMigrate)
+	if err != nil {
+		r.fail("privileges", err)
+		return
+	}
+	if len(missing) != 0 {
+		for i := range missing {
+			missing[i] = "missing " + missing[i]
+		}
+		r.add("privileges", statusFail, missing...)
+		return
+	}
+	r.add("privileges", statusPass)
+}
+
+// write writes the report as text or JSON
+func (r *report) write(w io.Writer, format string) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+
+	for _, c := range r.Checks {
+		fmt.Fprintf(w, "%-4s  %s\n", strings.ToUpper(c.Status), c.Name)
+		for _, detail := range c.Details {
+			fmt.Fprintf(w, "      %s\n", detail)
+		}
+	}
+	if r.Passed {
+		_, err := fmt.Fprintln(w, "All checks passed.")
+		return err
+	}
+	_, err := fmt.Fprintln(w, "Some checks failed.")
+	return err
+}
+
+// formatArg returns the value of the --format flag of the doctor, text by default, and the rest of args
+func formatArg(args []string) (string, []string, error) {
+	format := "text"
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--format" && i+1 < len(args):
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--format="):
+			format = strings.TrimPrefix(args[i], "--format=")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+
+	if format != "text" && format != "json" {
+		return "", nil, fmt.Errorf("usage: server doctor [--format text|json] [flags]")
+	}
+	return format, rest, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/config"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// statuses returns the status of every check of the report by its name
+func statuses(r *report) map[string]string {
+	statuses := make(map[string]string)
+	for _, c := range r.Checks {
+		statuses[c.Name] = c.Status
+	}
+	return statuses
+}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name       string
+		modify     func(t *testing.T, cfg *config.ServerConfig)
+		wantPassed bool
+		want       map[string]string
+	}{
+		{
+			name:       "memory",
+			modify:     func(t *testing.T, cfg *config.ServerConfig) { cfg.Storage.Type = storage.TypeMemory },
+			wantPassed: true,
+			want:       map[string]string{"config": statusPass, "connection": statusSkip, "migrations": statusSkip, "schema": statusSkip, "privileges": statusSkip},
+		},
+		{
+			name: "migrated sqlite",
+			modify: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.Storage.Type = storage.TypeSQLite
+				cfg.Storage.SQLite.Path = filepath.Join(t.TempDir(), "test.db")
+				s, err := storage.New(context.Background(), cfg.Storage)
+				if err != nil {
+					t.Fatalf("create storage: %v", err)
+				}
+				s.Close()
+			},
+			wantPassed: true,
+			want:       map[string]string{"config": statusPass, "connection": statusPass, "migrations": statusPass, "schema": statusSkip, "privileges": statusSkip},
+		},
+		{
+			name: "sqlite without migrations",
+			modify: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.Storage.Type = storage.TypeSQLite
+				cfg.Storage.SQLite.Path = filepath.Join(t.TempDir(), "test.db")
+			},
+			want: map[string]string{"config": statusPass, "connection": statusPass, "migrations": statusFail, "schema": statusSkip, "privileges": statusSkip},
+		},
+		{
+			name: "invalid config",
+			modify: func(t *testing.T, cfg *config.ServerConfig) {
+				cfg.Storage.Type = storage.TypeMemory
+				cfg.Server.Port = 0
+			},
+			want: map[string]string{"config": statusFail, "connection": statusSkip},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultServerConfig()
+			tt.modify(t, cfg)
+
+			r := &report{Passed: true}
+			diagnose(context.Background(), cfg, r)
+
+			if r.Passed != tt.wantPassed {
+				t.Errorf("expected passed %t, got %+v", tt.wantPassed, r.Checks)
+			}
+			got := statuses(r)
+			for name, want := range tt.want {
+				if got[name] != want {
+					t.Errorf("expected check %s to %s, got %s", name, want, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestReportWrite(t *testing.T) {
+	r := &report{Passed: true}
+	r.add("config", statusPass)
+	r.fail("migrations", errors.New("version 1 is applied\nbut the latest is 2"))
+
+	var text bytes.Buffer
+	if err := r.write(&text, "text"); err != nil {
+		t.Fatalf("write text: %v", err)
+	}
+	want := "PASS  config\nFAIL  migrations\n      version 1 is applied\n      but the latest is 2\nSome checks failed.\n"
+	if text.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, text.String())
+	}
+
+	var js bytes.Buffer
+	if err := r.write(&js, "json"); err != nil {
+		t.Fatalf("write json: %v", err)
+	}
+	var decoded report
+	if err := json.Unmarshal(js.Bytes(), &decoded); err != nil {
+		t.Fatalf("decode json: %v", err)
+	}
+	if decoded.Passed || len(decoded.Checks) != 2 || len(decoded.Checks[1].Details) != 2 {
+		t.Errorf("expected the checks in the JSON, got %s", js.String())
+	}
+}
+
+func TestFormatArg(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantRest []string
+		wantErr  bool
+	}{
+		{name: "default", args: []string{"--storage.type=sqlite"}, want: "text", wantRest: []string{"--storage.type=sqlite"}},
+		{name: "separate value", args: []string{"--format", "json", "--storage.type=sqlite"}, want: "json", wantRest: []string{"--storage.type=sqlite"}},
+		{name: "joined value", args: []string{"--storage.type=sqlite", "--format=text"}, want: "text", wantRest: []string{"--storage.type=sqlite"}},
+		{name: "unknown format", args: []string{"--format=yaml"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := formatArg(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			if got != tt.want || strings.Join(rest, " ") != strings.Join(tt.wantRest, " ") {
+				t.Errorf("expected format %q and args %v, got %q and %v", tt.want, tt.wantRest, got, rest)
+			}
+		})
+	}
+}
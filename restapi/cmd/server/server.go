@@ -3,55 +3,214 @@ package main // import "github.com/la4ezar/restapi"
 
 import (
 	"context"
+	"errors"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
 
 	"github.com/la4ezar/restapi/internal/config"
+	"github.com/la4ezar/restapi/internal/routes"
+	"github.com/la4ezar/restapi/pkg/admin"
 	"github.com/la4ezar/restapi/pkg/controller"
+	"github.com/la4ezar/restapi/pkg/grpcserver"
 	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/metrics"
 	"github.com/la4ezar/restapi/pkg/server"
 	"github.com/la4ezar/restapi/pkg/storage"
+	"github.com/la4ezar/restapi/pkg/tenancy"
+	"github.com/la4ezar/restapi/pkg/tracing"
 
 	_ "github.com/lib/pq"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	args := os.Args[1:]
+	if len(args) != 0 {
+		if cmd, ok := commands[args[0]]; ok {
+			fatalOnError(cmd(args[1:]))
+			return
+		}
+	}
+
+	fatalOnError(run(args))
+}
+
+// run starts the servers with the command-line flags args and blocks until they are shutdown.
+// The resources are released in reverse order after all the servers have returned.
+func run(args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	handleInterrupts(ctx, cancel)
 
-	cfg, err := config.NewDefaultServerConfig()
-	fatalOnError(err)
+	cfg, err := config.NewServerConfig(args)
+	if errors.Is(err, config.ErrHelp) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
 
-	err = cfg.Validate()
-	fatalOnError(err)
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
 
 	ctx, err = log.Configure(ctx, cfg.Logger)
-	fatalOnError(err)
+	if err != nil {
+		return err
+	}
+	cfg.OnReload(func(_, new *config.ServerConfig) error {
+		_, err := log.Configure(context.Background(), new.Logger)
+		return err
+	}, "logger")
 
-	db, err := storage.New(cfg.Storage)
-	fatalOnError(err)
+	shutdownTracing, err := tracing.Configure(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.C(ctx).WithError(err).Error()
+		}
+	}()
+
+	db, err := storage.New(ctx, cfg.Storage)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		if err := db.Close(); err != nil {
 			log.C(ctx).WithError(err).Error()
 		}
+		log.C(ctx).Info("Storage closed.")
 	}()
 
-	repository := storage.NewRepository(*db)
-	ctr := controller.NewController(repository)
-	srv := server.New(cfg.Server, *ctr)
+	cfg.OnReload(func(_, new *config.ServerConfig) error {
+		db.SetQueryTimeouts(new.Storage.QueryTimeouts)
+		return nil
+	}, "storage.query_timeouts")
 
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
+	var repository storage.Repository = storage.NewRepository(*db)
+	opts := []server.Option{server.WithMiddleware(tracing.Middleware())}
+	if len(cfg.Storage.Replicas) != 0 {
+		opts = append(opts, server.WithMiddleware(storage.SessionMiddleware()))
+	}
+	if cfg.Metrics.Enabled {
+		if db.DB != nil {
+			dbName := cfg.Storage.DataSource.DBName
+			if cfg.Storage.Type == storage.TypeSQLite {
+				dbName = cfg.Storage.SQLite.Path
+			}
+			if err := metrics.RegisterDB(db.DB, dbName); err != nil {
+				return err
+			}
+		}
 
-	go srv.Start(ctx, wg)
+		repository = metrics.NewRepository(repository)
+		opts = append(opts,
+			server.WithMiddleware(metrics.Route()),
+			server.WithHandler("Metrics", cfg.Metrics.Path, metrics.Handler()))
+	}
+	var cache *storage.CachingRepository
+	if cfg.Storage.Cache.Enabled {
+		cache = storage.NewCachingRepository(repository, cfg.Storage.Cache)
+		if cfg.Metrics.Enabled {
+			if err := metrics.RegisterCache(cache); err != nil {
+				return err
+			}
+		}
+		repository = cache
+	}
+	observable := storage.NewObservableRepository(tracing.NewRepository(repository))
+
+	ctr := controller.NewController(observable, controller.WithStrictJSON(cfg.Server.StrictJSON))
+
+	tenants := storage.NewTenantRepository(*db)
+	if cache != nil {
+		tenants = cache.PurgingTenants(tenants)
+	}
+	srv := server.New(cfg.Server, *ctr, opts...)
+	cfg.OnReload(func(_, new *config.ServerConfig) error {
+		srv.SetMaxBodyBytes(new.Server.MaxBodyBytes)
+		return nil
+	}, "server.max_body_bytes")
+	cfg.OnReload(func(_, _ *config.ServerConfig) error {
+		return errors.New("the server timeouts apply to the connections when they are accepted, restart to change them")
+	}, "server.readtimeout", "server.writetimeout", "server.idletimeout")
+	var grpcOpts []grpc.ServerOption
+	if cfg.Tenancy.Enabled {
+		resolver := tenancy.NewResolver(cfg.Tenancy, tenants)
+		tenants = resolver
+		srv.Use(resolver.Middleware(routes.HealthCheckURL, routes.ReadinessCheckURL, cfg.Metrics.Path))
+		grpcOpts = append(grpcOpts,
+			grpc.ChainUnaryInterceptor(resolver.UnaryInterceptor()),
+			grpc.ChainStreamInterceptor(resolver.StreamInterceptor()))
+	}
+	if cfg.Metrics.Enabled {
+		// Wraps the whole router, so the requests which match no route or are rejected are counted too
+		srv.Use(metrics.Middleware(cfg.Metrics.ServerTiming))
+	}
+
+	starters := []func(context.Context) error{srv.Start}
+	if cfg.GRPC.Enabled {
+		starters = append(starters, grpcserver.New(cfg.GRPC, observable, observable, grpcOpts...).Start)
+	}
+	if cfg.Admin.Enabled {
+		starters = append(starters, newAdminServer(cfg, ctr, controller.NewTenantController(tenants)).Start)
+	}
+	if cfg.Storage.Listener.Enabled {
+		listener := storage.NewListener(cfg.Storage.DataSource, cfg.Storage.Listener)
+		if cache != nil {
+			go cache.Follow(ctx, listener)
+		}
+		starters = append(starters, listener.Start)
+	}
+
+	return startAll(ctx, cancel, starters...)
+}
+
+// newAdminServer returns the admin server which serves the tenants and also the probes of ctr if configured
+func newAdminServer(cfg *config.ServerConfig, ctr *controller.Controller, tenantCtr *controller.TenantController) *admin.Server {
+	var opts []admin.Option
+	for _, route := range *tenantCtr.Routes() {
+		opts = append(opts, admin.WithHandler(route.Method, route.Path, route.Handler))
+	}
+	if cfg.Admin.ExposeProbes {
+		for _, route := range *ctr.Routes() {
+			if route.Path == routes.HealthCheckURL || route.Path == routes.ReadinessCheckURL {
+				opts = append(opts, admin.WithHandler(route.Method, route.Path, route.Handler))
+			}
+		}
+	}
+
+	return admin.New(cfg.Admin, func() interface{} {
+		return cfg.Current().Redacted()
+	}, opts...)
+}
+
+// startAll runs every starter and waits for all of them to return.
+// The first failure cancels ctx so that the others shutdown too and is returned.
+func startAll(ctx context.Context, cancel context.CancelFunc, starters ...func(context.Context) error) error {
+	errs := make(chan error, len(starters))
+	for _, start := range starters {
+		go func(start func(context.Context) error) {
+			errs <- start(ctx)
+		}(start)
+	}
 
-	wg.Wait()
+	var result error
+	for range starters {
+		if err := <-errs; err != nil {
+			log.C(ctx).WithError(err).Error("Server failed, shutting down...")
+			if result == nil {
+				result = err
+			}
+			cancel()
+		}
+	}
 
-	//log.C(ctx).Println("Server shutdown.")
+	return result
 }
 
 func fatalOnError(err error) {
@@ -61,12 +220,12 @@ func fatalOnError(err error) {
 }
 
 func handleInterrupts(ctx context.Context, cancel context.CancelFunc) {
-	term := make(chan os.Signal)
-	signal.Notify(term, os.Interrupt, os.Kill, syscall.SIGTERM)
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		select {
 		case <-term:
-			log.C(ctx).Println("Received OS Interrupt/Kill/Terminate signal, exiting gracefully...")
+			log.C(ctx).Println("Received OS Interrupt/Terminate signal, exiting gracefully...")
 			cancel()
 		case <-ctx.Done():
 			return
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartAll(t *testing.T) {
+	failure := errors.New("address already in use")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var stopped bool
+	err := startAll(ctx, cancel,
+		func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				stopped = true
+				return nil
+			case <-time.After(5 * time.Second):
+				return errors.New("not stopped")
+			}
+		},
+		func(context.Context) error {
+			return failure
+		})
+
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the failure to be returned, got %v", err)
+	}
+	if !stopped {
+		t.Error("expected the other servers to be stopped after the failure")
+	}
+}
+
+func TestStartAllStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wait := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	if err := startAll(ctx, cancel, wait, wait); err != nil {
+		t.Errorf("expected clean shutdown, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix prefixes the environment variables which override the settings
+const EnvPrefix = "RESTAPI"
+
+// ConfigFlag is the command-line flag which chooses the configuration file
+const ConfigFlag = "config"
+
+// ProfileFlag is the command-line flag which chooses the profile, RESTAPI_PROFILE if not set
+const ProfileFlag = "profile"
+
+// localSuffix names the optional configuration file which overrides the profile on this machine, e.g. application.local.yaml
+const localSuffix = ".local"
+
+// Sources of the settings which are not read from a configuration file
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceDefault = "default"
+)
+
+// ErrHelp is returned when the command-line flags asked for the usage
+var ErrHelp = pflag.ErrHelp
+
+// setting is a single leaf setting of a configuration
+type setting struct {
+	key         string
+	value       reflect.Value
+	description string
+}
+
+// EnvVar returns the environment variable which overrides the setting with key,
+// e.g. RESTAPI_STORAGE_DATA_SOURCE_HOST for storage.data_source.host
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// loader reads a configuration from its layers. Every setting is taken from the first layer which sets it, in order:
+// the command-line flags (--storage.data_source.host), the environment variables (RESTAPI_STORAGE_DATA_SOURCE_HOST),
+// the configuration files and the defaults. The configuration files are, in order of precedence, the optional
+// local override application.local.yaml, the file of the profile application-<profile>.yaml and the configuration
+// file chosen with --config or DefaultConfigFile. String settings can instead be read from a file or a secret
+// provider, see resolveSecrets.
+type loader struct {
+	flags    *pflag.FlagSet
+	settings []setting
+	profile  string
+	// files are the configuration files in order of increasing precedence
+	files []string
+}
+
+// newLoader returns loader of the settings of cfg with the overrides in the command-line flags args
+func newLoader(name string, cfg interface{}, args []string) (*loader, error) {
+	l := &loader{
+		flags:    pflag.NewFlagSet(name, pflag.ContinueOnError),
+		settings: settingsOf("", reflect.ValueOf(cfg), ""),
+	}
+
+	configFile := l.flags.String(ConfigFlag, "", "path to the configuration file, application.yaml in the working directory if empty")
+	profile := l.flags.String(ProfileFlag, "", fmt.Sprintf("profile which configuration file is merged over the configuration file (env %s)", EnvVar(ProfileFlag)))
+	for _, s := range l.settings {
+		addFlag(l.flags, s)
+		if err := addSecretFlags(l.flags, s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("could not parse flags: %w", err)
+	}
+
+	l.profile = *profile
+	if len(l.profile) == 0 {
+		l.profile = os.Getenv(EnvVar(ProfileFlag))
+	}
+
+	files, err := configFiles(*configFile, l.profile)
+	if err != nil {
+		return nil, err
+	}
+	l.files = files
+
+	return l, nil
+}
+
+// configFiles returns the existing configuration files of the profile in order of increasing precedence
+func configFiles(configFile, profile string) ([]string, error) {
+	defaultFile := DefaultConfigFile()
+	dir, name := defaultFile.Location, defaultFile.Name
+
+	var files []string
+	if len(configFile) != 0 {
+		if _, err := os.Stat(configFile); err != nil {
+			return nil, fmt.Errorf("could not read configuration: %s", err)
+		}
+		files = append(files, configFile)
+		dir, name = filepath.Dir(configFile), strings.TrimSuffix(filepath.Base(configFile), filepath.Ext(configFile))
+	} else if file := findFile(dir, name); len(file) != 0 {
+		files = append(files, file)
+	}
+
+	if len(profile) != 0 {
+		file := findFile(dir, name+"-"+profile)
+		if len(file) == 0 {
+			return nil, fmt.Errorf("could not find the configuration file %s-%s in %s of profile %s", name, profile, dir, profile)
+		}
+		files = append(files, file)
+	}
+
+	if file := findFile(dir, name+localSuffix); len(file) != 0 {
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// findFile returns the path of the configuration file with name in dir in any of the supported formats
+func findFile(dir, name string) string {
+	for _, ext := range viper.SupportedExts {
+		path := filepath.Join(dir, name+"."+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// readFile returns the settings in the configuration file
+func readFile(file string) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if len(filepath.Ext(file)) == 0 {
+		v.SetConfigType(DefaultConfigFile().Format)
+	}
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("could not read configuration %s: %s", file, err)
+	}
+	return v, nil
+}
+
+// viper returns the settings of all the layers merged
+func (l *loader) viper() (*viper.Viper, error) {
+	v := viper.New()
+
+	for _, file := range l.files {
+		fileSettings, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := v.MergeConfigMap(fileSettings.AllSettings()); err != nil {
+			return nil, fmt.Errorf("could not merge configuration %s: %s", file, err)
+		}
+	}
+
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+
+	var err error
+	l.flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == ConfigFlag || flag.Name == ProfileFlag || err != nil {
+			return
+		}
+		if bindErr := v.BindPFlag(flag.Name, flag); bindErr != nil {
+			err = fmt.Errorf("could not bind flag %s: %s", flag.Name, bindErr)
+		}
+	})
+
+	return v, err
+}
+
+// load reads the settings of all the layers into cfg and resolves the secrets among them
+func (l *loader) load(cfg interface{}) error {
+	v, err := l.viper()
+	if err != nil {
+		return err
+	}
+
+	if err := v.Unmarshal(cfg); err != nil {
+		return fmt.Errorf("error loading configuration: %s", err)
+	}
+
+	return resolveSecrets(context.Background(), v, cfg)
+}
+
+// sources returns the layer which every setting is taken from, e.g. "flag --server.port",
+// "env RESTAPI_SERVER_PORT", "application-prod.yaml" or "default"
+func (l *loader) sources() (map[string]string, error) {
+	fileSettings := make([]*viper.Viper, 0, len(l.files))
+	for _, file := range l.files {
+		v, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		fileSettings = append(fileSettings, v)
+	}
+
+	sources := make(map[string]string, len(l.settings))
+	for _, s := range l.settings {
+		sources[s.key] = l.source(s.key, fileSettings)
+	}
+	return sources, nil
+}
+
+func (l *loader) source(key string, fileSettings []*viper.Viper) string {
+	variants := []string{key, key + fileSuffix, key + secretSuffix}
+
+	for _, variant := range variants {
+		if flag := l.flags.Lookup(variant); flag != nil && flag.Changed {
+			return fmt.Sprintf("%s --%s", SourceFlag, variant)
+		}
+	}
+	for _, variant := range variants {
+		if _, ok := os.LookupEnv(EnvVar(variant)); ok {
+			return fmt.Sprintf("%s %s", SourceEnv, EnvVar(variant))
+		}
+	}
+	for i := len(fileSettings) - 1; i >= 0; i-- {
+		for _, variant := range variants {
+			if fileSettings[i].IsSet(variant) {
+				return l.files[i]
+			}
+		}
+	}
+	return SourceDefault
+}
+
+// addFlag defines the command-line flag of s with the current value of s as default
+func addFlag(flags *pflag.FlagSet, s setting) {
+	// Lists of structs, like the storage replicas, can be set only in the configuration files
+	if s.value.Kind() == reflect.Slice && s.value.Type().Elem().Kind() == reflect.Struct {
+		return
+	}
+
+	usage := fmt.Sprintf("%s (env %s)", s.description, EnvVar(s.key))
+
+	switch value := s.value.Interface().(type) {
+	case time.Duration:
+		flags.Duration(s.key, value, usage)
+	case bool:
+		flags.Bool(s.key, value, usage)
+	case int:
+		flags.Int(s.key, value, usage)
+	case int64:
+		flags.Int64(s.key, value, usage)
+	case float64:
+		flags.Float64(s.key, value, usage)
+	case []string:
+		flags.StringSlice(s.key, value, usage)
+	default:
+		flags.String(s.key, fmt.Sprint(value), usage)
+	}
+}
+
+// settingsOf returns the leaf settings of v keyed like the configuration file.
+// Maps contribute a setting for each of their entries in v.
+func settingsOf(prefix string, v reflect.Value, description string) []setting {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == reflect.TypeOf(time.Duration(0)):
+		return []setting{{key: prefix, value: v, description: description}}
+	case v.Kind() == reflect.Struct:
+		var settings []setting
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			settings = append(settings, settingsOf(join(prefix, keyOf(field)), v.Field(i), field.Tag.Get("description"))...)
+		}
+		return settings
+	case v.Kind() == reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			keys = append(keys, fmt.Sprint(k.Interface()))
+		}
+		sort.Strings(keys)
+
+		var settings []setting
+		for _, k := range keys {
+			settings = append(settings, settingsOf(join(prefix, k), v.MapIndex(reflect.ValueOf(k)), description)...)
+		}
+		return settings
+	default:
+		return []setting{{key: prefix, value: v, description: description}}
+	}
+}
+
+// join returns the key of the setting key nested under prefix
+func join(prefix, key string) string {
+	if len(prefix) == 0 {
+		return strings.ToLower(key)
+	}
+	return prefix + "." + strings.ToLower(key)
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewServerConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "server.yaml")
+	content := "server:\n  port: 8000\n  readtimeout: 5s\nstorage:\n  data_source:\n    host: file\n    user: file\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("write configuration: %v", err)
+	}
+
+	t.Setenv(EnvVar("storage.data_source.host"), "env")
+	t.Setenv(EnvVar("server.port"), "8001")
+
+	cfg, err := NewServerConfig([]string{"--config", file, "--server.port=8002"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if cfg.Server.Port != 8002 {
+		t.Errorf("expected the flag to override the environment, got port %d", cfg.Server.Port)
+	}
+	if cfg.Storage.DataSource.Host != "env" {
+		t.Errorf("expected the environment to override the file, got host %s", cfg.Storage.DataSource.Host)
+	}
+	if cfg.Storage.DataSource.User != "file" || cfg.Server.ReadTimeout != 5*time.Second {
+		t.Errorf("expected the settings from the file, got user %s and read timeout %s", cfg.Storage.DataSource.User, cfg.Server.ReadTimeout)
+	}
+	if want := DefaultServerConfig().Server.WriteTimeout; cfg.Server.WriteTimeout != want {
+		t.Errorf("expected the default write timeout %s, got %s", want, cfg.Server.WriteTimeout)
+	}
+}
+
+func TestNewClientConfig(t *testing.T) {
+	t.Setenv(EnvVar("client.endpoints.healthcheck"), "http://env/api/health")
+
+	cfg, err := NewClientConfig([]string{"--client.timeout=3s"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if cfg.Client.Timeout != 3*time.Second {
+		t.Errorf("expected timeout 3s, got %s", cfg.Client.Timeout)
+	}
+	if got := cfg.Client.Endpoints["healthcheck"]; got != "http://env/api/health" {
+		t.Errorf("expected the endpoint from the environment, got %s", got)
+	}
+	if got := cfg.Client.Endpoints["getcryptos"]; got != "http://localhost:8080/api/cryptos" {
+		t.Errorf("expected the default endpoint, got %s", got)
+	}
+}
+
+func TestNewServerConfigFlags(t *testing.T) {
+	if _, err := NewServerConfig([]string{"--help"}); !errors.Is(err, ErrHelp) {
+		t.Errorf("expected ErrHelp, got %v", err)
+	}
+	if _, err := NewServerConfig([]string{"--server.port=port"}); err == nil {
+		t.Error("expected error for an invalid flag value")
+	}
+	if _, err := NewServerConfig([]string{"--unknown"}); err == nil {
+		t.Error("expected error for an unknown flag")
+	}
+}
+
+func TestEnvVar(t *testing.T) {
+	if got := EnvVar("storage.data_source.host"); got != "RESTAPI_STORAGE_DATA_SOURCE_HOST" {
+		t.Errorf("expected RESTAPI_STORAGE_DATA_SOURCE_HOST, got %s", got)
+	}
+}
+
+func TestNewServerConfigProfile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"server.yaml":       "server:\n  port: 8000\n  readtimeout: 5s\n  writetimeout: 5s\n",
+		"server-prod.yaml":  "server:\n  port: 8001\n  readtimeout: 6s\n",
+		"server.local.yaml": "server:\n  port: 8002\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("write configuration: %v", err)
+		}
+	}
+	t.Setenv(EnvVar(ProfileFlag), "prod")
+	t.Setenv(EnvVar("server.idletimeout"), "7s")
+
+	cfg, err := NewServerConfig([]string{"--config", filepath.Join(dir, "server.yaml")})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if cfg.Server.Port != 8002 || cfg.Server.ReadTimeout != 6*time.Second || cfg.Server.WriteTimeout != 5*time.Second {
+		t.Errorf("expected the local file over the profile over the configuration file, got port %d, read timeout %s and write timeout %s",
+			cfg.Server.Port, cfg.Server.ReadTimeout, cfg.Server.WriteTimeout)
+	}
+
+	want := map[string]string{
+		"server.port":            filepath.Join(dir, "server.local.yaml"),
+		"server.readtimeout":     filepath.Join(dir, "server-prod.yaml"),
+		"server.writetimeout":    filepath.Join(dir, "server.yaml"),
+		"server.idletimeout":     "env RESTAPI_SERVER_IDLETIMEOUT",
+		"server.shutdowntimeout": SourceDefault,
+	}
+	for key, source := range want {
+		if got := cfg.Sources()[key]; got != source {
+			t.Errorf("expected %s from %s, got %s", key, source, got)
+		}
+	}
+
+	if _, err := NewServerConfig([]string{"--config", filepath.Join(dir, "server.yaml"), "--profile", "dev"}); err == nil {
+		t.Error("expected error for a profile without a configuration file")
+	}
+}
+
+func TestNewServerConfigReplicas(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "server.yaml")
+	content := "storage:\n  replicas:\n    - host: replica-1\n      port: \"5432\"\n    - host: replica-2\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("write configuration: %v", err)
+	}
+
+	cfg, err := NewServerConfig([]string{"--config", file})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(cfg.Storage.Replicas) != 2 || cfg.Storage.Replicas[0].Host != "replica-1" || cfg.Storage.Replicas[1].Host != "replica-2" {
+		t.Errorf("expected the replicas of the configuration file, got %+v", cfg.Storage.Replicas)
+	}
+
+	if _, err := NewServerConfig([]string{"--storage.replicas", "replica-1"}); err == nil {
+		t.Error("expected the replicas not to be a flag")
+	}
+}
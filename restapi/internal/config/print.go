@@ -0,0 +1,31 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// Print writes every setting with its value, the secrets masked, and the layer it is taken from
+func (c *ServerConfig) Print(w io.Writer) error {
+	settings := flatten(c.Redacted())
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, key := range keys {
+		source, ok := c.sources[key]
+		if !ok {
+			source = SourceDefault
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", key, settings[key], source)
+	}
+
+	return tw.Flush()
+}
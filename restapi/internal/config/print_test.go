@@ -0,0 +1,81 @@
+package config
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrint(t *testing.T) {
+	t.Setenv(EnvVar("storage.data_source.password"), "secret")
+
+	cfg, err := NewServerConfig([]string{"--server.port=8000"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := cfg.Print(&out); err != nil {
+		t.Fatalf("print: %v", err)
+	}
+
+	lines := []string{
+		`(?m)^KEY\s+VALUE\s+SOURCE$`,
+		`(?m)^server\.port\s+8000\s+flag --server\.port$`,
+		`(?m)^storage\.data_source\.password\s+\*{6}\s+env RESTAPI_STORAGE_DATA_SOURCE_PASSWORD$`,
+		`(?m)^server\.shutdowntimeout\s+\S+\s+default$`,
+	}
+	for _, line := range lines {
+		if !regexp.MustCompile(line).MatchString(out.String()) {
+			t.Errorf("expected a line matching %s, got\n%s", line, out.String())
+		}
+	}
+	if bytes.Contains(out.Bytes(), []byte("secret")) {
+		t.Errorf("expected the password to be masked, got\n%s", out.String())
+	}
+}
+
+func TestValidateJoinsErrors(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.Server.Port = 0
+	cfg.Logger.Level = "verbose"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, setting := range []string{"Server", "Logger"} {
+		if !regexp.MustCompile(`validate ` + setting + ` settings`).MatchString(err.Error()) {
+			t.Errorf("expected the %s error in %v", setting, err)
+		}
+	}
+}
+
+func TestValidateReportsEveryInvalidSetting(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.Server.Port = 0
+	cfg.Server.ReadTimeout = 0
+	cfg.Storage.IsolationLevel = "chaos"
+	cfg.Storage.QueryTimeouts.AddCrypto = -time.Second
+	cfg.Storage.QueryTimeouts.RemoveCrypto = -time.Second
+	cfg.Storage.Retry = nil
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{
+		"Port missing",
+		"ReadTimeout missing",
+		"unknown IsolationLevel chaos",
+		"AddCrypto must not be negative",
+		"RemoveCrypto must not be negative",
+		"Retry missing",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// redactedValue replaces the values of the secret settings
+const redactedValue = "******"
+
+// secretKeys are the settings which values are never shown
+var secretKeys = map[string]bool{
+	"password":  true,
+	"token_key": true,
+}
+
+// toMap returns cfg as nested maps keyed like the configuration file
+func toMap(cfg interface{}) map[string]interface{} {
+	m, _ := toValue(reflect.ValueOf(cfg)).(map[string]interface{})
+	return m
+}
+
+func toValue(v reflect.Value) interface{} {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return v.Interface()
+	}
+
+	m := make(map[string]interface{}, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		m[keyOf(field)] = toValue(v.Field(i))
+	}
+	return m
+}
+
+// keyOf returns the key of the field in the configuration file
+func keyOf(field reflect.StructField) string {
+	if tag := strings.Split(field.Tag.Get("mapstructure"), ",")[0]; len(tag) != 0 {
+		return tag
+	}
+	return strings.ToLower(field.Name)
+}
+
+// redact masks the values of the secret settings in m and returns it
+func redact(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		switch value := v.(type) {
+		case map[string]interface{}:
+			redact(value)
+		case string:
+			if secretKeys[k] && len(value) != 0 {
+				m[k] = redactedValue
+			}
+		}
+	}
+	return m
+}
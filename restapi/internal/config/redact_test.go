@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRedacted(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.Storage.DataSource.Password = "secret"
+	cfg.Server.ShutdownTimeout = 3 * time.Second
+
+	m := cfg.Redacted()
+
+	storage, _ := m["storage"].(map[string]interface{})
+	dataSource, _ := storage["data_source"].(map[string]interface{})
+	if got := dataSource["password"]; got != redactedValue {
+		t.Errorf("expected the password to be redacted, got %v", got)
+	}
+	if cfg.Storage.DataSource.Password != "secret" {
+		t.Error("expected the configuration not to be changed")
+	}
+
+	server, _ := m["server"].(map[string]interface{})
+	if got := server["shutdowntimeout"]; got != "3s" {
+		t.Errorf("expected the durations as strings keyed like the configuration file, got %v", got)
+	}
+}
+
+func TestRedactedEmptyPassword(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.Storage.DataSource.Password = ""
+
+	storage, _ := cfg.Redacted()["storage"].(map[string]interface{})
+	dataSource, _ := storage["data_source"].(map[string]interface{})
+	if got := dataSource["password"]; got != "" {
+		t.Errorf("expected the empty password to stay empty, got %v", got)
+	}
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ReloadHook applies a reloaded configuration to a subsystem
+type ReloadHook func(old, new *ServerConfig) error
+
+type reloadHook struct {
+	keys  []string
+	apply ReloadHook
+}
+
+// reloader keeps the current configuration and applies the reloaded ones to the registered hooks
+type reloader struct {
+	loader  *loader
+	mutex   sync.Mutex
+	current atomic.Value
+	hooks   []reloadHook
+}
+
+func newReloader(l *loader, cfg *ServerConfig) *reloader {
+	r := &reloader{loader: l}
+	r.current.Store(cfg)
+	return r
+}
+
+// OnReload registers hook which is called when a reloaded configuration changes any setting under keys,
+// e.g. "logger" or "server.max_body_bytes". Changes to settings without a hook require a restart.
+func (c *ServerConfig) OnReload(hook ReloadHook, keys ...string) {
+	if c.reloader == nil {
+		return
+	}
+
+	c.reloader.mutex.Lock()
+	defer c.reloader.mutex.Unlock()
+
+	c.reloader.hooks = append(c.reloader.hooks, reloadHook{keys: keys, apply: hook})
+}
+
+// Current returns the last successfully reloaded configuration
+func (c *ServerConfig) Current() *ServerConfig {
+	if c.reloader == nil {
+		return c
+	}
+	return c.reloader.current.Load().(*ServerConfig)
+}
+
+// watch reloads the configuration every time any of the configuration files changes,
+// including when a symlinked file is replaced as Kubernetes does with mounted ConfigMaps
+func (r *reloader) watch() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not watch the configuration files: %s", err)
+	}
+
+	realPaths := make(map[string]string, len(r.loader.files))
+	for _, file := range r.loader.files {
+		realPaths[file], _ = filepath.EvalSymlinks(file)
+		if err := watcher.Add(filepath.Dir(file)); err != nil {
+			watcher.Close()
+			return fmt.Errorf("could not watch the configuration file %s: %s", file, err)
+		}
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				for _, file := range r.loader.files {
+					realPath, _ := filepath.EvalSymlinks(file)
+					written := filepath.Clean(event.Name) == filepath.Clean(file) && event.Op&(fsnotify.Write|fsnotify.Create) != 0
+					if written || realPath != realPaths[file] {
+						realPaths[file] = realPath
+						r.reload(file)
+						break
+					}
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.D().WithError(err).Error("Error watching the configuration files")
+			}
+		}
+	}()
+
+	return nil
+}
+
+// reload validates the configuration with the changed file and if it is valid replaces the current one with it
+// and applies the changes to the hooks. Invalid configurations are rejected as a whole.
+func (r *reloader) reload(file string) {
+	next := DefaultServerConfig()
+	if err := r.loader.load(next); err != nil {
+		log.D().WithError(err).Errorf("Rejected configuration reload from %s, keeping the current configuration", file)
+		return
+	}
+	if err := next.Validate(); err != nil {
+		log.D().WithError(err).Errorf("Rejected invalid configuration reload from %s, keeping the current configuration", file)
+		return
+	}
+	next.sources, _ = r.loader.sources()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	old := r.current.Load().(*ServerConfig)
+	changed := changedKeys(old, next)
+	if len(changed) == 0 {
+		log.D().Debugf("Configuration file %s changed without changing any setting", file)
+		return
+	}
+
+	next.reloader = r
+	r.current.Store(next)
+
+	applied := make(map[string]bool, len(changed))
+	for _, hook := range r.hooks {
+		keys := matchingKeys(changed, hook.keys)
+		if len(keys) == 0 {
+			continue
+		}
+		if err := hook.apply(old, next); err != nil {
+			log.D().WithError(err).Errorf("Couldn't apply reloaded settings %s", strings.Join(keys, ", "))
+			continue
+		}
+		for _, key := range keys {
+			applied[key] = true
+		}
+	}
+
+	for _, key := range changed {
+		if !applied[key] {
+			log.D().Warnf("Setting %s changed in %s but can't be reloaded, restart to apply it", key, file)
+		}
+	}
+
+	log.D().Infof("Reloaded configuration from %s, changed settings: %s", file, strings.Join(changed, ", "))
+}
+
+// changedKeys returns the sorted keys of the settings which differ between old and new
+func changedKeys(old, new *ServerConfig) []string {
+	oldValues, newValues := flatten(toMap(old)), flatten(toMap(new))
+
+	var changed []string
+	for key, value := range newValues {
+		if !reflect.DeepEqual(oldValues[key], value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldValues {
+		if _, ok := newValues[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+// matchingKeys returns the keys which are equal to or nested under any of the prefixes
+func matchingKeys(keys, prefixes []string) []string {
+	var matching []string
+	for _, key := range keys {
+		for _, prefix := range prefixes {
+			if key == prefix || strings.HasPrefix(key, prefix+".") {
+				matching = append(matching, key)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// flatten returns the leaf settings of the nested maps m keyed by their dot separated path
+func flatten(m map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+	flattenInto("", m, flat)
+	return flat
+}
+
+func flattenInto(prefix string, m map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range m {
+		key := k
+		if len(prefix) != 0 {
+			key = fmt.Sprintf("%s.%s", prefix, k)
+		}
+
+		if nested, ok := v.(map[string]interface{}); ok {
+			flattenInto(key, nested, flat)
+			continue
+		}
+		flat[key] = v
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newTestReloader returns the default configuration reloaded from a configuration file
+func newTestReloader(t *testing.T) (*ServerConfig, string) {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "application.yaml")
+	writeConfig(t, file, "logger:\n  level: info\n")
+
+	cfg := DefaultServerConfig()
+	l, err := newLoader("server", cfg, []string{"--" + ConfigFlag, file})
+	if err != nil {
+		t.Fatalf("new loader: %v", err)
+	}
+	cfg.reloader = newReloader(l, cfg)
+	return cfg, file
+}
+
+func writeConfig(t *testing.T, file, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("write configuration: %v", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	cfg, file := newTestReloader(t)
+
+	var loggerCalls, serverCalls int
+	cfg.OnReload(func(old, new *ServerConfig) error {
+		loggerCalls++
+		if old.Logger.Level != "info" || new.Logger.Level != "debug" {
+			t.Errorf("expected the level to change from info to debug, got %s to %s", old.Logger.Level, new.Logger.Level)
+		}
+		return nil
+	}, "logger")
+	cfg.OnReload(func(_, _ *ServerConfig) error {
+		serverCalls++
+		return nil
+	}, "server.max_body_bytes")
+
+	writeConfig(t, file, "logger:\n  level: debug\n")
+	cfg.reloader.reload(file)
+
+	if loggerCalls != 1 || serverCalls != 0 {
+		t.Errorf("expected only the logger hook to be called once, got %d and %d calls", loggerCalls, serverCalls)
+	}
+	if got := cfg.Current().Logger.Level; got != "debug" {
+		t.Errorf("expected the current level debug, got %s", got)
+	}
+
+	cfg.reloader.reload(file)
+	if loggerCalls != 1 {
+		t.Errorf("expected no hook to be called without changes, got %d logger calls", loggerCalls)
+	}
+}
+
+func TestReloadRejectsInvalid(t *testing.T) {
+	cfg, file := newTestReloader(t)
+
+	called := false
+	cfg.OnReload(func(_, _ *ServerConfig) error {
+		called = true
+		return nil
+	}, "logger")
+
+	writeConfig(t, file, "logger:\n  level: verbose\n")
+	cfg.reloader.reload(file)
+
+	if called {
+		t.Error("expected the hook not to be called for an invalid configuration")
+	}
+	if got := cfg.Current().Logger.Level; got != "info" {
+		t.Errorf("expected the current level to stay info, got %s", got)
+	}
+}
+
+func TestReloadKeepsFailedHook(t *testing.T) {
+	cfg, file := newTestReloader(t)
+	cfg.OnReload(func(_, _ *ServerConfig) error {
+		return errors.New("failed")
+	}, "logger")
+
+	writeConfig(t, file, "logger:\n  level: debug\n")
+	cfg.reloader.reload(file)
+
+	if got := cfg.Current().Logger.Level; got != "debug" {
+		t.Errorf("expected the valid configuration to become current, got level %s", got)
+	}
+}
+
+func TestCurrentWithoutReloader(t *testing.T) {
+	cfg := DefaultServerConfig()
+	cfg.OnReload(func(_, _ *ServerConfig) error { return nil }, "logger")
+
+	if cfg.Current() != cfg {
+		t.Error("expected the configuration itself without a configuration file")
+	}
+}
+
+func TestChangedKeys(t *testing.T) {
+	old, next := DefaultServerConfig(), DefaultServerConfig()
+	next.Logger.Level = "debug"
+	next.Server.MaxBodyBytes = 1
+
+	want := []string{"logger.level", "server.max_body_bytes"}
+	if got := changedKeys(old, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected changed keys %v, got %v", want, got)
+	}
+}
+
+func TestMatchingKeys(t *testing.T) {
+	keys := []string{"logger.level", "server.max_body_bytes", "server.port", "serverless"}
+
+	want := []string{"logger.level", "server.port"}
+	if got := matchingKeys(keys, []string{"logger", "server.port"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected matching keys %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/la4ezar/restapi/pkg/secrets"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Suffixes of the settings which hold the value of a string setting outside of the configuration,
+// e.g. storage.data_source.password_file: /run/secrets/db_password or storage.data_source.password_secret: env:DB_PASSWORD
+const (
+	fileSuffix   = "_file"
+	secretSuffix = "_secret"
+)
+
+// addSecretFlags defines the hidden command-line flags of the file and secret variants of s
+func addSecretFlags(flags *pflag.FlagSet, s setting) error {
+	if s.value.Kind() != reflect.String {
+		return nil
+	}
+
+	variants := map[string]string{
+		s.key + fileSuffix:   fmt.Sprintf("path to a file with the value of %s", s.key),
+		s.key + secretSuffix: fmt.Sprintf("reference to a secret with the value of %s", s.key),
+	}
+	for key, usage := range variants {
+		flags.String(key, "", usage)
+		if err := flags.MarkHidden(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveSecrets sets every string setting of cfg which has a <key>_file or <key>_secret variant in v
+// to the contents of the file or the value of the referenced secret
+func resolveSecrets(ctx context.Context, v *viper.Viper, cfg interface{}) error {
+	for _, s := range settingsOf("", reflect.ValueOf(cfg), "") {
+		if s.value.Kind() != reflect.String || !s.value.CanSet() {
+			continue
+		}
+
+		ref := v.GetString(s.key + secretSuffix)
+		if file := v.GetString(s.key + fileSuffix); len(file) != 0 {
+			if len(ref) != 0 {
+				return fmt.Errorf("only one of %s%s and %s%s can be set", s.key, fileSuffix, s.key, secretSuffix)
+			}
+			ref = secrets.FileScheme + ":" + file
+		}
+		if len(ref) == 0 {
+			continue
+		}
+
+		value, err := secrets.Resolve(ctx, ref)
+		if err != nil {
+			return fmt.Errorf("could not resolve %s: %s", s.key, err)
+		}
+		s.value.SetString(value)
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewServerConfigSecrets(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "password")
+	if err := os.WriteFile(passwordFile, []byte("from file\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	t.Setenv("TEST_DB_USER", "from env")
+
+	cfg, err := NewServerConfig([]string{
+		"--storage.data_source.password_file=" + passwordFile,
+		"--storage.data_source.user_secret=env:TEST_DB_USER",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if cfg.Storage.DataSource.Password != "from file" {
+		t.Errorf("expected the password from the file, got %q", cfg.Storage.DataSource.Password)
+	}
+	if cfg.Storage.DataSource.User != "from env" {
+		t.Errorf("expected the user from the secret, got %q", cfg.Storage.DataSource.User)
+	}
+}
+
+func TestNewServerConfigSecretsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "file and secret", args: []string{"--storage.data_source.password_file=/run/secrets/password", "--storage.data_source.password_secret=env:PASSWORD"}},
+		{name: "missing file", args: []string{"--storage.data_source.password_file=" + filepath.Join(t.TempDir(), "missing")}},
+		{name: "unknown provider", args: []string{"--storage.data_source.password_secret=vault:password"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewServerConfig(tt.args); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
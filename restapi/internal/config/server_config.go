@@ -1,58 +1,97 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"os"
 
+	"github.com/la4ezar/restapi/pkg/admin"
+	"github.com/la4ezar/restapi/pkg/grpcserver"
 	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/metrics"
 	"github.com/la4ezar/restapi/pkg/server"
 	"github.com/la4ezar/restapi/pkg/storage"
-	"github.com/spf13/viper"
+	"github.com/la4ezar/restapi/pkg/tenancy"
+	"github.com/la4ezar/restapi/pkg/tracing"
 )
 
 type ServerConfig struct {
 	Server  *server.Config
+	GRPC    *grpcserver.Config
 	Storage *storage.Config
 	Logger  *log.Config
+	Metrics *metrics.Config
+	Tracing *tracing.Config
+	Admin   *admin.Config
+	Tenancy *tenancy.Config
+
+	reloader *reloader
+	sources  map[string]string
 }
 
+// Validate returns all the invalid settings joined in a single error
 func (c *ServerConfig) Validate() error {
-	validatable := []Validator{c.Server, c.Logger, c.Storage}
+	validatable := []Validator{c.Server, c.GRPC, c.Logger, c.Storage, c.Metrics, c.Tracing, c.Admin, c.Tenancy}
 
+	var errs []error
 	for _, v := range validatable {
 		if err := v.Validate(); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// Sources returns the layer which every setting is taken from keyed like the configuration file,
+// e.g. "flag --server.port", "env RESTAPI_SERVER_PORT", the path of a configuration file or "default"
+func (c *ServerConfig) Sources() map[string]string {
+	return c.sources
+}
+
+// Redacted returns the configuration keyed like the configuration file with the secrets masked
+func (c *ServerConfig) Redacted() map[string]interface{} {
+	return redact(toMap(c))
 }
 
 func DefaultServerConfig() *ServerConfig {
 	return &ServerConfig{
 		Server:  server.DefaultConfig(),
+		GRPC:    grpcserver.DefaultConfig(),
 		Storage: storage.DefaultConfig(),
 		Logger:  log.DefaultConfig(),
+		Metrics: metrics.DefaultConfig(),
+		Tracing: tracing.DefaultConfig(),
+		Admin:   admin.DefaultConfig(),
+		Tenancy: tenancy.DefaultConfig(),
 	}
 }
 
+// NewDefaultServerConfig returns the configuration of the server with the overrides in the command-line flags of the process
 func NewDefaultServerConfig() (*ServerConfig, error) {
-	serverConfig := DefaultServerConfig()
-	configFile := DefaultConfigFile()
-
-	v := viper.New()
+	return NewServerConfig(os.Args[1:])
+}
 
-	v.AddConfigPath(configFile.Location)
-	v.SetConfigName(configFile.Name)
-	v.SetConfigType(configFile.Format)
+// NewServerConfig returns the configuration of the server with the overrides in the command-line flags args.
+// The configuration is reloaded when the configuration files change, see OnReload.
+func NewServerConfig(args []string) (*ServerConfig, error) {
+	serverConfig := DefaultServerConfig()
 
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("could not read configuration: %s", err)
-		}
+	l, err := newLoader("server", serverConfig, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.load(serverConfig); err != nil {
+		return nil, err
+	}
+	if serverConfig.sources, err = l.sources(); err != nil {
+		return nil, err
 	}
 
-	if err := v.Unmarshal(serverConfig); err != nil {
-		return nil, fmt.Errorf("error loading configuration: %s", err)
+	if len(l.files) != 0 {
+		serverConfig.reloader = newReloader(l, serverConfig)
+		if err := serverConfig.reloader.watch(); err != nil {
+			return nil, err
+		}
 	}
 
 	return serverConfig, nil
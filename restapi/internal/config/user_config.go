@@ -1,53 +1,55 @@
 package config
 
 import (
-	"fmt"
+	"errors"
+	"os"
 
 	"github.com/la4ezar/restapi/pkg/client"
-	"github.com/spf13/viper"
+	"github.com/la4ezar/restapi/pkg/tracing"
 )
 
 type ClientConfig struct {
-	Client *client.Config
+	Client  *client.Config
+	Tracing *tracing.Config
 }
 
+// Validate returns all the invalid settings joined in a single error
 func (c *ClientConfig) Validate() error {
-	validatable := []Validator{c.Client}
+	validatable := []Validator{c.Client, c.Tracing}
 
+	var errs []error
 	for _, v := range validatable {
 		if err := v.Validate(); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 func DefaultClientConfig() *ClientConfig {
 	return &ClientConfig{
-		Client: client.DefaultConfig(),
+		Client:  client.DefaultConfig(),
+		Tracing: tracing.DefaultConfig(),
 	}
 }
 
+// NewDefaultClientConfig returns the configuration of the client with the overrides in the command-line flags of the process
 func NewDefaultClientConfig() (*ClientConfig, error) {
-	ClientConfig := DefaultClientConfig()
-	configFile := DefaultConfigFile()
-
-	v := viper.New()
+	return NewClientConfig(os.Args[1:])
+}
 
-	v.AddConfigPath(configFile.Location)
-	v.SetConfigName(configFile.Name)
-	v.SetConfigType(configFile.Format)
+// NewClientConfig returns the configuration of the client with the overrides in the command-line flags args
+func NewClientConfig(args []string) (*ClientConfig, error) {
+	clientConfig := DefaultClientConfig()
 
-	if err := v.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, fmt.Errorf("could not read configuration: %s", err)
-		}
+	l, err := newLoader("client", clientConfig, args)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := v.Unmarshal(ClientConfig); err != nil {
-		return nil, fmt.Errorf("error loading configuration: %s", err)
+	if err := l.load(clientConfig); err != nil {
+		return nil, err
 	}
 
-	return ClientConfig, nil
+	return clientConfig, nil
 }
@@ -1,20 +1,23 @@
 // Package crypto contains Cryptocurrency structure
 package crypto // import "github.com/la4ezar/restapi/internal/server
 
+import "github.com/shopspring/decimal"
+
 type Authors []Author
 
 // Cryptocurrency structure with crypto's id, name, crypto_id, current price and authors/innovators
 type Cryptocurrency struct {
-	Name     string   `json:"name"`
-	CryptoID string   `json:"crypto_id"`
-	Price    float64  `json:"price"`
-	Authors  []Author `json:"authors"`
+	Name     string `json:"name" yaml:"name"`
+	CryptoID string `json:"crypto_id" yaml:"crypto_id"`
+	// Price is exact and encoded as a string in JSON, e.g. "0.00000001", while numbers are accepted too
+	Price   decimal.Decimal `json:"price" yaml:"price"`
+	Authors []Author        `json:"authors" yaml:"authors"`
 }
 
 // Author structure with crypto author's first and last name
 type Author struct {
-	Firstname string `json:"firstname"`
-	Lastname  string `json:"lastname"`
+	Firstname string `json:"firstname" yaml:"firstname"`
+	Lastname  string `json:"lastname" yaml:"lastname"`
 }
 
 // DefaultCrypto returns default Cryptocurrency
@@ -22,7 +25,7 @@ func DefaultCrypto() *Cryptocurrency {
 	return &Cryptocurrency{
 		Name:     "",
 		CryptoID: "",
-		Price:    0.0,
+		Price:    decimal.Zero,
 		Authors:  []Author{},
 	}
 }
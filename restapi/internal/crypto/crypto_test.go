@@ -0,0 +1,51 @@
+package crypto
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCryptocurrencyJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantPrice string
+		wantErr   bool
+	}{
+		{name: "string price", body: `{"price":"45000.94"}`, wantPrice: "45000.94"},
+		{name: "number price", body: `{"price":45000.94}`, wantPrice: "45000.94"},
+		{name: "smallest string price", body: `{"price":"0.00000001"}`, wantPrice: "0.00000001"},
+		{name: "smallest number price", body: `{"price":1e-8}`, wantPrice: "0.00000001"},
+		{name: "string price beyond float64", body: `{"price":"12345678901234567.123456789"}`, wantPrice: "12345678901234567.123456789"},
+		{name: "integer price", body: `{"price":3}`, wantPrice: "3"},
+		{name: "invalid price", body: `{"price":"abc"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Cryptocurrency
+			err := json.Unmarshal([]byte(tt.body), &c)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected the price to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+
+			encoded, err := json.Marshal(c)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(encoded, &fields); err != nil {
+				t.Fatalf("decode encoded: %v", err)
+			}
+			if price, ok := fields["price"].(string); !ok || price != tt.wantPrice {
+				t.Errorf("expected the price to be encoded as the string %q, got %#v", tt.wantPrice, fields["price"])
+			}
+		})
+	}
+}
@@ -8,4 +8,7 @@ const (
 	RemoveCryptoURL   = "/api/cryptos/{crypto_id}"
 	HealthCheckURL    = "/api/health"
 	ReadinessCheckURL = "/api/ready"
+
+	TenantsURL      = "/admin/tenants"
+	SingleTenantURL = "/admin/tenants/{tenant_id}"
 )
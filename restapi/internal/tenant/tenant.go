@@ -0,0 +1,69 @@
+// Package tenant contains Tenant structure
+package tenant // import "github.com/la4ezar/restapi/internal/tenant
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultID is the tenant of the cryptos which were stored before the tenants and of the calls without a tenant
+const DefaultID = "default"
+
+// maxIDLength is the length of the TenantID columns
+const maxIDLength = 36
+
+// Tenant is an isolated workspace with its own cryptos and settings
+type Tenant struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Settings Settings `json:"settings"`
+}
+
+// Settings of a tenant which override the ones of the server
+type Settings struct {
+	// Decimals are the decimal places of the prices of the cryptos without their own, the configured ones if nil
+	Decimals *int `json:"decimals,omitempty"`
+	// Assets are the decimal places of the prices by CryptoID
+	Assets map[string]int `json:"assets,omitempty"`
+	// ReadOnly tenants can only read their cryptos
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// ValidateID checks that id can be stored and used in paths, headers and logs as it is
+func ValidateID(id string) error {
+	if len(id) == 0 || len(id) > maxIDLength {
+		return fmt.Errorf("tenant id must be between 1 and %d characters", maxIDLength)
+	}
+
+	for i, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case (r == '-' || r == '_') && i != 0:
+		default:
+			return fmt.Errorf("tenant id must contain only lower case letters, digits, '-' and '_' and start with a letter or a digit")
+		}
+	}
+
+	return nil
+}
+
+type tenantKey struct{}
+
+// ContextWithTenant returns copy of ctx which carries the tenant
+func ContextWithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// FromContext returns the tenant carried by ctx and whether there is one
+func FromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(Tenant)
+	return t, ok
+}
+
+// IDFromContext returns the ID of the tenant carried by ctx or DefaultID if there is none
+func IDFromContext(ctx context.Context) string {
+	if t, ok := FromContext(ctx); ok {
+		return t.ID
+	}
+	return DefaultID
+}
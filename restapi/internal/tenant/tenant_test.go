@@ -0,0 +1,44 @@
+package tenant
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateID(t *testing.T) {
+	tests := []struct {
+		id      string
+		wantErr bool
+	}{
+		{id: "acme"},
+		{id: "acme-corp_2"},
+		{id: "1acme"},
+		{id: strings.Repeat("a", maxIDLength)},
+		{id: "", wantErr: true},
+		{id: strings.Repeat("a", maxIDLength+1), wantErr: true},
+		{id: "-acme", wantErr: true},
+		{id: "Acme", wantErr: true},
+		{id: "a/b", wantErr: true},
+		{id: "a b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if err := ValidateID(tt.id); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestIDFromContext(t *testing.T) {
+	if id := IDFromContext(context.Background()); id != DefaultID {
+		t.Errorf("expected the default tenant without a tenant, got %q", id)
+	}
+
+	ctx := ContextWithTenant(context.Background(), Tenant{ID: "acme"})
+	if id := IDFromContext(ctx); id != "acme" {
+		t.Errorf("expected tenant acme, got %q", id)
+	}
+}
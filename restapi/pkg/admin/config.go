@@ -0,0 +1,44 @@
+// Package admin contains the server for the operational endpoints of our API
+package admin // import "github.com/la4ezar/restapi/pkg/admin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config contains admin Server settings
+type Config struct {
+	Enabled         bool          `mapstructure:"enabled" description:"whether to start the admin server"`
+	Port            int           `mapstructure:"port" description:"port of the admin server"`
+	ExposeProbes    bool          `mapstructure:"expose_probes" description:"whether to serve the health and readiness checks on the admin server too"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdowntimeout" description:"time to wait for the admin server to shutdown"`
+}
+
+// DefaultConfig returns the default values for configuring the admin Server
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:      false,
+		Port:         8081,
+		ExposeProbes: true,
+
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+// Validate validates the admin server settings
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if c.Port <= 0 {
+		errs = append(errs, fmt.Errorf("validate Admin settings: Port missing"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("validate Admin settings: ShutdownTimeout missing"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strconv"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	LogLevelURL = "/admin/loglevel"
+	ConfigURL   = "/admin/config"
+)
+
+// Server is wrapped http.Server which serves pprof, expvar, runtime log level and the effective configuration
+type Server struct {
+	*http.Server
+
+	shutdownTimeout time.Duration
+}
+
+// Option configures the router of the admin Server
+type Option func(r *mux.Router)
+
+// WithHandler adds route which serves path with handler
+func WithHandler(method, path string, handler http.Handler) Option {
+	return func(r *mux.Router) {
+		r.Methods(method).Path(path).Handler(handler)
+	}
+}
+
+// New returns new admin Server with given configurations.
+// effectiveConfig is called on every request and must return the configuration with the secrets redacted.
+func New(cfg *Config, effectiveConfig func() interface{}, opts ...Option) *Server {
+	r := mux.NewRouter()
+
+	r.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	r.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	r.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	r.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	r.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+	r.Handle("/debug/vars", expvar.Handler())
+
+	r.Methods(http.MethodGet).Path(LogLevelURL).HandlerFunc(getLogLevel)
+	r.Methods(http.MethodPut).Path(LogLevelURL).HandlerFunc(setLogLevel)
+	r.Methods(http.MethodGet).Path(ConfigURL).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(r.Context(), w, http.StatusOK, effectiveConfig())
+	})
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return &Server{
+		Server: &http.Server{
+			Addr:              ":" + strconv.Itoa(cfg.Port),
+			Handler:           log.RequestLogger()(r),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		shutdownTimeout: cfg.ShutdownTimeout,
+	}
+}
+
+// Start starts the admin Server and blocks until ctx is done and the Server is shutdown or it fails to serve
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen on %s: %v", s.Addr, err)
+	}
+
+	log.C(ctx).Infof("Starting admin server and listening on %s\n", s.Addr)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("couldn't serve admin on %s: %v", s.Addr, err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	if err := s.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("couldn't gracefully shutdown the admin server: %v", err)
+	}
+
+	log.C(ctx).Infof("Admin server gracefully shutdown.")
+	return nil
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+func getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(r.Context(), w, http.StatusOK, logLevel{Level: log.Level()})
+}
+
+func setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var level logLevel
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&level); err != nil {
+		writeJSON(r.Context(), w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request body: %v", err)})
+		return
+	}
+
+	old := log.Level()
+	if err := log.SetLevel(level.Level); err != nil {
+		writeJSON(r.Context(), w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	log.C(r.Context()).Infof("Changed log level from %s to %s", old, log.Level())
+	writeJSON(r.Context(), w, http.StatusOK, logLevel{Level: log.Level()})
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.C(ctx).WithError(err).Error("an error occurred while encoding admin response")
+	}
+}
@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+func TestServer(t *testing.T) {
+	defer func(level string) { _ = log.SetLevel(level) }(log.Level())
+	if err := log.SetLevel("info"); err != nil {
+		t.Fatalf("set level: %v", err)
+	}
+
+	srv := New(DefaultConfig(), func() interface{} {
+		return map[string]string{"password": "******"}
+	}, WithHandler(http.MethodGet, "/api/ready", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+
+	// The steps run in order against the same server
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "get level", method: http.MethodGet, path: LogLevelURL, wantStatus: http.StatusOK, wantBody: `{"level":"info"}`},
+		{name: "set level", method: http.MethodPut, path: LogLevelURL, body: `{"level":"debug"}`, wantStatus: http.StatusOK, wantBody: `{"level":"debug"}`},
+		{name: "get changed level", method: http.MethodGet, path: LogLevelURL, wantStatus: http.StatusOK, wantBody: `{"level":"debug"}`},
+		{name: "set unknown level", method: http.MethodPut, path: LogLevelURL, body: `{"level":"verbose"}`, wantStatus: http.StatusBadRequest},
+		{name: "set malformed level", method: http.MethodPut, path: LogLevelURL, body: `{"level":`, wantStatus: http.StatusBadRequest},
+		{name: "config", method: http.MethodGet, path: ConfigURL, wantStatus: http.StatusOK, wantBody: `{"password":"******"}`},
+		{name: "pprof", method: http.MethodGet, path: "/debug/pprof/", wantStatus: http.StatusOK},
+		{name: "expvar", method: http.MethodGet, path: "/debug/vars", wantStatus: http.StatusOK},
+		{name: "extra handler", method: http.MethodGet, path: "/api/ready", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		srv.Handler.ServeHTTP(w, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.wantStatus, w.Code, w.Body.String())
+		}
+		if got := strings.TrimSpace(w.Body.String()); len(step.wantBody) != 0 && got != step.wantBody {
+			t.Errorf("%s: expected body %s, got %s", step.name, step.wantBody, got)
+		}
+	}
+
+	if got := log.Level(); got != "debug" {
+		t.Errorf("expected the level to stay debug after the invalid changes, got %s", got)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := DefaultConfig()
+	c.Port = 0
+	if err := c.Validate(); err != nil {
+		t.Errorf("expected the disabled admin server not to be validated, got %v", err)
+	}
+
+	c.Enabled = true
+	if err := c.Validate(); err == nil {
+		t.Error("expected error without Port")
+	}
+}
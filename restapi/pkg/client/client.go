@@ -5,10 +5,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 
 	"github.com/la4ezar/restapi/internal/crypto"
 	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/tlsutil"
+	"github.com/la4ezar/restapi/pkg/tracing"
 
 	"github.com/sirupsen/logrus"
 )
@@ -23,16 +26,23 @@ type Client struct {
 	Endpoints map[string]string
 }
 
-func New(c *Config) *Client {
+// New returns new Client with given configurations
+func New(c *Config) (*Client, error) {
+	tlsConfig, err := tlsutil.ClientConfig(c.TLS.CAFile, c.TLS.CertFile, c.TLS.KeyFile, c.TLS.ServerName, c.TLS.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure client TLS: %v", err)
+	}
+
 	return &Client{
 		Client: &http.Client{
 			Timeout: c.Timeout,
-			Transport: &http.Transport{
+			Transport: tracing.NewTransport(&http.Transport{
 				DisableKeepAlives: c.DisableKeepAlives,
-			},
+				TLSClientConfig:   tlsConfig,
+			}),
 		},
 		Endpoints: c.Endpoints,
-	}
+	}, nil
 }
 
 // GetCryptos sends GET HTTP request to URL
@@ -40,18 +50,16 @@ func New(c *Config) *Client {
 func (c *Client) GetCryptos() {
 	url := c.Endpoints["getcryptos"]
 
-	request, err := http.NewRequest(http.MethodGet, url, nil)
-	logOnError("An error occurred while creating GET request.", err)
+	request, logger := newRequest(http.MethodGet, url, nil)
 
-	setHeaders(request)
 	response, err := c.Do(request)
-	logOnError(fmt.Sprintf("An error occured while making GET request to %s", url), err)
+	logOnError(logger, fmt.Sprintf("An error occured while making GET request to %s", url), err)
 	defer func() {
 		err := response.Body.Close()
-		logOnError("An error occurred while closing response body", err)
+		logOnError(logger, "An error occurred while closing response body", err)
 	}()
 
-	logAllCryptos(response)
+	logAllCryptos(logger, response)
 }
 
 // GetCrypto sends GET HTTP request to URL/{cryptoId}
@@ -60,18 +68,16 @@ func (c *Client) GetCryptos() {
 func (c *Client) GetCrypto(cryptoID string) {
 	url := c.Endpoints["getcrypto"] + cryptoID
 
-	request, err := http.NewRequest(http.MethodGet, url, nil)
-	logOnError("An error occurred while creating GET request.", err)
+	request, logger := newRequest(http.MethodGet, url, nil)
 
-	setHeaders(request)
 	response, err := c.Do(request)
-	logOnError(fmt.Sprintf("An error occured while making GET request to %s", url), err)
+	logOnError(logger, fmt.Sprintf("An error occured while making GET request to %s", url), err)
 	defer func() {
 		err := response.Body.Close()
-		logOnError("An error occurred while closing response body", err)
+		logOnError(logger, "An error occurred while closing response body", err)
 	}()
 
-	logSingleCrypto(response)
+	logSingleCrypto(logger, response)
 }
 
 // PostCrypto sends POST HTTP request to URL and creates new crypto - crypto
@@ -79,20 +85,18 @@ func (c *Client) PostCrypto(crypto Cryptocurrency) {
 	url := c.Endpoints["postcrypto"]
 
 	requestBody, err := json.Marshal(crypto)
-	logOnError("An error occurred while marshalling crypto", err)
+	logOnError(log.D(), "An error occurred while marshalling crypto", err)
 
-	request, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(requestBody))
-	logOnError("An error occurred while creating POST request.", err)
+	request, logger := newRequest(http.MethodPost, url, bytes.NewBuffer(requestBody))
 
-	setHeaders(request)
 	response, err := c.Do(request)
-	logOnError(fmt.Sprintf("An error occured while making POST request to %s", url), err)
+	logOnError(logger, fmt.Sprintf("An error occured while making POST request to %s", url), err)
 	defer func() {
 		err := response.Body.Close()
-		logOnError("An error occurred while closing response body", err)
+		logOnError(logger, "An error occurred while closing response body", err)
 	}()
 
-	logSingleCrypto(response)
+	logSingleCrypto(logger, response)
 }
 
 // PutCrypto sends a PUT HTTP request to URL/{crypto.cryptoID}
@@ -101,20 +105,18 @@ func (c *Client) PutCrypto(crypto Cryptocurrency) {
 	url := c.Endpoints["putcrypto"] + crypto.CryptoID
 
 	requestBody, err := json.Marshal(crypto)
-	logOnError("An error occurred while marshalling crypto", err)
+	logOnError(log.D(), "An error occurred while marshalling crypto", err)
 
-	request, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(requestBody))
-	logOnError("An error occurred while creating PUT request.", err)
+	request, logger := newRequest(http.MethodPut, url, bytes.NewBuffer(requestBody))
 
-	setHeaders(request)
 	response, err := c.Do(request)
-	logOnError(fmt.Sprintf("An error occured while making PUT request to %s", url), err)
+	logOnError(logger, fmt.Sprintf("An error occured while making PUT request to %s", url), err)
 	defer func() {
 		err := response.Body.Close()
-		logOnError("An error occurred while closing response body", err)
+		logOnError(logger, "An error occurred while closing response body", err)
 	}()
 
-	logAllCryptos(response)
+	logAllCryptos(logger, response)
 }
 
 // DeleteCrypto sends a DELETE HTTP request to URL/{cryptoID}
@@ -122,18 +124,16 @@ func (c *Client) PutCrypto(crypto Cryptocurrency) {
 func (c *Client) DeleteCrypto(cryptoID string) {
 	url := c.Endpoints["deletecrypto"] + cryptoID
 
-	request, err := http.NewRequest(http.MethodDelete, url, nil)
-	logOnError("An error occurred while creating DELETE request.", err)
+	request, logger := newRequest(http.MethodDelete, url, nil)
 
-	setHeaders(request)
 	response, err := c.Do(request)
-	logOnError(fmt.Sprintf("An error occured while making DELETE request to %s", url), err)
+	logOnError(logger, fmt.Sprintf("An error occured while making DELETE request to %s", url), err)
 	defer func() {
 		err := response.Body.Close()
-		logOnError("An error occurred while closing response body", err)
+		logOnError(logger, "An error occurred while closing response body", err)
 	}()
 
-	logAllCryptos(response)
+	logAllCryptos(logger, response)
 }
 
 // HealthCheck sends a GET HTTP request to URL/health
@@ -141,42 +141,59 @@ func (c *Client) DeleteCrypto(cryptoID string) {
 func (c *Client) HealthCheck() {
 	url := c.Endpoints["healthcheck"]
 
-	request, err := http.NewRequest(http.MethodGet, url, nil)
-	logOnError("An error occurred while creating GET request.", err)
+	request, logger := newRequest(http.MethodGet, url, nil)
 
-	setHeaders(request)
 	response, err := c.Do(request)
-	logOnError(fmt.Sprintf("An error occured while making GET request to %s", url), err)
+	logOnError(logger, fmt.Sprintf("An error occured while making GET request to %s", url), err)
 	defer func() {
 		err := response.Body.Close()
-		logOnError("An error occurred while closing response body", err)
+		logOnError(logger, "An error occurred while closing response body", err)
 	}()
 
-	log.D().WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"status code": response.StatusCode,
 	}).Info("Health Check...")
 }
 
-// logOnError logs error message if err is not nil
-func logOnError(msg string, err error) {
+// logOnError logs error message with logger if err is not nil
+func logOnError(logger *logrus.Entry, msg string, err error) {
 	if err != nil {
-		log.D().WithError(err).Errorf("%s: %v", msg, err)
+		logger.WithError(err).Errorf("%s: %v", msg, err)
 	}
 }
 
+// newRequest creates http.Request with new request ID
+// and returns it together with logger which logs the request ID
+func newRequest(method, url string, body io.Reader) (*http.Request, *logrus.Entry) {
+	requestID := log.NewRequestID()
+	logger := log.D().WithField("request_id", requestID)
+
+	request, err := http.NewRequest(method, url, body)
+	logOnError(logger, fmt.Sprintf("An error occurred while creating %s request.", method), err)
+
+	setHeaders(request, requestID)
+	logger.WithFields(logrus.Fields{
+		"method": method,
+		"url":    url,
+	}).Info("Sending request...")
+
+	return request, logger
+}
+
 // setHeaders sets http.Request headers
-func setHeaders(r *http.Request) {
+func setHeaders(r *http.Request, requestID string) {
 	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set(log.RequestIDHeader, requestID)
 }
 
 // logAllCryptos decodes the cryptos in http.Response Body and logs them
-func logAllCryptos(response *http.Response) {
-	log.D().Info("Server response...")
+func logAllCryptos(logger *logrus.Entry, response *http.Response) {
+	logger.Info("Server response...")
 
 	var cryptos []Cryptocurrency
 	_ = json.NewDecoder(response.Body).Decode(&cryptos)
 	for _, crypto := range cryptos {
-		log.D().WithFields(logrus.Fields{
+		logger.WithFields(logrus.Fields{
 			"name":     crypto.Name,
 			"cryptoid": crypto.CryptoID,
 			"price":    crypto.Price,
@@ -186,12 +203,12 @@ func logAllCryptos(response *http.Response) {
 }
 
 // logSingleCrypto decodes the crypto in http.Response Body and logs it
-func logSingleCrypto(response *http.Response) {
-	log.D().Info("Server response...")
+func logSingleCrypto(logger *logrus.Entry, response *http.Response) {
+	logger.Info("Server response...")
 
 	var crypto Cryptocurrency
 	_ = json.NewDecoder(response.Body).Decode(&crypto)
-	log.D().WithFields(logrus.Fields{
+	logger.WithFields(logrus.Fields{
 		"name":     crypto.Name,
 		"cryptoid": crypto.CryptoID,
 		"price":    crypto.Price,
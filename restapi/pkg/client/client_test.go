@@ -0,0 +1,32 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+func TestRequestID(t *testing.T) {
+	var requestIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestIDs = append(requestIDs, r.Header.Get(log.RequestIDHeader))
+		_, _ = w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	c := DefaultConfig()
+	c.Endpoints["getcryptos"] = srv.URL
+	client, err := New(c)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	client.GetCryptos()
+	client.GetCryptos()
+
+	if len(requestIDs) != 2 || len(requestIDs[0]) == 0 || requestIDs[0] == requestIDs[1] {
+		t.Errorf("expected new request ID for every request, got %q", requestIDs)
+	}
+}
@@ -9,13 +9,31 @@ type Config struct {
 	Endpoints         map[string]string `mapstructure:"endpoints" description:"All client http requests endpoints"`
 	Timeout           time.Duration     `mapstructure:"timeout" description:"Client timeout"`
 	DisableKeepAlives bool              `mapstructure:"disable_keep_alives" description:"Whether to disable http keep-alives"`
+	TLS               *TLSConfig        `mapstructure:"tls" description:"TLS settings of the client"`
+}
+
+// TLSConfig contains Client TLS settings
+type TLSConfig struct {
+	CAFile             string `mapstructure:"ca_file" description:"path to the PEM encoded CA which signed the server certificate, system CAs if empty"`
+	CertFile           string `mapstructure:"cert_file" description:"path to the PEM encoded client certificate for mTLS"`
+	KeyFile            string `mapstructure:"key_file" description:"path to the PEM encoded client private key for mTLS"`
+	ServerName         string `mapstructure:"server_name" description:"name to verify the server certificate against, the host of the URL if empty"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify" description:"Whether to skip the server certificate verification"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Endpoints:         make(map[string]string),
+		Endpoints: map[string]string{
+			"getcryptos":   "http://localhost:8080/api/cryptos",
+			"getcrypto":    "http://localhost:8080/api/cryptos",
+			"postcrypto":   "http://localhost:8080/api/cryptos",
+			"putcrypto":    "http://localhost:8080/api/cryptos",
+			"deletecrypto": "http://localhost:8080/api/cryptos",
+			"healthcheck":  "http://localhost:8080/api/health",
+		},
 		Timeout:           15 * time.Second,
 		DisableKeepAlives: false,
+		TLS:               &TLSConfig{},
 	}
 }
 
@@ -26,6 +44,12 @@ func (c *Config) Validate() error {
 	if c.Timeout <= 0*time.Second {
 		return fmt.Errorf("validate Client settings: Timeout missing")
 	}
+	if c.TLS == nil {
+		return fmt.Errorf("validate Client settings: TLS missing")
+	}
+	if (len(c.TLS.CertFile) == 0) != (len(c.TLS.KeyFile) == 0) {
+		return fmt.Errorf("validate Client settings: TLS CertFile and KeyFile must be set together")
+	}
 
 	return nil
 }
@@ -1,9 +1,13 @@
 package controller
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"sync/atomic"
 
 	"github.com/la4ezar/restapi/internal/routes"
 
@@ -11,8 +15,12 @@ import (
 	"github.com/la4ezar/restapi/internal/crypto"
 	"github.com/la4ezar/restapi/pkg/log"
 	"github.com/la4ezar/restapi/pkg/storage"
+
+	"go.opentelemetry.io/otel"
 )
 
+var tracer = otel.Tracer("github.com/la4ezar/restapi/pkg/controller")
+
 type Route struct {
 	Name    string
 	Method  string
@@ -24,6 +32,20 @@ type Routes []Route
 
 type Controller struct {
 	repository storage.Repository
+	strictJSON bool
+
+	// draining is shared between the copies of the Controller
+	draining *int32
+}
+
+// Option configures the Controller
+type Option func(c *Controller)
+
+// WithStrictJSON makes the Controller reject request bodies with unknown fields or data after the JSON document
+func WithStrictJSON(strict bool) Option {
+	return func(c *Controller) {
+		c.strictJSON = strict
+	}
 }
 
 // getCryptos returns http.HandlerFunc
@@ -32,16 +54,19 @@ func (c *Controller) getAll() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 
 		setHeaders(&w)
 
-		cryptos, err := c.repository.GetAllCryptos()
-		logOnError("an error occurred while getting all cryptos from repository", err)
+		cryptos, err := c.repository.GetAllCryptos(r.Context())
+		if err != nil {
+			fail(w, r, "an error occurred while getting all cryptos from repository", err)
+			return
+		}
 
-		err = json.NewEncoder(w).Encode(cryptos) // Response with all cryptos
-		logOnError("an error occurred while encoding cryptos", err)
+		err = encode(r.Context(), w, cryptos) // Response with all cryptos
+		logOnError(r.Context(), "an error occurred while encoding cryptos", err)
 	}
 }
 
@@ -51,18 +76,21 @@ func (c *Controller) getByCryptoID() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 
 		setHeaders(&w)
 
 		params := mux.Vars(r)
 
-		crypto, err := c.repository.GetSingleCrypto(params["crypto_id"])
-		logOnError(fmt.Sprintf("an error occurred while getting crypto with CryptoID=%s from repository", params["crypto_id"]), err)
+		crypto, err := c.repository.GetSingleCrypto(r.Context(), params["crypto_id"])
+		if err != nil {
+			fail(w, r, fmt.Sprintf("an error occurred while getting crypto with CryptoID=%s from repository", params["crypto_id"]), err)
+			return
+		}
 
-		err = json.NewEncoder(w).Encode(crypto)
-		logOnError("an error occurred while encoding crypto", err)
+		err = encode(r.Context(), w, crypto)
+		logOnError(r.Context(), "an error occurred while encoding crypto", err)
 	}
 }
 
@@ -73,19 +101,24 @@ func (c *Controller) add() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 
 		setHeaders(&w)
 
 		var crypto crypto.Cryptocurrency
-		_ = json.NewDecoder(r.Body).Decode(&crypto)
+		if err := c.decode(r, &crypto); err != nil {
+			c.badRequest(w, r, err)
+			return
+		}
 
-		err := c.repository.AddCrypto(crypto)
-		logOnError("an error occurred while adding crypto to repository", err)
+		if err := c.repository.AddCrypto(r.Context(), crypto); err != nil {
+			fail(w, r, "an error occurred while adding crypto to repository", err)
+			return
+		}
 
-		err = json.NewEncoder(w).Encode(crypto) // Response with the new crypto
-		logOnError("an error occurred while encoding crypto", err)
+		err := encode(r.Context(), w, crypto) // Response with the new crypto
+		logOnError(r.Context(), "an error occurred while encoding crypto", err)
 	}
 }
 
@@ -96,7 +129,7 @@ func (c *Controller) update() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 
 		setHeaders(&w)
@@ -104,16 +137,24 @@ func (c *Controller) update() http.HandlerFunc {
 		params := mux.Vars(r)
 
 		newCrypto := crypto.Cryptocurrency{}
-		_ = json.NewDecoder(r.Body).Decode(&newCrypto)
+		if err := c.decode(r, &newCrypto); err != nil {
+			c.badRequest(w, r, err)
+			return
+		}
 
-		err := c.repository.UpdateCrypto(params["crypto_id"], newCrypto)
-		logOnError("an error occurred while updating crypto in repository", err)
+		if err := c.repository.UpdateCrypto(r.Context(), params["crypto_id"], newCrypto); err != nil {
+			fail(w, r, "an error occurred while updating crypto in repository", err)
+			return
+		}
 
-		cryptos, err := c.repository.GetAllCryptos()
-		logOnError("an error occurred while getting all cryptos from repository", err)
+		cryptos, err := c.repository.GetAllCryptos(r.Context())
+		if err != nil {
+			fail(w, r, "an error occurred while getting all cryptos from repository", err)
+			return
+		}
 
-		err = json.NewEncoder(w).Encode(cryptos)
-		logOnError("an error occurred while encoding cryptos", err)
+		err = encode(r.Context(), w, cryptos)
+		logOnError(r.Context(), "an error occurred while encoding cryptos", err)
 
 	}
 }
@@ -125,20 +166,25 @@ func (c *Controller) remove() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 		setHeaders(&w)
 
 		params := mux.Vars(r)
 
-		err := c.repository.RemoveCrypto(params["crypto_id"])
-		logOnError("an error occurred while deleting crypto from repository", err)
+		if err := c.repository.RemoveCrypto(r.Context(), params["crypto_id"]); err != nil {
+			fail(w, r, "an error occurred while deleting crypto from repository", err)
+			return
+		}
 
-		cryptos, err := c.repository.GetAllCryptos()
-		logOnError("an error occurred while getting all cryptos from repository", err)
+		cryptos, err := c.repository.GetAllCryptos(r.Context())
+		if err != nil {
+			fail(w, r, "an error occurred while getting all cryptos from repository", err)
+			return
+		}
 
-		err = json.NewEncoder(w).Encode(cryptos) // Response with all cryptos
-		logOnError("an error occurred while encoding cryptos", err)
+		err = encode(r.Context(), w, cryptos) // Response with all cryptos
+		logOnError(r.Context(), "an error occurred while encoding cryptos", err)
 	}
 }
 
@@ -146,7 +192,7 @@ func (c *Controller) healthCheck() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 
 		setHeaders(&w)
@@ -159,11 +205,16 @@ func (c *Controller) readinessCheck() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			err := r.Body.Close()
-			logOnError("an error occurred while closing request body", err)
+			logOnError(r.Context(), "an error occurred while closing request body", err)
 		}()
 
 		setHeaders(&w)
 
+		if atomic.LoadInt32(c.draining) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
 		if err := c.repository.PingWithContext(r.Context()); err != nil {
 			w.WriteHeader(http.StatusServiceUnavailable)
 		} else {
@@ -172,6 +223,77 @@ func (c *Controller) readinessCheck() http.HandlerFunc {
 	}
 }
 
+// decode decodes the JSON request body in v
+func (c *Controller) decode(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	if c.strictJSON {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+
+	if c.strictJSON {
+		if err := decoder.Decode(&struct{}{}); err != io.EOF {
+			return errors.New("request body must contain a single JSON document")
+		}
+	}
+
+	return nil
+}
+
+// badRequest responds with the error which occurred while decoding the request body
+func (c *Controller) badRequest(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusBadRequest
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		status = http.StatusRequestEntityTooLarge
+	}
+
+	log.C(r.Context()).WithError(err).Warn("an error occurred while decoding request body")
+	WriteError(w, status, fmt.Sprintf("invalid request body: %v", err))
+}
+
+// fail responds with the status for the error returned by the repository
+// and logs msg with the errors which aren't caused by the request
+func fail(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrCryptoNotFound), errors.Is(err, storage.ErrTenantNotFound):
+		WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, storage.ErrCryptoAlreadyExists), errors.Is(err, storage.ErrTenantAlreadyExists),
+		errors.Is(err, storage.ErrDefaultTenant):
+		WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, storage.ErrTenantReadOnly):
+		WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		WriteError(w, http.StatusGatewayTimeout, err.Error())
+	case errors.Is(err, context.Canceled):
+		WriteError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		logOnError(r.Context(), msg, err)
+		WriteError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// WriteError responds with status and JSON body containing msg
+func WriteError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+	}{Error: msg})
+}
+
+// encode encodes v as JSON in the http.ResponseWriter in its own span
+func encode(ctx context.Context, w http.ResponseWriter, v interface{}) error {
+	_, span := tracer.Start(ctx, "JSON encode")
+	defer span.End()
+
+	return json.NewEncoder(w).Encode(v)
+}
+
 // setHeaders sets http.ResponseWriter headers
 func setHeaders(w *http.ResponseWriter) {
 	// Set Content-Type to accept json
@@ -179,16 +301,26 @@ func setHeaders(w *http.ResponseWriter) {
 }
 
 // logOnError logs error message if err is not nil
-func logOnError(msg string, err error) {
+func logOnError(ctx context.Context, msg string, err error) {
 	if err != nil {
-		log.D().WithError(err).Errorf("%s: %v", msg, err)
+		log.C(ctx).WithError(err).Errorf("%s: %v", msg, err)
 	}
 }
 
-func NewController(repository storage.Repository) *Controller {
-	return &Controller{
+func NewController(repository storage.Repository, opts ...Option) *Controller {
+	c := &Controller{
 		repository: repository,
+		draining:   new(int32),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	return c
+}
+
+// Drain makes the readiness check fail so that no new requests are routed to the instance before it shuts down
+func (c *Controller) Drain() {
+	atomic.StoreInt32(c.draining, 1)
 }
 
 func (c *Controller) Routes() *Routes {
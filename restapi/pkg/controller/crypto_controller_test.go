@@ -0,0 +1,176 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/pkg/metrics"
+	"github.com/la4ezar/restapi/pkg/storage"
+	"github.com/la4ezar/restapi/pkg/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	os.Exit(m.Run())
+}
+
+// stubRepository is a storage.Repository which takes delay to read the cryptos
+// and returns err from every call, accepting every new crypto when it is nil
+type stubRepository struct {
+	storage.Repository
+
+	delay time.Duration
+	err   error
+}
+
+func (r *stubRepository) GetAllCryptos(_ context.Context) ([]crypto.Cryptocurrency, error) {
+	time.Sleep(r.delay)
+	return []crypto.Cryptocurrency{}, r.err
+}
+
+func (r *stubRepository) GetSingleCrypto(_ context.Context, _ string) (crypto.Cryptocurrency, error) {
+	return crypto.Cryptocurrency{}, r.err
+}
+
+func (r *stubRepository) AddCrypto(_ context.Context, _ crypto.Cryptocurrency) error {
+	return r.err
+}
+
+func (r *stubRepository) UpdateCrypto(_ context.Context, _ string, _ crypto.Cryptocurrency) error {
+	return r.err
+}
+
+func (r *stubRepository) RemoveCrypto(_ context.Context, _ string) error {
+	return r.err
+}
+
+func TestServerTiming(t *testing.T) {
+	const delay = 20 * time.Millisecond
+
+	c := NewController(metrics.NewRepository(&stubRepository{delay: delay}))
+	handler := metrics.Middleware(true)(c.getAll())
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/cryptos", nil))
+
+	timing := regexp.MustCompile(`db;desc="Database";dur=(\d+\.\d{3})`).FindStringSubmatch(w.Header().Get("Server-Timing"))
+	if timing == nil {
+		t.Fatalf("expected Server-Timing with the database time, got %q", w.Header().Get("Server-Timing"))
+	}
+	if dur, _ := strconv.ParseFloat(timing[1], 64); dur < float64(delay/time.Millisecond) {
+		t.Errorf("expected at least %v in the database, got %sms", delay, timing[1])
+	}
+}
+
+func TestTracing(t *testing.T) {
+	exporter.Reset()
+
+	c := NewController(tracing.NewRepository(&stubRepository{}))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /api/cryptos")
+	c.getAll().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/cryptos", nil).WithContext(ctx))
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for i, want := range []string{"Repository.GetAllCryptos", "JSON encode"} {
+		if spans[i].Name != want || spans[i].Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected span %q in the request span, got %q in %s", want, spans[i].Name, spans[i].Parent.SpanID())
+		}
+	}
+}
+
+func TestDecodeStrictJSON(t *testing.T) {
+	const valid = `{"name":"Bitcoin","crypto_id":"BTC","price":45000.94}`
+
+	tests := []struct {
+		name       string
+		strict     bool
+		body       string
+		maxBytes   int64
+		wantStatus int
+	}{
+		{name: "valid", strict: true, body: valid, wantStatus: http.StatusOK},
+		{name: "unknown field", strict: true, body: `{"name":"Bitcoin","crypto_id":"BTC","price":1,"ticker":"BTC"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field without strict", body: `{"name":"Bitcoin","crypto_id":"BTC","price":1,"ticker":"BTC"}`, wantStatus: http.StatusOK},
+		{name: "trailing document", strict: true, body: valid + valid, wantStatus: http.StatusBadRequest},
+		{name: "trailing document without strict", body: valid + valid, wantStatus: http.StatusOK},
+		{name: "trailing whitespace", strict: true, body: valid + "\n", wantStatus: http.StatusOK},
+		{name: "malformed", strict: true, body: `{"name":`, wantStatus: http.StatusBadRequest},
+		{name: "too large", strict: true, body: valid, maxBytes: 10, wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(&stubRepository{}, WithStrictJSON(tt.strict))
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/cryptos", strings.NewReader(tt.body))
+			if tt.maxBytes > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, tt.maxBytes)
+			}
+			c.add().ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRepositoryErrors(t *testing.T) {
+	const body = `{"name":"Bitcoin","crypto_id":"BTC","price":45000.94}`
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{name: "no error", wantStatus: http.StatusOK},
+		{name: "not found", err: fmt.Errorf("query crypto: %w", storage.ErrCryptoNotFound), wantStatus: http.StatusNotFound},
+		{name: "already exists", err: fmt.Errorf("insert crypto: %w", storage.ErrCryptoAlreadyExists), wantStatus: http.StatusConflict},
+		{name: "deadline exceeded", err: fmt.Errorf("query cryptos exceeded the query timeout (%w)", context.DeadlineExceeded), wantStatus: http.StatusGatewayTimeout},
+		{name: "canceled", err: fmt.Errorf("query cryptos: %w", context.Canceled), wantStatus: http.StatusServiceUnavailable},
+		{name: "unexpected", err: errors.New("connection refused"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		c := NewController(&stubRepository{err: tt.err})
+		handlers := map[string]http.HandlerFunc{
+			http.MethodGet:    c.getByCryptoID(),
+			http.MethodPost:   c.add(),
+			http.MethodPut:    c.update(),
+			http.MethodDelete: c.remove(),
+		}
+
+		for method, handler := range handlers {
+			t.Run(tt.name+"/"+method, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(method, "/api/cryptos/BTC", strings.NewReader(body)))
+
+				if w.Code != tt.wantStatus {
+					t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+				}
+			})
+		}
+	}
+}
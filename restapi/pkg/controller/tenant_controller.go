@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/la4ezar/restapi/internal/routes"
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"github.com/gorilla/mux"
+)
+
+// TenantController serves the tenants and their settings. Unlike the cryptos,
+// the failures are reported by the status of the responses.
+type TenantController struct {
+	repository storage.TenantRepository
+}
+
+func NewTenantController(repository storage.TenantRepository) *TenantController {
+	return &TenantController{
+		repository: repository,
+	}
+}
+
+// getAll returns http.HandlerFunc which encodes all the tenants in the http.ResponseWriter
+func (c *TenantController) getAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenants, err := c.repository.GetAllTenants(r.Context())
+		if err != nil {
+			fail(w, r, "an error occurred while getting all tenants from repository", err)
+			return
+		}
+		if tenants == nil {
+			tenants = []tenant.Tenant{}
+		}
+
+		c.write(w, r, http.StatusOK, tenants)
+	}
+}
+
+// get returns http.HandlerFunc which encodes the requested tenant in the http.ResponseWriter
+func (c *TenantController) get() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, err := c.repository.GetTenant(r.Context(), mux.Vars(r)["tenant_id"])
+		if err != nil {
+			fail(w, r, "an error occurred while getting tenant from repository", err)
+			return
+		}
+
+		c.write(w, r, http.StatusOK, t)
+	}
+}
+
+// add returns http.HandlerFunc which adds the tenant in the request body and encodes it in the http.ResponseWriter
+func (c *TenantController) add() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := c.decode(w, r)
+		if !ok {
+			return
+		}
+
+		if err := c.repository.AddTenant(r.Context(), t); err != nil {
+			fail(w, r, "an error occurred while adding tenant to repository", err)
+			return
+		}
+
+		log.C(r.Context()).Infof("Tenant %s added", t.ID)
+		c.write(w, r, http.StatusCreated, t)
+	}
+}
+
+// update returns http.HandlerFunc which replaces the name and the settings of the tenant in the path
+// with the ones in the request body and encodes it in the http.ResponseWriter
+func (c *TenantController) update() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, ok := c.decode(w, r)
+		if !ok {
+			return
+		}
+
+		if err := c.repository.UpdateTenant(r.Context(), t); err != nil {
+			fail(w, r, "an error occurred while updating tenant in repository", err)
+			return
+		}
+
+		log.C(r.Context()).Infof("Tenant %s updated", t.ID)
+		c.write(w, r, http.StatusOK, t)
+	}
+}
+
+// remove returns http.HandlerFunc which removes the tenant in the path with all its cryptos
+func (c *TenantController) remove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["tenant_id"]
+		if err := c.repository.RemoveTenant(r.Context(), id); err != nil {
+			fail(w, r, "an error occurred while deleting tenant from repository", err)
+			return
+		}
+
+		log.C(r.Context()).Infof("Tenant %s removed", id)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// decode decodes the tenant in the request body and responds with the error if it is not a valid tenant.
+// Unknown fields are rejected, so that misspelled settings are not silently ignored.
+func (c *TenantController) decode(w http.ResponseWriter, r *http.Request) (tenant.Tenant, bool) {
+	defer func() {
+		err := r.Body.Close()
+		logOnError(r.Context(), "an error occurred while closing request body", err)
+	}()
+
+	var t tenant.Tenant
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&t)
+	if err == nil && decoder.Decode(&struct{}{}) != io.EOF {
+		err = errors.New("request body must contain a single JSON document")
+	}
+	if err != nil {
+		log.C(r.Context()).WithError(err).Warn("an error occurred while decoding request body")
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return t, false
+	}
+
+	if id, ok := mux.Vars(r)["tenant_id"]; ok {
+		t.ID = id
+	}
+	if err := storage.ValidateTenant(t); err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid tenant: %v", err))
+		return t, false
+	}
+
+	return t, true
+}
+
+// write responds with status and v encoded as JSON
+func (c *TenantController) write(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	setHeaders(&w)
+	w.WriteHeader(status)
+
+	err := encode(r.Context(), w, v)
+	logOnError(r.Context(), "an error occurred while encoding tenants", err)
+}
+
+func (c *TenantController) Routes() *Routes {
+	return &Routes{
+		{
+			Name:    "Get all tenants",
+			Method:  http.MethodGet,
+			Path:    routes.TenantsURL,
+			Handler: c.getAll(),
+		},
+		{
+			Name:    "Get specific tenant",
+			Method:  http.MethodGet,
+			Path:    routes.SingleTenantURL,
+			Handler: c.get(),
+		},
+		{
+			Name:    "Create tenant",
+			Method:  http.MethodPost,
+			Path:    routes.TenantsURL,
+			Handler: c.add(),
+		},
+		{
+			Name:    "Update existing tenant",
+			Method:  http.MethodPut,
+			Path:    routes.SingleTenantURL,
+			Handler: c.update(),
+		},
+		{
+			Name:    "Remove existing tenant",
+			Method:  http.MethodDelete,
+			Path:    routes.SingleTenantURL,
+			Handler: c.remove(),
+		},
+	}
+}
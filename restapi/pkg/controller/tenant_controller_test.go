@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"github.com/gorilla/mux"
+)
+
+func newTestTenantRouter(t *testing.T) *mux.Router {
+	c := storage.DefaultConfig()
+	c.Type = storage.TypeMemory
+	s, err := storage.New(context.Background(), c)
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+
+	router := mux.NewRouter()
+	for _, route := range *NewTenantController(storage.NewTenantRepository(*s)).Routes() {
+		router.Methods(route.Method).Path(route.Path).Handler(route.Handler)
+	}
+	return router
+}
+
+func TestTenantController(t *testing.T) {
+	const acme = `{"id":"acme","name":"Acme","settings":{"decimals":2}}`
+
+	// The steps run in order against the same storage
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "list default", method: http.MethodGet, path: "/admin/tenants", wantStatus: http.StatusOK, wantBody: `"id":"default"`},
+		{name: "get missing", method: http.MethodGet, path: "/admin/tenants/acme", wantStatus: http.StatusNotFound},
+		{name: "add", method: http.MethodPost, path: "/admin/tenants", body: acme, wantStatus: http.StatusCreated, wantBody: `"decimals":2`},
+		{name: "add existing", method: http.MethodPost, path: "/admin/tenants", body: acme, wantStatus: http.StatusConflict},
+		{name: "add invalid ID", method: http.MethodPost, path: "/admin/tenants", body: `{"id":"a/b","name":"AB"}`, wantStatus: http.StatusBadRequest},
+		{name: "add without name", method: http.MethodPost, path: "/admin/tenants", body: `{"id":"globex"}`, wantStatus: http.StatusBadRequest},
+		{name: "add invalid decimals", method: http.MethodPost, path: "/admin/tenants", body: `{"id":"globex","name":"Globex","settings":{"decimals":-1}}`, wantStatus: http.StatusBadRequest},
+		{name: "add unknown setting", method: http.MethodPost, path: "/admin/tenants", body: `{"id":"globex","name":"Globex","settings":{"decimal":2}}`, wantStatus: http.StatusBadRequest},
+		{name: "get", method: http.MethodGet, path: "/admin/tenants/acme", wantStatus: http.StatusOK, wantBody: `"name":"Acme"`},
+		{name: "update", method: http.MethodPut, path: "/admin/tenants/acme", body: `{"name":"Acme Corp","settings":{"read_only":true}}`, wantStatus: http.StatusOK, wantBody: `"id":"acme"`},
+		{name: "get updated", method: http.MethodGet, path: "/admin/tenants/acme", wantStatus: http.StatusOK, wantBody: `"read_only":true`},
+		{name: "update missing", method: http.MethodPut, path: "/admin/tenants/globex", body: `{"name":"Globex"}`, wantStatus: http.StatusNotFound},
+		{name: "remove default", method: http.MethodDelete, path: "/admin/tenants/default", wantStatus: http.StatusConflict},
+		{name: "remove", method: http.MethodDelete, path: "/admin/tenants/acme", wantStatus: http.StatusNoContent},
+		{name: "remove missing", method: http.MethodDelete, path: "/admin/tenants/acme", wantStatus: http.StatusNotFound},
+		{name: "get removed", method: http.MethodGet, path: "/admin/tenants/acme", wantStatus: http.StatusNotFound},
+	}
+
+	router := newTestTenantRouter(t)
+	for _, step := range steps {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(step.method, step.path, strings.NewReader(step.body)))
+
+		if w.Code != step.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", step.name, step.wantStatus, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), step.wantBody) {
+			t.Errorf("%s: expected the body to contain %s, got %s", step.name, step.wantBody, w.Body.String())
+		}
+	}
+}
+
+func TestControllerTenantErrors(t *testing.T) {
+	const body = `{"name":"Bitcoin","crypto_id":"BTC","price":45000.94}`
+
+	c := storage.DefaultConfig()
+	c.Type = storage.TypeMemory
+	s, err := storage.New(context.Background(), c)
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+	acme := tenant.Tenant{ID: "acme", Name: "Acme", Settings: tenant.Settings{ReadOnly: true}}
+	if err := storage.NewTenantRepository(*s).AddTenant(context.Background(), acme); err != nil {
+		t.Fatalf("add tenant: %v", err)
+	}
+
+	router := mux.NewRouter()
+	for _, route := range *NewController(storage.NewRepository(*s)).Routes() {
+		router.Methods(route.Method).Path(route.Path).Handler(route.Handler)
+	}
+
+	tests := []struct {
+		name       string
+		tenant     tenant.Tenant
+		method     string
+		path       string
+		wantStatus int
+	}{
+		{name: "read-only list", tenant: acme, method: http.MethodGet, path: "/api/cryptos", wantStatus: http.StatusOK},
+		{name: "read-only add", tenant: acme, method: http.MethodPost, path: "/api/cryptos", wantStatus: http.StatusForbidden},
+		{name: "read-only update", tenant: acme, method: http.MethodPut, path: "/api/cryptos/BTC", wantStatus: http.StatusForbidden},
+		{name: "read-only remove", tenant: acme, method: http.MethodDelete, path: "/api/cryptos/BTC", wantStatus: http.StatusForbidden},
+		{name: "missing list", tenant: tenant.Tenant{ID: "globex"}, method: http.MethodGet, path: "/api/cryptos", wantStatus: http.StatusNotFound},
+		{name: "missing add", tenant: tenant.Tenant{ID: "globex"}, method: http.MethodPost, path: "/api/cryptos", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tenant.ContextWithTenant(context.Background(), tt.tenant)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(body)).WithContext(ctx))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+}
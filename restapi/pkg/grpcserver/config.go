@@ -0,0 +1,44 @@
+// Package grpcserver contains the gRPC server for our API
+package grpcserver // import "github.com/la4ezar/restapi/pkg/grpcserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config contains gRPC Server settings
+type Config struct {
+	Enabled         bool          `mapstructure:"enabled" description:"whether to start the gRPC server"`
+	Port            int           `mapstructure:"port" description:"port of the gRPC server"`
+	Reflection      bool          `mapstructure:"reflection" description:"whether to register the gRPC reflection service"`
+	ShutdownTimeout time.Duration `mapstructure:"shutdowntimeout" description:"time to wait for the gRPC server to shutdown"`
+}
+
+// DefaultConfig returns the default values for configuring the gRPC Server
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:    true,
+		Port:       9090,
+		Reflection: true,
+
+		ShutdownTimeout: 10 * time.Second,
+	}
+}
+
+// Validate validates the gRPC server settings
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if c.Port <= 0 {
+		errs = append(errs, fmt.Errorf("validate gRPC Server settings: Port missing"))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("validate gRPC Server settings: ShutdownTimeout missing"))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	cryptov1 "github.com/la4ezar/restapi/api/crypto/v1"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server is wrapped grpc.Server with health checking and shutdown timeout
+type Server struct {
+	*grpc.Server
+
+	addr            string
+	health          *health.Server
+	service         *service
+	shutdownTimeout time.Duration
+}
+
+// New returns new Server instance with given configurations and gRPC options,
+// which serves the CryptoService over repository and streams the changes from feed
+func New(cfg *Config, repository storage.Repository, feed storage.Feed, opts ...grpc.ServerOption) *Server {
+	s := &Server{
+		Server:          grpc.NewServer(opts...),
+		addr:            ":" + strconv.Itoa(cfg.Port),
+		health:          health.NewServer(),
+		service:         newService(repository, feed),
+		shutdownTimeout: cfg.ShutdownTimeout,
+	}
+
+	cryptov1.RegisterCryptoServiceServer(s.Server, s.service)
+	healthpb.RegisterHealthServer(s.Server, s.health)
+	if cfg.Reflection {
+		reflection.Register(s.Server)
+	}
+
+	s.health.SetServingStatus(cryptov1.CryptoService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+
+	return s
+}
+
+// Start starts the Server and blocks until ctx is done and the Server is stopped or it fails to serve
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen on %s: %v", s.addr, err)
+	}
+
+	log.C(ctx).Infof("Starting gRPC server and listening on %s\n", s.addr)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("couldn't serve gRPC on %s: %v", s.addr, err)
+	case <-ctx.Done():
+		s.stop(ctx)
+		return nil
+	}
+}
+
+// stop reports NOT_SERVING to the health checks, notifies the Watch streams to finish,
+// gracefully stops the Server and forcefully closes the connections left after the shutdown timeout
+func (s *Server) stop(ctx context.Context) {
+	s.health.Shutdown()
+	close(s.service.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		log.C(ctx).Infof("gRPC server gracefully shutdown.")
+	case <-time.After(s.shutdownTimeout):
+		s.Stop()
+		log.C(ctx).Warnf("Couldn't gracefully shutdown the gRPC server in %s, closed all connections.", s.shutdownTimeout)
+	}
+}
@@ -0,0 +1,44 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+func TestServerStart(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	cfg := DefaultConfig()
+	cfg.Port = port
+	repository := storage.NewObservableRepository(newMapRepository())
+
+	// The port is taken
+	if err := New(cfg, repository, repository).Start(context.Background()); err == nil {
+		t.Error("expected error when the port is taken")
+	}
+	_ = listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- New(cfg, repository, repository).Start(ctx)
+	}()
+	cancel()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("expected the server to stop, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server didn't stop")
+	}
+}
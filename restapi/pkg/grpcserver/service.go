@@ -0,0 +1,239 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cryptov1 "github.com/la4ezar/restapi/api/crypto/v1"
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// service implements cryptov1.CryptoServiceServer over storage.Repository
+type service struct {
+	cryptov1.UnimplementedCryptoServiceServer
+
+	repository storage.Repository
+	feed       storage.Feed
+
+	// done is closed when the server is stopping so that the Watch streams can finish
+	done chan struct{}
+}
+
+func newService(repository storage.Repository, feed storage.Feed) *service {
+	return &service{
+		repository: repository,
+		feed:       feed,
+		done:       make(chan struct{}),
+	}
+}
+
+// Get returns the crypto with the requested CryptoID
+func (s *service) Get(ctx context.Context, req *cryptov1.GetCryptoRequest) (*cryptov1.Cryptocurrency, error) {
+	if len(req.GetCryptoId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "crypto_id missing")
+	}
+
+	c, err := s.repository.GetSingleCrypto(ctx, req.GetCryptoId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toProto(c), nil
+}
+
+// List streams all the cryptos
+func (s *service) List(_ *cryptov1.ListCryptosRequest, stream grpc.ServerStreamingServer[cryptov1.Cryptocurrency]) error {
+	cryptos, err := s.repository.GetAllCryptos(stream.Context())
+	if err != nil {
+		return toStatus(stream.Context(), err)
+	}
+
+	for _, c := range cryptos {
+		if err := stream.Send(toProto(c)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Create adds new crypto and returns it
+func (s *service) Create(ctx context.Context, req *cryptov1.CreateCryptoRequest) (*cryptov1.Cryptocurrency, error) {
+	if len(req.GetCrypto().GetCryptoId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "crypto.crypto_id missing")
+	}
+
+	c, err := fromProto(req.GetCrypto())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.repository.AddCrypto(ctx, c); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return req.GetCrypto(), nil
+}
+
+// Update replaces the crypto with the requested CryptoID and returns the updated crypto
+func (s *service) Update(ctx context.Context, req *cryptov1.UpdateCryptoRequest) (*cryptov1.Cryptocurrency, error) {
+	if len(req.GetCryptoId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "crypto_id missing")
+	}
+	if len(req.GetCrypto().GetCryptoId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "crypto.crypto_id missing")
+	}
+
+	c, err := fromProto(req.GetCrypto())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := s.repository.UpdateCrypto(ctx, req.GetCryptoId(), c); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	c, err = s.repository.GetSingleCrypto(ctx, req.GetCrypto().GetCryptoId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return toProto(c), nil
+}
+
+// Delete removes the crypto with the requested CryptoID
+func (s *service) Delete(ctx context.Context, req *cryptov1.DeleteCryptoRequest) (*cryptov1.DeleteCryptoResponse, error) {
+	if len(req.GetCryptoId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "crypto_id missing")
+	}
+
+	if err := s.repository.RemoveCrypto(ctx, req.GetCryptoId()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return &cryptov1.DeleteCryptoResponse{}, nil
+}
+
+// Watch streams the changes made to the cryptos of the tenant of the stream until the client or the server goes away.
+// The stream is aborted when it falls behind and misses changes.
+func (s *service) Watch(req *cryptov1.WatchCryptosRequest, stream grpc.ServerStreamingServer[cryptov1.CryptoEvent]) error {
+	changes, unsubscribe := s.feed.Subscribe()
+	defer unsubscribe()
+
+	tenantID := tenant.IDFromContext(stream.Context())
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case change, ok := <-changes:
+			if !ok {
+				return status.Error(codes.Unavailable, "change feed closed")
+			}
+			if change.Op == storage.OpResync {
+				return status.Error(codes.Aborted, "the stream fell behind and missed changes, list the cryptos and watch again")
+			}
+			if change.TenantID != tenantID {
+				continue
+			}
+			if id := req.GetCryptoId(); len(id) != 0 && id != change.CryptoID && id != change.OldCryptoID {
+				continue
+			}
+			if err := stream.Send(toEvent(change)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toStatus converts repository error to gRPC status error
+func toStatus(ctx context.Context, err error) error {
+	if errors.Is(err, storage.ErrCryptoNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, storage.ErrCryptoAlreadyExists) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, storage.ErrTenantNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if errors.Is(err, storage.ErrTenantReadOnly) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	log.C(ctx).WithError(err).Error("an error occurred while calling the repository")
+	return status.Error(codes.Internal, err.Error())
+}
+
+func toProto(c crypto.Cryptocurrency) *cryptov1.Cryptocurrency {
+	authors := make([]*cryptov1.Author, 0, len(c.Authors))
+	for _, a := range c.Authors {
+		authors = append(authors, &cryptov1.Author{Firstname: a.Firstname, Lastname: a.Lastname})
+	}
+
+	return &cryptov1.Cryptocurrency{
+		Name:       c.Name,
+		CryptoId:   c.CryptoID,
+		Price:      c.Price.InexactFloat64(),
+		ExactPrice: c.Price.String(),
+		Authors:    authors,
+	}
+}
+
+// fromProto converts c to crypto, preferring its exact price to the approximate one
+func fromProto(c *cryptov1.Cryptocurrency) (crypto.Cryptocurrency, error) {
+	price := decimal.NewFromFloat(c.GetPrice())
+	if len(c.GetExactPrice()) != 0 {
+		var err error
+		if price, err = decimal.NewFromString(c.GetExactPrice()); err != nil {
+			return crypto.Cryptocurrency{}, fmt.Errorf("crypto.exact_price invalid: %v", err)
+		}
+	}
+
+	authors := make([]crypto.Author, 0, len(c.GetAuthors()))
+	for _, a := range c.GetAuthors() {
+		authors = append(authors, crypto.Author{Firstname: a.GetFirstname(), Lastname: a.GetLastname()})
+	}
+
+	return crypto.Cryptocurrency{
+		Name:     c.GetName(),
+		CryptoID: c.GetCryptoId(),
+		Price:    price,
+		Authors:  authors,
+	}, nil
+}
+
+func toEvent(change storage.Change) *cryptov1.CryptoEvent {
+	event := &cryptov1.CryptoEvent{
+		CryptoId:    change.CryptoID,
+		OldCryptoId: change.OldCryptoID,
+	}
+
+	switch change.Op {
+	case storage.OpCreate:
+		event.Type = cryptov1.CryptoEvent_TYPE_CREATED
+	case storage.OpUpdate:
+		event.Type = cryptov1.CryptoEvent_TYPE_UPDATED
+	case storage.OpDelete:
+		event.Type = cryptov1.CryptoEvent_TYPE_DELETED
+	}
+
+	if change.Crypto != nil {
+		event.Crypto = toProto(*change.Crypto)
+	}
+
+	return event
+}
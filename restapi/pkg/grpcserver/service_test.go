@@ -0,0 +1,420 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	cryptov1 "github.com/la4ezar/restapi/api/crypto/v1"
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// mapRepository is a storage.Repository with the cryptos in a map
+type mapRepository struct {
+	storage.Repository
+
+	mutex   sync.Mutex
+	cryptos map[string]crypto.Cryptocurrency
+}
+
+func newMapRepository(cryptos ...crypto.Cryptocurrency) *mapRepository {
+	r := &mapRepository{cryptos: make(map[string]crypto.Cryptocurrency)}
+	for _, c := range cryptos {
+		r.cryptos[c.CryptoID] = c
+	}
+	return r
+}
+
+func (r *mapRepository) GetAllCryptos(_ context.Context) ([]crypto.Cryptocurrency, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	cryptos := make([]crypto.Cryptocurrency, 0, len(r.cryptos))
+	for _, c := range r.cryptos {
+		cryptos = append(cryptos, c)
+	}
+	sort.Slice(cryptos, func(i, j int) bool { return cryptos[i].CryptoID < cryptos[j].CryptoID })
+	return cryptos, nil
+}
+
+func (r *mapRepository) GetSingleCrypto(_ context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	c, ok := r.cryptos[cryptoID]
+	if !ok {
+		return c, storage.ErrCryptoNotFound
+	}
+	return c, nil
+}
+
+func (r *mapRepository) AddCrypto(_ context.Context, c crypto.Cryptocurrency) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.cryptos[c.CryptoID]; ok {
+		return storage.ErrCryptoAlreadyExists
+	}
+	r.cryptos[c.CryptoID] = c
+	return nil
+}
+
+func (r *mapRepository) UpdateCrypto(_ context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.cryptos[oldCryptoID]; !ok {
+		return storage.ErrCryptoNotFound
+	}
+	delete(r.cryptos, oldCryptoID)
+	r.cryptos[c.CryptoID] = c
+	return nil
+}
+
+func (r *mapRepository) RemoveCrypto(_ context.Context, cryptoID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.cryptos[cryptoID]; !ok {
+		return storage.ErrCryptoNotFound
+	}
+	delete(r.cryptos, cryptoID)
+	return nil
+}
+
+// newTestClient serves the CryptoService over repository on an in-memory listener and returns its client
+func newTestClient(t *testing.T, repository storage.Repository, feed storage.Feed) cryptov1.CryptoServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	cryptov1.RegisterCryptoServiceServer(srv, newService(repository, feed))
+	go func() {
+		_ = srv.Serve(listener)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return cryptov1.NewCryptoServiceClient(conn)
+}
+
+func TestService(t *testing.T) {
+	bitcoin := crypto.Cryptocurrency{Name: "Bitcoin", CryptoID: "BTC", Price: decimal.RequireFromString("45000.94"),
+		Authors: []crypto.Author{{Firstname: "Satoshi", Lastname: "Nakamoto"}}}
+
+	tests := []struct {
+		name     string
+		call     func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error)
+		wantCode codes.Code
+		wantID   string
+	}{
+		{
+			name: "get",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Get(ctx, &cryptov1.GetCryptoRequest{CryptoId: "BTC"})
+			},
+			wantID: "BTC",
+		},
+		{
+			name: "get missing",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Get(ctx, &cryptov1.GetCryptoRequest{CryptoId: "ETH"})
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "get without CryptoID",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Get(ctx, &cryptov1.GetCryptoRequest{})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "create",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Create(ctx, &cryptov1.CreateCryptoRequest{Crypto: &cryptov1.Cryptocurrency{Name: "Ethereum", CryptoId: "ETH", Price: 3000}})
+			},
+			wantID: "ETH",
+		},
+		{
+			name: "create without CryptoID",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Create(ctx, &cryptov1.CreateCryptoRequest{Crypto: &cryptov1.Cryptocurrency{Name: "Ethereum"}})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "create existing",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Create(ctx, &cryptov1.CreateCryptoRequest{Crypto: toProto(bitcoin)})
+			},
+			wantCode: codes.AlreadyExists,
+		},
+		{
+			name: "update",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Update(ctx, &cryptov1.UpdateCryptoRequest{CryptoId: "BTC", Crypto: &cryptov1.Cryptocurrency{Name: "Bitcoin", CryptoId: "XBT", Price: 1}})
+			},
+			wantID: "XBT",
+		},
+		{
+			name: "update missing",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Update(ctx, &cryptov1.UpdateCryptoRequest{CryptoId: "ETH", Crypto: &cryptov1.Cryptocurrency{CryptoId: "ETH"}})
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "update without the new CryptoID",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				return client.Update(ctx, &cryptov1.UpdateCryptoRequest{CryptoId: "BTC", Crypto: &cryptov1.Cryptocurrency{Name: "Bitcoin"}})
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "delete",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				_, err := client.Delete(ctx, &cryptov1.DeleteCryptoRequest{CryptoId: "BTC"})
+				return nil, err
+			},
+		},
+		{
+			name: "delete missing",
+			call: func(ctx context.Context, client cryptov1.CryptoServiceClient) (*cryptov1.Cryptocurrency, error) {
+				_, err := client.Delete(ctx, &cryptov1.DeleteCryptoRequest{CryptoId: "ETH"})
+				return nil, err
+			},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := storage.NewObservableRepository(newMapRepository(bitcoin))
+			client := newTestClient(t, repository, repository)
+
+			got, err := tt.call(context.Background(), client)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("expected code %s, got %s: %v", tt.wantCode, code, err)
+			}
+			if got.GetCryptoId() != tt.wantID {
+				t.Errorf("expected crypto %q, got %q", tt.wantID, got.GetCryptoId())
+			}
+		})
+	}
+}
+
+func TestServiceList(t *testing.T) {
+	repository := newMapRepository(
+		crypto.Cryptocurrency{Name: "Bitcoin", CryptoID: "BTC", Price: decimal.RequireFromString("45000.94"), Authors: []crypto.Author{{Firstname: "Satoshi", Lastname: "Nakamoto"}}},
+		crypto.Cryptocurrency{Name: "Ethereum", CryptoID: "ETH", Price: decimal.RequireFromString("3000")})
+	client := newTestClient(t, repository, storage.NewObservableRepository(repository))
+
+	stream, err := client.List(context.Background(), &cryptov1.ListCryptosRequest{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var got []*cryptov1.Cryptocurrency
+	for {
+		c, err := stream.Recv()
+		if err != nil {
+			break
+		}
+		got = append(got, c)
+	}
+
+	if len(got) != 2 || got[0].GetCryptoId() != "BTC" || got[1].GetCryptoId() != "ETH" {
+		t.Fatalf("expected BTC and ETH, got %v", got)
+	}
+	if authors := got[0].GetAuthors(); len(authors) != 1 || authors[0].GetLastname() != "Nakamoto" {
+		t.Errorf("expected the authors of BTC, got %v", authors)
+	}
+}
+
+// subscribedFeed is a storage.Feed which reports the subscriptions
+type subscribedFeed struct {
+	storage.Feed
+
+	subscribed chan struct{}
+}
+
+func (f *subscribedFeed) Subscribe() (<-chan storage.Change, func()) {
+	changes, unsubscribe := f.Feed.Subscribe()
+	f.subscribed <- struct{}{}
+	return changes, unsubscribe
+}
+
+func TestServiceWatch(t *testing.T) {
+	repository := storage.NewObservableRepository(newMapRepository(crypto.Cryptocurrency{Name: "Bitcoin", CryptoID: "BTC", Price: decimal.RequireFromString("1")}))
+	feed := &subscribedFeed{Feed: repository, subscribed: make(chan struct{}, 1)}
+	client := newTestClient(t, repository, feed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &cryptov1.WatchCryptosRequest{CryptoId: "XBT"})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	select {
+	case <-feed.subscribed:
+	case <-ctx.Done():
+		t.Fatal("the stream didn't subscribe to the changes")
+	}
+
+	if err := repository.AddCrypto(context.Background(), crypto.Cryptocurrency{CryptoID: "ETH"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := repository.UpdateCrypto(context.Background(), "BTC", crypto.Cryptocurrency{CryptoID: "XBT", Price: decimal.RequireFromString("2")}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := repository.RemoveCrypto(context.Background(), "XBT"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	want := []*cryptov1.CryptoEvent{
+		{Type: cryptov1.CryptoEvent_TYPE_UPDATED, CryptoId: "XBT", OldCryptoId: "BTC"},
+		{Type: cryptov1.CryptoEvent_TYPE_DELETED, CryptoId: "XBT"},
+	}
+	for _, w := range want {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("receive: %v", err)
+		}
+		if event.GetType() != w.GetType() || event.GetCryptoId() != w.GetCryptoId() || event.GetOldCryptoId() != w.GetOldCryptoId() {
+			t.Errorf("expected event %v, got %v", w, event)
+		}
+	}
+}
+
+// channelFeed is a storage.Feed which delivers the changes sent on its channel
+type channelFeed chan storage.Change
+
+func (f channelFeed) Subscribe() (<-chan storage.Change, func()) {
+	return f, func() {}
+}
+
+func TestServiceWatchResync(t *testing.T) {
+	feed := make(channelFeed, 1)
+	client := newTestClient(t, newMapRepository(), feed)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.Watch(ctx, &cryptov1.WatchCryptosRequest{})
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+
+	feed <- storage.Change{Op: storage.OpResync}
+	if _, err := stream.Recv(); status.Code(err) != codes.Aborted {
+		t.Errorf("expected the stream which missed changes to be aborted, got %v", err)
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want codes.Code
+	}{
+		{name: "not found", err: fmt.Errorf("get BTC: %w", storage.ErrCryptoNotFound), want: codes.NotFound},
+		{name: "already exists", err: storage.ErrCryptoAlreadyExists, want: codes.AlreadyExists},
+		{name: "query timeout", err: fmt.Errorf("GetAllCryptos exceeded the query timeout of 1s (%w)", context.DeadlineExceeded), want: codes.DeadlineExceeded},
+		{name: "other", err: errors.New("connection refused"), want: codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := status.Code(toStatus(context.Background(), tt.err)); got != tt.want {
+				t.Errorf("expected code %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "default", modify: func(c *Config) {}},
+		{name: "invalid port", modify: func(c *Config) { c.Port = 0 }, wantErr: true},
+		{name: "invalid shutdown timeout", modify: func(c *Config) { c.ShutdownTimeout = 0 }, wantErr: true},
+		{name: "disabled", modify: func(c *Config) { c.Enabled = false; c.Port = 0 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFromProtoPrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		price      float64
+		exactPrice string
+		wantPrice  string
+		wantErr    bool
+	}{
+		{name: "exact price", exactPrice: "0.00000001", wantPrice: "0.00000001"},
+		{name: "exact price over the approximate one", price: 1, exactPrice: "45000.94", wantPrice: "45000.94"},
+		{name: "exact price beyond float64", exactPrice: "12345678901234567.123456789", wantPrice: "12345678901234567.123456789"},
+		{name: "approximate price without an exact one", price: 45000.94, wantPrice: "45000.94"},
+		{name: "smallest approximate price", price: 0.00000001, wantPrice: "0.00000001"},
+		{name: "no price", wantPrice: "0"},
+		{name: "invalid exact price", price: 1, exactPrice: "1,5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := fromProto(&cryptov1.Cryptocurrency{Name: "Bitcoin", CryptoId: "BTC", Price: tt.price, ExactPrice: tt.exactPrice})
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected the exact price to be rejected")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("convert: %v", err)
+			}
+			if c.Price.String() != tt.wantPrice {
+				t.Errorf("expected the price %s, got %s", tt.wantPrice, c.Price)
+			}
+
+			// The response has the price both exactly and approximately, and converts back to the same price
+			response := toProto(c)
+			if response.GetExactPrice() != tt.wantPrice {
+				t.Errorf("expected the exact price %s in the response, got %s", tt.wantPrice, response.GetExactPrice())
+			}
+			if back, _ := fromProto(response); !back.Price.Equal(c.Price) {
+				t.Errorf("expected the response to convert back to %s, got %s", c.Price, back.Price)
+			}
+		})
+	}
+}
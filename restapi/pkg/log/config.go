@@ -1,8 +1,11 @@
 package log
 
 import (
+	"errors"
 	"fmt"
 	"os"
+
+	"github.com/sirupsen/logrus"
 )
 
 type Config struct {
@@ -20,15 +23,22 @@ func DefaultConfig() *Config {
 }
 
 func (c *Config) Validate() error {
+	var errs []error
 	if len(c.Level) == 0 {
-		return fmt.Errorf("validate Logger settings: Level missing")
+		errs = append(errs, fmt.Errorf("validate Logger settings: Level missing"))
+	} else if _, err := logrus.ParseLevel(c.Level); err != nil {
+		errs = append(errs, fmt.Errorf("validate Logger settings: %v", err))
 	}
 	if len(c.Format) == 0 {
-		return fmt.Errorf("validate Logger settings: Format missing")
+		errs = append(errs, fmt.Errorf("validate Logger settings: Format missing"))
+	} else if !hasFormatter(c.Format) {
+		errs = append(errs, fmt.Errorf("validate Logger settings: unknown Format %s", c.Format))
 	}
 	if len(c.Output) == 0 {
-		return fmt.Errorf("validate Logger settings: Output missing")
+		errs = append(errs, fmt.Errorf("validate Logger settings: Output missing"))
+	} else if !hasOutput(c.Output) {
+		errs = append(errs, fmt.Errorf("validate Logger settings: unknown Output %s", c.Output))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
@@ -0,0 +1,27 @@
+package log
+
+import "testing"
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "default", modify: func(c *Config) {}},
+		{name: "unknown level", modify: func(c *Config) { c.Level = "verbose" }, wantErr: true},
+		{name: "unknown format", modify: func(c *Config) { c.Format = "xml" }, wantErr: true},
+		{name: "unknown output", modify: func(c *Config) { c.Output = "/dev/null" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type logKey struct{}
@@ -52,6 +53,28 @@ func Configure(ctx context.Context, cfg *Config) (context.Context, error) {
 	return ContextWithLogger(ctx, entry), nil
 }
 
+// Level returns the current level of the logger configured with Configure
+func Level() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	return logrus.StandardLogger().GetLevel().String()
+}
+
+// SetLevel changes the level of the logger configured with Configure at runtime
+func SetLevel(level string) error {
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	logrus.StandardLogger().SetLevel(parsed)
+	return nil
+}
+
 func ContextWithLogger(ctx context.Context, entry *logrus.Entry) context.Context {
 	return context.WithValue(ctx, logKey{}, entry)
 }
@@ -65,13 +88,35 @@ func LoggerFromContext(ctx context.Context) *logrus.Entry {
 		entry = logrus.NewEntry(logrus.StandardLogger())
 	}
 
-	return copyFromEntry(entry.(*logrus.Entry))
+	newEntry := copyFromEntry(entry.(*logrus.Entry))
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		newEntry.Data["trace_id"] = spanContext.TraceID().String()
+		newEntry.Data["span_id"] = spanContext.SpanID().String()
+	}
+
+	return newEntry
 }
 
 func DefaultLogger() *logrus.Entry {
 	return LoggerFromContext(context.Background())
 }
 
+func hasFormatter(formatterName string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	_, exists := formatters[formatterName]
+	return exists
+}
+
+func hasOutput(outputName string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	_, exists := outputs[outputName]
+	return exists
+}
+
 func RegisterFormatter(formatterName string, formatter logrus.Formatter) error {
 	if _, exists := formatters[formatterName]; exists {
 		return fmt.Errorf("formatter with name %s is already registered", formatterName)
@@ -80,10 +125,19 @@ func RegisterFormatter(formatterName string, formatter logrus.Formatter) error {
 	return nil
 }
 
+// RequestLogger returns http middleware which stores in the request context a logger with the request ID
+// and the fields of the logger already in it and logs the start and the end of the handling of every request
 func RequestLogger() func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := ContextWithLogger(r.Context(), DefaultLogger())
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = NewRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			ctx := ContextWithRequestID(r.Context(), requestID)
+			ctx = ContextWithLogger(ctx, C(ctx).WithField(requestIDField, requestID))
 			r = r.WithContext(ctx)
 
 			start := time.Now()
@@ -93,7 +147,7 @@ func RequestLogger() func(next http.Handler) http.Handler {
 				remoteAddr = realIP
 			}
 
-			beforeLogger := D().WithFields(logrus.Fields{
+			beforeLogger := C(ctx).WithFields(logrus.Fields{
 				"request": r.RequestURI,
 				"method":  r.Method,
 				"remote":  remoteAddr,
@@ -106,7 +160,7 @@ func RequestLogger() func(next http.Handler) http.Handler {
 
 			duration := time.Since(start)
 
-			afterLogger := D().WithFields(logrus.Fields{
+			afterLogger := C(ctx).WithFields(logrus.Fields{
 				"status_code": loggingResponseWriter.statusCode,
 				"took":        duration,
 			})
@@ -0,0 +1,29 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLoggerFromContextWithSpan(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	ctx := ContextWithLogger(context.Background(), logrus.NewEntry(logrus.New()).WithField("component", "test"))
+
+	entry := C(trace.ContextWithSpanContext(ctx, spanContext))
+	if entry.Data["trace_id"] != traceID.String() || entry.Data["span_id"] != spanID.String() {
+		t.Errorf("expected the trace and span IDs, got %v", entry.Data)
+	}
+	if entry.Data["component"] != "test" {
+		t.Errorf("expected the fields of the logger in the context, got %v", entry.Data)
+	}
+
+	if entry := C(ctx); entry.Data["trace_id"] != nil || entry.Data["span_id"] != nil {
+		t.Errorf("expected no trace IDs without span, got %v", entry.Data)
+	}
+}
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// RequestIDHeader is the header which carries the ID of a request between the client and the server
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	requestIDField     = "request_id"
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// NewRequestID returns new random request ID
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// ContextWithRequestID returns copy of ctx which carries the request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx or empty string if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// validRequestID reports whether a request ID received from a client can be trusted in the logs and the response
+func validRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,70 @@
+package log
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		requestID string
+		want      bool
+	}{
+		{requestID: "4bf92f3577b34da6a3ce929d0e0e4736", want: true},
+		{requestID: "req-1_a.b:c", want: true},
+		{requestID: strings.Repeat("a", maxRequestIDLength), want: true},
+		{requestID: strings.Repeat("a", maxRequestIDLength+1)},
+		{requestID: ""},
+		{requestID: "id with spaces"},
+		{requestID: "id\nforged=entry"},
+		{requestID: "<script>"},
+	}
+
+	for _, tt := range tests {
+		if got := validRequestID(tt.requestID); got != tt.want {
+			t.Errorf("validRequestID(%q): expected %t, got %t", tt.requestID, tt.want, got)
+		}
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	tests := []struct {
+		name      string
+		requestID string
+		wantSame  bool
+	}{
+		{name: "client request ID", requestID: "req-1", wantSame: true},
+		{name: "missing request ID"},
+		{name: "invalid request ID", requestID: "id\nforged=entry"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotContext, gotLogger interface{}
+			handler := RequestLogger()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotContext = RequestIDFromContext(r.Context())
+				gotLogger = C(r.Context()).Data[requestIDField]
+			}))
+
+			r := httptest.NewRequest(http.MethodGet, "/api/cryptos", nil)
+			if len(tt.requestID) != 0 {
+				r.Header.Set(RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			requestID := w.Header().Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				t.Fatalf("expected valid request ID in the response, got %q", requestID)
+			}
+			if same := requestID == tt.requestID; same != tt.wantSame {
+				t.Errorf("expected the request ID of the client %t, got %q", tt.wantSame, requestID)
+			}
+			if gotContext != requestID || gotLogger != requestID {
+				t.Errorf("expected request ID %q in the context and the logger, got %v and %v", requestID, gotContext, gotLogger)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+// Package metrics contains the Prometheus metrics of our API
+package metrics // import "github.com/la4ezar/restapi/pkg/metrics
+
+import "fmt"
+
+// Config contains metrics settings
+type Config struct {
+	Enabled      bool   `mapstructure:"enabled" description:"whether to collect and expose metrics"`
+	Path         string `mapstructure:"path" description:"path on which the metrics are exposed"`
+	ServerTiming bool   `mapstructure:"server_timing" description:"whether to add Server-Timing header to the responses"`
+}
+
+// DefaultConfig returns the default values for configuring the metrics
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:      true,
+		Path:         "/metrics",
+		ServerTiming: true,
+	}
+}
+
+// Validate validates the metrics settings
+func (c *Config) Validate() error {
+	if c.Enabled && len(c.Path) == 0 {
+		return fmt.Errorf("validate Metrics settings: Path missing")
+	}
+
+	return nil
+}
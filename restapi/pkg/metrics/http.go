@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+
+	"github.com/gorilla/mux"
+)
+
+type timingKey struct{}
+
+type labelsKey struct{}
+
+// labels are the route and the tenant of a request
+type labels struct {
+	route  string
+	tenant string
+}
+
+// record sets the labels from the route the request is matched to and from the tenant of the request
+func (l *labels) record(r *http.Request) {
+	if current := mux.CurrentRoute(r); current != nil && len(current.GetName()) != 0 {
+		l.route = current.GetName()
+	}
+	l.tenant = tenant.IDFromContext(r.Context())
+}
+
+// timing accumulates the time spent in the database while handling a request
+type timing struct {
+	db int64
+}
+
+func (t *timing) addDB(d time.Duration) {
+	atomic.AddInt64(&t.db, int64(d))
+}
+
+func (t *timing) dbDuration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.db))
+}
+
+// Middleware returns http middleware which counts and times the requests by route name, status code and tenant
+// and if serverTiming is true adds Server-Timing header with the time spent in the database.
+// When it wraps the whole router, so the requests which match no route are counted too, Route has to be
+// added to the router to label the matched requests with their route and tenant
+func Middleware(serverTiming bool) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			httpInFlight.Inc()
+			defer httpInFlight.Dec()
+
+			l := &labels{route: "unknown"}
+			l.record(r)
+
+			t := &timing{}
+			ctx := context.WithValue(r.Context(), timingKey{}, t)
+			r = r.WithContext(context.WithValue(ctx, labelsKey{}, l))
+
+			rw := &responseWriter{
+				ResponseWriter: w,
+				statusCode:     http.StatusOK,
+				start:          start,
+				timing:         t,
+				serverTiming:   serverTiming,
+			}
+			next.ServeHTTP(rw, r)
+
+			code := strconv.Itoa(rw.statusCode)
+			httpRequests.WithLabelValues(l.route, r.Method, code, l.tenant).Inc()
+			httpDuration.WithLabelValues(l.route, r.Method, code, l.tenant).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// Route returns http middleware for the router which labels the requests counted by Middleware
+// with the route they are matched to and their tenant
+func Route() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l, ok := r.Context().Value(labelsKey{}).(*labels); ok {
+				l.record(r)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// observeDB adds d to the database time of the request in ctx
+func observeDB(ctx context.Context, d time.Duration) {
+	if t, ok := ctx.Value(timingKey{}).(*timing); ok {
+		t.addDB(d)
+	}
+}
+
+// responseWriter records the status code and sets the Server-Timing header before the headers are sent
+type responseWriter struct {
+	http.ResponseWriter
+
+	statusCode    int
+	start         time.Time
+	timing        *timing
+	serverTiming  bool
+	headerWritten bool
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if !rw.headerWritten {
+		rw.headerWritten = true
+		rw.statusCode = code
+		if rw.serverTiming {
+			rw.Header().Add("Server-Timing", fmt.Sprintf("db;desc=\"Database\";dur=%.3f, app;dur=%.3f",
+				milliseconds(rw.timing.dbDuration()), milliseconds(time.Since(rw.start))))
+		}
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.headerWritten {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
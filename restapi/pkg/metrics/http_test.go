@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// sampleCount returns the number of the samples of the metric with the given name and labels
+func sampleCount(t *testing.T, name string, labels map[string]string) uint64 {
+	t.Helper()
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && want != label.GetValue() {
+					continue metrics
+				}
+			}
+			if c := m.GetCounter(); c != nil {
+				return uint64(c.GetValue())
+			}
+			return m.GetHistogram().GetSampleCount()
+		}
+	}
+
+	return 0
+}
+
+func TestMiddleware(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Middleware(true))
+	r.Name("Teapot").Path("/teapot").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observeDB(r.Context(), 2*time.Millisecond)
+		observeDB(r.Context(), 3*time.Millisecond)
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r.Path("/unnamed").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("{}"))
+	})
+
+	teapot := map[string]string{"route": "Teapot", "method": http.MethodGet, "code": "418"}
+	unnamed := map[string]string{"route": "unknown", "method": http.MethodGet, "code": "200"}
+	wantTeapot := sampleCount(t, "restapi_http_requests_total", teapot) + 1
+	wantUnnamed := sampleCount(t, "restapi_http_request_duration_seconds", unnamed) + 1
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/teapot", nil))
+
+	if w.Code != http.StatusTeapot {
+		t.Fatalf("expected status %d, got %d", http.StatusTeapot, w.Code)
+	}
+	timing := regexp.MustCompile(`^db;desc="Database";dur=(\d+\.\d{3}), app;dur=\d+\.\d{3}$`).FindStringSubmatch(w.Header().Get("Server-Timing"))
+	if timing == nil || timing[1] != "5.000" {
+		t.Errorf("expected Server-Timing with 5ms in the database, got %q", w.Header().Get("Server-Timing"))
+	}
+	if got := sampleCount(t, "restapi_http_requests_total", teapot); got != wantTeapot {
+		t.Errorf("expected %d requests to Teapot, got %d", wantTeapot, got)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/unnamed", nil))
+
+	if got := w.Header().Get("Server-Timing"); len(got) == 0 {
+		t.Error("expected Server-Timing when the handler only writes the body")
+	}
+	if got := sampleCount(t, "restapi_http_request_duration_seconds", unnamed); got != wantUnnamed {
+		t.Errorf("expected %d timed requests to unnamed routes, got %d", wantUnnamed, got)
+	}
+}
+
+func TestMiddlewareWithoutServerTiming(t *testing.T) {
+	handler := Middleware(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		observeDB(r.Context(), time.Millisecond)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := w.Header().Get("Server-Timing"); len(got) != 0 {
+		t.Errorf("expected no Server-Timing, got %q", got)
+	}
+}
+
+func TestMiddlewareWrappingRouter(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(Route())
+	r.Name("Teapot").Path("/teapot").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := Middleware(false)(r)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		wantCode int
+		labels   map[string]string
+	}{
+		{name: "matched", method: http.MethodGet, path: "/teapot", wantCode: http.StatusTeapot,
+			labels: map[string]string{"route": "Teapot", "method": http.MethodGet, "code": "418"}},
+		{name: "not found", method: http.MethodGet, path: "/coffee", wantCode: http.StatusNotFound,
+			labels: map[string]string{"route": "unknown", "method": http.MethodGet, "code": "404"}},
+		{name: "method not allowed", method: http.MethodPost, path: "/teapot", wantCode: http.StatusMethodNotAllowed,
+			labels: map[string]string{"route": "unknown", "method": http.MethodPost, "code": "405"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := sampleCount(t, "restapi_http_requests_total", tt.labels) + 1
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			if got := sampleCount(t, "restapi_http_requests_total", tt.labels); got != want {
+				t.Errorf("expected %d requests, got %d", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "restapi"
+
+var (
+	registry = newRegistry()
+
+	httpRequests = promauto.With(registry).NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of handled HTTP requests by route name, method, status code and tenant.",
+	}, []string{"route", "method", "code", "tenant"})
+
+	httpDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the handled HTTP requests by route name, method, status code and tenant.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "code", "tenant"})
+
+	httpInFlight = promauto.With(registry).NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests which are currently handled.",
+	})
+
+	repositoryDuration = promauto.With(registry).NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the repository calls by method, outcome and tenant.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"method", "outcome", "tenant"})
+)
+
+func newRegistry() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(
+		collectors.NewGoCollector(collectors.WithGoCollectorRuntimeMetrics(
+			collectors.MetricsGC, collectors.MetricsMemory, collectors.MetricsScheduler,
+		)),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Handler returns http.Handler which exposes all the collected metrics
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// RegisterDB collects the connection pool stats of db under the given database name
+func RegisterDB(db *sql.DB, dbName string) error {
+	return registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// RegisterCache collects the hits, misses, stale reads, evictions and size of the repository cache
+func RegisterCache(cache *storage.CachingRepository) error {
+	counter := func(name, help string, value func(storage.CacheStats) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      name,
+			Help:      help,
+		}, func() float64 { return float64(value(cache.Stats())) })
+	}
+
+	collectors := []prometheus.Collector{
+		counter("hits_total", "Number of repository reads served from the cache.",
+			func(s storage.CacheStats) uint64 { return s.Hits }),
+		counter("misses_total", "Number of repository reads which went to the storage.",
+			func(s storage.CacheStats) uint64 { return s.Misses }),
+		counter("stale_total", "Number of repository reads served with a stale cache entry.",
+			func(s storage.CacheStats) uint64 { return s.Stale }),
+		counter("evictions_total", "Number of cache entries evicted to keep the cache within its maximum size.",
+			func(s storage.CacheStats) uint64 { return s.Evictions }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Number of cached entries.",
+		}, func() float64 { return float64(cache.Stats().Size) }),
+	}
+	for _, c := range collectors {
+		if err := registry.Register(c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+func TestRegisterCache(t *testing.T) {
+	cache := storage.NewCachingRepository(&stubRepository{}, &storage.CacheConfig{Enabled: true, MaxSize: 10, TTL: time.Minute})
+	if err := RegisterCache(cache); err != nil {
+		t.Fatalf("register cache: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetAllCryptos(context.Background()); err != nil {
+			t.Fatalf("get cryptos: %v", err)
+		}
+	}
+
+	if got := sampleCount(t, "restapi_cache_misses_total", nil); got != 1 {
+		t.Errorf("expected 1 miss, got %d", got)
+	}
+	if got := sampleCount(t, "restapi_cache_hits_total", nil); got != 2 {
+		t.Errorf("expected 2 hits, got %d", got)
+	}
+
+	if err := RegisterCache(cache); err == nil {
+		t.Error("expected error registering the cache twice")
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// repository is a storage.Repository which times every call of the wrapped repository
+type repository struct {
+	storage.Repository
+}
+
+// NewRepository returns storage.Repository which records the duration of the calls to r by tenant
+// and adds it to the database time of the request
+func NewRepository(r storage.Repository) storage.Repository {
+	return &repository{
+		Repository: r,
+	}
+}
+
+func (r *repository) GetAllCryptos(ctx context.Context) (cryptos []crypto.Cryptocurrency, err error) {
+	defer observe(ctx, "GetAllCryptos", time.Now(), &err)
+	return r.Repository.GetAllCryptos(ctx)
+}
+
+func (r *repository) GetSingleCrypto(ctx context.Context, cryptoID string) (c crypto.Cryptocurrency, err error) {
+	defer observe(ctx, "GetSingleCrypto", time.Now(), &err)
+	return r.Repository.GetSingleCrypto(ctx, cryptoID)
+}
+
+func (r *repository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) (err error) {
+	defer observe(ctx, "AddCrypto", time.Now(), &err)
+	return r.Repository.AddCrypto(ctx, c)
+}
+
+func (r *repository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) (err error) {
+	defer observe(ctx, "UpdateCrypto", time.Now(), &err)
+	return r.Repository.UpdateCrypto(ctx, oldCryptoID, c)
+}
+
+func (r *repository) RemoveCrypto(ctx context.Context, cryptoID string) (err error) {
+	defer observe(ctx, "RemoveCrypto", time.Now(), &err)
+	return r.Repository.RemoveCrypto(ctx, cryptoID)
+}
+
+func (r *repository) PingWithContext(ctx context.Context) (err error) {
+	defer observe(ctx, "PingWithContext", time.Now(), &err)
+	return r.Repository.PingWithContext(ctx)
+}
+
+// WithTx times the transaction and the calls made in it. Only the calls are added to the database time
+// of the request since they make up the transaction.
+func (r *repository) WithTx(ctx context.Context, fn func(storage.Repository) error) (err error) {
+	defer func(start time.Time) {
+		repositoryDuration.WithLabelValues("WithTx", outcome(err), tenant.IDFromContext(ctx)).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	return r.Repository.WithTx(ctx, func(tx storage.Repository) error {
+		return fn(NewRepository(tx))
+	})
+}
+
+func observe(ctx context.Context, method string, start time.Time, err *error) {
+	d := time.Since(start)
+
+	repositoryDuration.WithLabelValues(method, outcome(*err), tenant.IDFromContext(ctx)).Observe(d.Seconds())
+	observeDB(ctx, d)
+}
+
+func outcome(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
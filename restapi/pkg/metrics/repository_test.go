@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// stubRepository is a storage.Repository which fails the reads with err after delay
+type stubRepository struct {
+	storage.Repository
+
+	err   error
+	delay time.Duration
+}
+
+func (r *stubRepository) GetAllCryptos(_ context.Context) ([]crypto.Cryptocurrency, error) {
+	time.Sleep(r.delay)
+	return nil, r.err
+}
+
+func (r *stubRepository) PingWithContext(_ context.Context) error {
+	return nil
+}
+
+func (r *stubRepository) WithTx(_ context.Context, fn func(storage.Repository) error) error {
+	return fn(r)
+}
+
+func TestRepository(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantOutcome string
+	}{
+		{name: "success", wantOutcome: "success"},
+		{name: "error", err: errors.New("connection refused"), wantOutcome: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels := map[string]string{"method": "GetAllCryptos", "outcome": tt.wantOutcome}
+			want := sampleCount(t, "restapi_repository_query_duration_seconds", labels) + 1
+
+			if _, err := NewRepository(&stubRepository{err: tt.err}).GetAllCryptos(context.Background()); !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if got := sampleCount(t, "restapi_repository_query_duration_seconds", labels); got != want {
+				t.Errorf("expected %d %s calls, got %d", want, tt.wantOutcome, got)
+			}
+		})
+	}
+}
+
+func TestRepositoryPing(t *testing.T) {
+	labels := map[string]string{"method": "PingWithContext", "outcome": "success"}
+	want := sampleCount(t, "restapi_repository_query_duration_seconds", labels) + 1
+
+	if err := NewRepository(&stubRepository{}).PingWithContext(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got := sampleCount(t, "restapi_repository_query_duration_seconds", labels); got != want {
+		t.Errorf("expected %d pings, got %d", want, got)
+	}
+}
+
+func TestRepositoryWithTx(t *testing.T) {
+	txLabels := map[string]string{"method": "WithTx", "outcome": "success"}
+	callLabels := map[string]string{"method": "GetAllCryptos", "outcome": "success"}
+	wantTx := sampleCount(t, "restapi_repository_query_duration_seconds", txLabels) + 1
+	wantCalls := sampleCount(t, "restapi_repository_query_duration_seconds", callLabels) + 1
+
+	err := NewRepository(&stubRepository{}).WithTx(context.Background(), func(tx storage.Repository) error {
+		_, err := tx.GetAllCryptos(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if got := sampleCount(t, "restapi_repository_query_duration_seconds", txLabels); got != wantTx {
+		t.Errorf("expected %d transactions, got %d", wantTx, got)
+	}
+	if got := sampleCount(t, "restapi_repository_query_duration_seconds", callLabels); got != wantCalls {
+		t.Errorf("expected the calls in the transaction to be timed, got %d instead of %d", got, wantCalls)
+	}
+}
+
+func TestRepositoryAddsDatabaseTime(t *testing.T) {
+	repository := NewRepository(&stubRepository{delay: 5 * time.Millisecond})
+	handler := Middleware(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = repository.WithTx(r.Context(), func(tx storage.Repository) error {
+			_, err := tx.GetAllCryptos(r.Context())
+			return err
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// The call in the transaction is counted once, the transaction itself isn't added
+	timing := regexp.MustCompile(`^db;desc="Database";dur=(\d+\.\d{3}),`).FindStringSubmatch(w.Header().Get("Server-Timing"))
+	if timing == nil {
+		t.Fatalf("expected Server-Timing with the database time, got %q", w.Header().Get("Server-Timing"))
+	}
+	if db, _ := strconv.ParseFloat(timing[1], 64); db < 5 || db >= 10 {
+		t.Errorf("expected between 5ms and 10ms in the database, got %sms", timing[1])
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := DefaultConfig()
+	if err := c.Validate(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	c.Path = ""
+	if err := c.Validate(); err == nil {
+		t.Error("expected error when the metrics are enabled without Path")
+	}
+
+	c.Enabled = false
+	if err := c.Validate(); err != nil {
+		t.Errorf("unexpected error when the metrics are disabled %v", err)
+	}
+}
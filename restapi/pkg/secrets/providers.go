@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// EnvProvider returns the value of the environment variable named ref
+type EnvProvider struct{}
+
+func (EnvProvider) Secret(_ context.Context, ref string) (string, error) {
+	value, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+	return value, nil
+}
+
+// FileProvider returns the contents of the file at path ref without the trailing newline,
+// as Docker and Kubernetes mount secrets
+type FileProvider struct{}
+
+func (FileProvider) Secret(_ context.Context, ref string) (string, error) {
+	content, err := os.ReadFile(ref)
+	if err != nil {
+		return "", fmt.Errorf("could not read secret file: %v", err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// CommandProvider returns the output of the command ref without the trailing newline.
+// The command is split on whitespace and run without a shell.
+type CommandProvider struct{}
+
+func (CommandProvider) Secret(ctx context.Context, ref string) (string, error) {
+	args := strings.Fields(ref)
+	if len(args) == 0 {
+		return "", fmt.Errorf("secret command missing")
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		// The output is not part of the error since it may contain the secret
+		return "", fmt.Errorf("could not run secret command %s: %v", args[0], err)
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
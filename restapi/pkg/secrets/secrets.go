@@ -0,0 +1,62 @@
+// Package secrets resolves references to secrets kept outside of the configuration
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Schemes of the built-in providers
+const (
+	EnvScheme     = "env"
+	FileScheme    = "file"
+	CommandScheme = "cmd"
+)
+
+// Provider returns the secrets of a single scheme
+type Provider interface {
+	// Secret returns the value of the secret which ref points to
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+var (
+	providers = map[string]Provider{
+		EnvScheme:     EnvProvider{},
+		FileScheme:    FileProvider{},
+		CommandScheme: CommandProvider{},
+	}
+	mutex = sync.RWMutex{}
+)
+
+// Register makes the provider resolve the references with the scheme
+func Register(scheme string, provider Provider) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, exists := providers[scheme]; exists {
+		return fmt.Errorf("secret provider with scheme %s is already registered", scheme)
+	}
+	providers[scheme] = provider
+	return nil
+}
+
+// Resolve returns the value of the secret which ref points to. The ref has the form <scheme>:<reference>,
+// e.g. env:DB_PASSWORD, file:/run/secrets/db_password or cmd:vault kv get -field=password secret/db.
+func Resolve(ctx context.Context, ref string) (string, error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("secret reference %q is not in the form <scheme>:<reference>", ref)
+	}
+
+	mutex.RLock()
+	provider, exists := providers[parts[0]]
+	mutex.RUnlock()
+
+	if !exists {
+		return "", fmt.Errorf("unknown secret provider scheme %s", parts[0])
+	}
+
+	return provider.Secret(ctx, parts[1])
+}
@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type staticProvider string
+
+func (p staticProvider) Secret(_ context.Context, ref string) (string, error) {
+	return string(p) + ref, nil
+}
+
+func TestResolve(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("from file\n"), 0o600); err != nil {
+		t.Fatalf("write secret: %v", err)
+	}
+	t.Setenv("TEST_SECRET", "from env")
+
+	if err := Register("static", staticProvider("static ")); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if err := Register(EnvScheme, EnvProvider{}); err == nil {
+		t.Error("expected error registering an existing scheme")
+	}
+
+	tests := []struct {
+		name    string
+		ref     string
+		want    string
+		wantErr bool
+	}{
+		{name: "env", ref: "env:TEST_SECRET", want: "from env"},
+		{name: "env missing", ref: "env:TEST_SECRET_MISSING", wantErr: true},
+		{name: "file", ref: "file:" + file, want: "from file"},
+		{name: "file missing", ref: "file:" + file + ".missing", wantErr: true},
+		{name: "command", ref: "cmd:echo from command", want: "from command"},
+		{name: "command fails", ref: "cmd:false", wantErr: true},
+		{name: "command missing", ref: "cmd: ", wantErr: true},
+		{name: "registered", ref: "static:secret", want: "static secret"},
+		{name: "unknown scheme", ref: "vault:secret", wantErr: true},
+		{name: "without scheme", ref: "secret", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(context.Background(), tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
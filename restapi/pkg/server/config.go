@@ -2,17 +2,24 @@
 package server // import "github.com/la4ezar/restapi/pkg/server
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
 
 // Config contains Server settings
 type Config struct {
-	Port            int           `mapstructure:"port" description:"port of the server"`
-	ReadTimeout     time.Duration `mapstructure:"readtimeout" description:"read timeout duration for the server"`
-	WriteTimeout    time.Duration `mapstructure:"writetimeout" description:"write timeout duration for the server"`
-	IdleTimeout     time.Duration `mapstructure:"idletimeout" description:"idle timeout duration for the server"`
-	ShutdownTimeout time.Duration `mapstructure:"shutdowntimeout" description:"time to wait for the server to shutdown"`
+	Port             int           `mapstructure:"port" description:"port of the server"`
+	ReadTimeout      time.Duration `mapstructure:"readtimeout" description:"read timeout duration for the server"`
+	WriteTimeout     time.Duration `mapstructure:"writetimeout" description:"write timeout duration for the server"`
+	IdleTimeout      time.Duration `mapstructure:"idletimeout" description:"idle timeout duration for the server"`
+	ShutdownTimeout  time.Duration `mapstructure:"shutdowntimeout" description:"time to wait for the server to shutdown"`
+	PreStopDelay     time.Duration `mapstructure:"prestopdelay" description:"time to fail the readiness check before the server stops accepting connections"`
+	DrainLogInterval time.Duration `mapstructure:"drain_log_interval" description:"how often to log the number of requests in flight while shutting down"`
+	RecoverPanics    bool          `mapstructure:"recover_panics" description:"whether to respond with 500 instead of dropping the connection when a handler panics"`
+	MaxBodyBytes     int64         `mapstructure:"max_body_bytes" description:"maximum size of a request body in bytes, 0 means unlimited"`
+	StrictJSON       bool          `mapstructure:"strict_json" description:"whether to reject request bodies with unknown fields or data after the JSON document"`
+	TLS              *TLSConfig    `mapstructure:"tls" description:"TLS settings of the server"`
 }
 
 // DefaultConfig returns the default values for configuring the Server
@@ -24,26 +31,50 @@ func DefaultConfig() *Config {
 		WriteTimeout:    15 * time.Second,
 		IdleTimeout:     45 * time.Second,
 		ShutdownTimeout: 10 * time.Second,
+		PreStopDelay:    5 * time.Second,
+
+		DrainLogInterval: time.Second,
+
+		RecoverPanics: true,
+		MaxBodyBytes:  1 << 20,
+		StrictJSON:    true,
+
+		TLS: DefaultTLSConfig(),
 	}
 }
 
 // Validate validates the server settings
 func (c *Config) Validate() error {
+	var errs []error
 	if c.Port <= 0 {
-		return fmt.Errorf("validate Server settings: Port missing")
+		errs = append(errs, fmt.Errorf("validate Server settings: Port missing"))
 	}
 	if c.ReadTimeout <= 0 {
-		return fmt.Errorf("validate Server settings: ReadTimeout missing")
+		errs = append(errs, fmt.Errorf("validate Server settings: ReadTimeout missing"))
 	}
 	if c.WriteTimeout <= 0 {
-		return fmt.Errorf("validate Server settings: WriteTimeout missing")
+		errs = append(errs, fmt.Errorf("validate Server settings: WriteTimeout missing"))
 	}
 	if c.IdleTimeout <= 0 {
-		return fmt.Errorf("validate Server settings: IdleTimeout missing")
+		errs = append(errs, fmt.Errorf("validate Server settings: IdleTimeout missing"))
 	}
 	if c.ShutdownTimeout <= 0 {
-		return fmt.Errorf("validate Server settings: ShutdownTimeout missing")
+		errs = append(errs, fmt.Errorf("validate Server settings: ShutdownTimeout missing"))
+	}
+	if c.PreStopDelay < 0 {
+		errs = append(errs, fmt.Errorf("validate Server settings: PreStopDelay must not be negative"))
+	}
+	if c.DrainLogInterval <= 0 {
+		errs = append(errs, fmt.Errorf("validate Server settings: DrainLogInterval missing"))
+	}
+	if c.MaxBodyBytes < 0 {
+		errs = append(errs, fmt.Errorf("validate Server settings: MaxBodyBytes must not be negative"))
+	}
+	if c.TLS == nil {
+		errs = append(errs, fmt.Errorf("validate Server settings: TLS missing"))
+	} else if err := c.TLS.Validate(); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
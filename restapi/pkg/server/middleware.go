@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+
+	"github.com/la4ezar/restapi/pkg/controller"
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+// recoverer returns http middleware which recovers from panics in the handlers,
+// logs them with the stack and responds with 500 instead of dropping the connection.
+// If the handler has already started the response, the connection is aborted instead,
+// so that the client doesn't take the partial response for a complete one.
+func recoverer() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := &startedWriter{ResponseWriter: w}
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				log.C(r.Context()).
+					WithField("stack", string(debug.Stack())).
+					Errorf("Recovered from panic while handling request: %v", rec)
+
+				if rw.started {
+					panic(http.ErrAbortHandler)
+				}
+				controller.WriteError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// limitBody returns http middleware which fails reading request bodies larger than the current maxBytes, if positive
+func limitBody(maxBytes *atomic.Int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit := maxBytes.Load(); limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// startedWriter records whether the response has been started
+type startedWriter struct {
+	http.ResponseWriter
+
+	started bool
+}
+
+func (w *startedWriter) WriteHeader(code int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *startedWriter) Write(b []byte) (int, error) {
+	w.started = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter for http.ResponseController
+func (w *startedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecoverer(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBody   string
+		wantAbort  bool
+	}{
+		{
+			name: "no panic",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "panic before the response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic("boom")
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"Internal Server Error"}`,
+		},
+		{
+			name: "panic after the header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				panic("boom")
+			},
+			wantAbort: true,
+		},
+		{
+			name: "panic after the body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("[{"))
+				panic("boom")
+			},
+			wantAbort: true,
+		},
+		{
+			name: "abort",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				panic(http.ErrAbortHandler)
+			},
+			wantAbort: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rec := serve(recoverer()(tt.handler), w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if tt.wantAbort {
+				if rec != http.ErrAbortHandler {
+					t.Fatalf("expected the handler to be aborted, got panic %v", rec)
+				}
+				return
+			}
+			if rec != nil {
+				t.Fatalf("unexpected panic %v", rec)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if body := strings.TrimSpace(w.Body.String()); body != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, body)
+			}
+		})
+	}
+}
+
+func TestLimitBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   int64
+		body    string
+		wantErr bool
+	}{
+		{name: "under the limit", limit: 5, body: "1234"},
+		{name: "at the limit", limit: 5, body: "12345"},
+		{name: "over the limit", limit: 5, body: "123456", wantErr: true},
+		{name: "unlimited", limit: 0, body: "123456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var maxBytes atomic.Int64
+			maxBytes.Store(tt.limit)
+
+			var err error
+			handler := limitBody(&maxBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, err = io.ReadAll(r.Body)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			var maxBytesErr *http.MaxBytesError
+			if got := errors.As(err, &maxBytesErr); got != tt.wantErr {
+				t.Errorf("expected MaxBytesError %t, got error %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// serve calls handler and returns the value it panicked with, if any
+func serve(handler http.Handler, w http.ResponseWriter, r *http.Request) (rec interface{}) {
+	defer func() {
+		rec = recover()
+	}()
+
+	handler.ServeHTTP(w, r)
+	return nil
+}
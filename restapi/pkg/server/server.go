@@ -3,9 +3,11 @@ package server // import "github.com/la4ezar/restapi/pkg/server
 
 import (
 	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"strconv"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/la4ezar/restapi/internal/crypto"
@@ -18,69 +20,199 @@ import (
 type Cryptocurrency crypto.Cryptocurrency
 type CryptoAuthors []crypto.Author
 
-// Server is wrapped http.Server with shutdown timeout
+// Server is wrapped http.Server with readiness draining, in-flight tracking and shutdown timeout
 type Server struct {
 	*http.Server
 
-	shutdownTimeout time.Duration
+	shutdownTimeout  time.Duration
+	preStopDelay     time.Duration
+	drainLogInterval time.Duration
+	tls              *TLSConfig
+
+	// drain makes the readiness check fail before the Server stops accepting connections
+	drain    func()
+	inFlight int64
+
+	// maxBodyBytes is the current limit of the request bodies, 0 for none
+	maxBodyBytes atomic.Int64
+
+	// handler is the router with the middlewares added by Use
+	handler http.Handler
 }
 
-// New returns new Server instance with given configurations and router
-func New(cfg *Config, ctr controller.Controller) *Server {
-	r := mux.NewRouter().StrictSlash(true)
+// Option configures the router of the Server
+type Option func(r *mux.Router)
 
-	r.Use(log.RequestLogger())
+// WithMiddleware adds middleware which is called after the request is matched to a route
+func WithMiddleware(mw func(next http.Handler) http.Handler) Option {
+	return func(r *mux.Router) {
+		r.Use(mw)
+	}
+}
 
-	for _, route := range *ctr.Routes() {
-		r.Name(route.Name).
-			Methods(route.Method).
-			Path(route.Path).
-			Handler(route.Handler)
+// WithHandler adds a route with the given name which serves path with handler
+func WithHandler(name, path string, handler http.Handler) Option {
+	return func(r *mux.Router) {
+		r.Name(name).
+			Path(path).
+			Handler(handler)
 	}
+}
 
+// New returns new Server instance with given configurations and router
+func New(cfg *Config, ctr controller.Controller, opts ...Option) *Server {
 	s := &Server{
 		Server: &http.Server{
 			Addr:         ":" + strconv.Itoa(cfg.Port),
-			Handler:      r,
 			ReadTimeout:  cfg.ReadTimeout,
 			WriteTimeout: cfg.WriteTimeout,
 			IdleTimeout:  cfg.IdleTimeout,
 		},
-		shutdownTimeout: cfg.ShutdownTimeout,
+		shutdownTimeout:  cfg.ShutdownTimeout,
+		preStopDelay:     cfg.PreStopDelay,
+		drainLogInterval: cfg.DrainLogInterval,
+		tls:              cfg.TLS,
+		drain:            ctr.Drain,
+	}
+	s.maxBodyBytes.Store(cfg.MaxBodyBytes)
+
+	r := mux.NewRouter().StrictSlash(true)
+
+	for _, route := range *ctr.Routes() {
+		r.Name(route.Name).
+			Methods(route.Method).
+			Path(route.Path).
+			Handler(route.Handler)
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	// The request logger is inside the middlewares of the options, so that its lines carry the trace of the request
+	r.Use(log.RequestLogger())
+
+	// Hardening middlewares are innermost so that the other middlewares observe their responses
+	if cfg.RecoverPanics {
+		r.Use(recoverer())
 	}
+	r.Use(limitBody(&s.maxBodyBytes))
+
+	s.handler = r
+	s.Handler = s.track(s.handler)
 
 	return s
 }
 
-// Start starts the Server
-func (s *Server) Start(parentCtx context.Context, wg *sync.WaitGroup) {
-	ctx, cancel := context.WithCancel(parentCtx)
-	defer cancel()
+// Use adds middleware which is called before the request is matched to a route, so it can change its path
+func (s *Server) Use(mw func(next http.Handler) http.Handler) {
+	s.handler = mw(s.handler)
+	s.Handler = s.track(s.handler)
+}
+
+// SetMaxBodyBytes replaces the limit of the request bodies from the next requests on, 0 for none
+func (s *Server) SetMaxBodyBytes(maxBytes int64) {
+	s.maxBodyBytes.Store(maxBytes)
+}
+
+// InFlight returns the number of requests which are currently handled
+func (s *Server) InFlight() int64 {
+	return atomic.LoadInt64(&s.inFlight)
+}
+
+// track counts the requests in flight
+func (s *Server) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.inFlight, 1)
+		defer atomic.AddInt64(&s.inFlight, -1)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Start starts the Server and blocks until ctx is done and the Server is shutdown or it fails to serve
+func (s *Server) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("couldn't listen on %s: %v", s.Addr, err)
+	}
 
-	// Checks if the ctx or parentCtx is canceled
+	serve := s.Serve
+	if s.tls.Enabled {
+		if serve, err = s.serveTLS(ctx); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		log.C(ctx).Infof("Starting and listening with TLS on %s\n", s.Addr)
+	} else {
+		log.C(ctx).Infof("Starting and listening on %s\n", s.Addr)
+	}
+
+	serveErr := make(chan error, 1)
 	go func() {
-		defer wg.Done()
-		<-ctx.Done()
-		s.stop()
+		serveErr <- serve(listener)
 	}()
 
-	log.C(ctx).Infof("Starting and listening on %s\n", s.Addr)
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("couldn't serve on %s: %v", s.Addr, err)
+	case <-ctx.Done():
+		return s.stop(ctx)
+	}
+}
 
-	if err := s.ListenAndServe(); err != http.ErrServerClosed {
-		log.C(ctx).Fatalf("Couldn't listen on %s: %v\n", s.Addr, err)
+// serveTLS returns function which serves HTTPS with certificates which are reloaded until ctx is done
+func (s *Server) serveTLS(ctx context.Context) (func(net.Listener) error, error) {
+	reloader, err := newCertReloader(s.tls)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load TLS certificates: %v", err)
 	}
+	if s.TLSConfig, err = reloader.tlsConfig(); err != nil {
+		return nil, fmt.Errorf("couldn't configure TLS: %v", err)
+	}
+
+	go reloader.watch(ctx)
+
+	return func(listener net.Listener) error {
+		return s.ServeTLS(listener, "", "")
+	}, nil
 }
 
-// stop stops the Server
-func (s *Server) stop() {
-	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+// stop fails the readiness check, waits the pre-stop delay so that the load balancers stop sending new requests
+// and then drains the requests in flight until the shutdown timeout
+func (s *Server) stop(ctx context.Context) error {
+	log.C(ctx).Infof("Shutting down, failing the readiness check...")
+	s.drain()
+
+	if s.preStopDelay > 0 {
+		log.C(ctx).Infof("Waiting %s before closing the listener...", s.preStopDelay)
+		time.Sleep(s.preStopDelay)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 
 	s.SetKeepAlivesEnabled(false)
 
-	if err := s.Shutdown(ctx); err != nil {
-		log.C(ctx).Fatalf("Couldn't gracefully shutdown the server: %v\n", err)
-	}
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- s.Shutdown(shutdownCtx)
+	}()
 
-	log.C(ctx).Infof("Server gracefully shutdown.")
+	ticker := time.NewTicker(s.drainLogInterval)
+	defer ticker.Stop()
+
+	log.C(ctx).Infof("Draining %d requests in flight...", s.InFlight())
+	for {
+		select {
+		case err := <-shutdown:
+			if err != nil {
+				return fmt.Errorf("couldn't gracefully shutdown the server with %d requests in flight: %v", s.InFlight(), err)
+			}
+			log.C(ctx).Infof("Server gracefully shutdown.")
+			return nil
+		case <-ticker.C:
+			log.C(ctx).Infof("Draining %d requests in flight...", s.InFlight())
+		}
+	}
 }
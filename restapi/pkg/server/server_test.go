@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/routes"
+	"github.com/la4ezar/restapi/pkg/controller"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// readyRepository is a storage.Repository which is always reachable
+type readyRepository struct {
+	storage.Repository
+}
+
+func (r *readyRepository) PingWithContext(_ context.Context) error {
+	return nil
+}
+
+// freePort returns port which nothing listens on
+func freePort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func newTestConfig(t *testing.T) *Config {
+	c := DefaultConfig()
+	c.Port = freePort(t)
+	c.PreStopDelay = 200 * time.Millisecond
+	c.ShutdownTimeout = 5 * time.Second
+	c.DrainLogInterval = 10 * time.Millisecond
+	return c
+}
+
+// waitFor polls condition until it is true or the test times out
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !condition(); {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestServerDrainsOnShutdown(t *testing.T) {
+	cfg := newTestConfig(t)
+	base := fmt.Sprintf("http://localhost:%d", cfg.Port)
+
+	release := make(chan struct{})
+	srv := New(cfg, *controller.NewController(&readyRepository{}),
+		WithHandler("Slow", "/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- srv.Start(ctx)
+	}()
+
+	waitFor(t, func() bool {
+		response, err := http.Get(base + routes.ReadinessCheckURL)
+		if err != nil {
+			return false
+		}
+		_ = response.Body.Close()
+		return response.StatusCode == http.StatusOK
+	})
+
+	slow := make(chan int, 1)
+	go func() {
+		response, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- 0
+			return
+		}
+		_ = response.Body.Close()
+		slow <- response.StatusCode
+	}()
+	waitFor(t, func() bool { return srv.InFlight() == 1 })
+
+	cancel()
+
+	// The readiness check fails during the pre-stop delay while the listener is still open
+	response, err := http.Get(base + routes.ReadinessCheckURL)
+	if err != nil {
+		t.Fatalf("readiness check during the pre-stop delay: %v", err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the readiness check to fail while draining, got %d", response.StatusCode)
+	}
+
+	close(release)
+	if code := <-slow; code != http.StatusOK {
+		t.Errorf("expected the request in flight to complete, got %d", code)
+	}
+	if err := <-stopped; err != nil {
+		t.Errorf("expected graceful shutdown, got %v", err)
+	}
+}
+
+func TestServerStartFails(t *testing.T) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	cfg := newTestConfig(t)
+	cfg.Port = listener.Addr().(*net.TCPAddr).Port
+
+	if err := New(cfg, *controller.NewController(&readyRepository{})).Start(context.Background()); err == nil {
+		t.Error("expected error when the port is taken")
+	}
+}
+
+func TestServerLogsWithTrace(t *testing.T) {
+	var output bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&output)
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{1},
+	})
+	// traced stands in for the tracing middleware, which starts the span of the request
+	traced := WithMiddleware(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := log.ContextWithLogger(r.Context(), logrus.NewEntry(logger))
+			next.ServeHTTP(w, r.WithContext(trace.ContextWithSpanContext(ctx, spanContext)))
+		})
+	})
+
+	srv := New(newTestConfig(t), *controller.NewController(&readyRepository{}), traced)
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, routes.ReadinessCheckURL, nil))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected the request to be logged, got %q", output.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "trace_id="+spanContext.TraceID().String()) {
+			t.Errorf("expected the line to carry the trace, got %q", line)
+		}
+	}
+}
+
+func TestServerSetMaxBodyBytes(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.MaxBodyBytes = 5
+
+	var err error
+	srv := New(cfg, *controller.NewController(&readyRepository{}),
+		WithHandler("Read", "/read", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err = io.ReadAll(r.Body)
+		})))
+
+	read := func() error {
+		srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/read", strings.NewReader("123456")))
+		return err
+	}
+
+	if read() == nil {
+		t.Error("expected the body over the configured limit to be rejected")
+	}
+	srv.SetMaxBodyBytes(10)
+	if err := read(); err != nil {
+		t.Errorf("expected the body under the new limit to be read, got %v", err)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/tlsutil"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TLSConfig contains Server TLS settings
+type TLSConfig struct {
+	Enabled      bool     `mapstructure:"enabled" description:"whether to serve HTTPS instead of HTTP"`
+	CertFile     string   `mapstructure:"cert_file" description:"path to the PEM encoded server certificate"`
+	KeyFile      string   `mapstructure:"key_file" description:"path to the PEM encoded server private key"`
+	MinVersion   string   `mapstructure:"min_version" description:"minimum accepted TLS version, one of 1.0, 1.1, 1.2 or 1.3"`
+	CipherSuites []string `mapstructure:"cipher_suites" description:"accepted TLS 1.0-1.2 cipher suites, Go defaults if empty"`
+	ClientCAFile string   `mapstructure:"client_ca_file" description:"path to the PEM encoded CA which client certificates must be signed by, enables mTLS"`
+}
+
+// DefaultTLSConfig returns the default values for configuring the Server TLS
+func DefaultTLSConfig() *TLSConfig {
+	return &TLSConfig{
+		Enabled:    false,
+		MinVersion: "1.2",
+	}
+}
+
+// Validate validates the Server TLS settings
+func (c *TLSConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if len(c.CertFile) == 0 {
+		errs = append(errs, fmt.Errorf("validate Server TLS settings: CertFile missing"))
+	}
+	if len(c.KeyFile) == 0 {
+		errs = append(errs, fmt.Errorf("validate Server TLS settings: KeyFile missing"))
+	}
+	if _, err := tlsutil.ParseVersion(c.MinVersion); err != nil {
+		errs = append(errs, fmt.Errorf("validate Server TLS settings: %v", err))
+	}
+	if _, err := tlsutil.ParseCipherSuites(c.CipherSuites); err != nil {
+		errs = append(errs, fmt.Errorf("validate Server TLS settings: %v", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// certReloader keeps the server certificate and the client CA loaded from disk
+// and reloads them when the files change or the process receives SIGHUP
+type certReloader struct {
+	cfg *TLSConfig
+
+	mutex     sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+func newCertReloader(cfg *TLSConfig) (*certReloader, error) {
+	r := &certReloader{cfg: cfg}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the certificate and the client CA from disk, keeping the old ones on failure
+func (r *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load server certificate: %v", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if len(r.cfg.ClientCAFile) != 0 {
+		if clientCAs, err = tlsutil.LoadCertPool(r.cfg.ClientCAFile); err != nil {
+			return err
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	return nil
+}
+
+// tlsConfig returns tls.Config which always uses the last loaded certificate and client CA
+func (r *certReloader) tlsConfig() (*tls.Config, error) {
+	minVersion, err := tlsutil.ParseVersion(r.cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := tlsutil.ParseCipherSuites(r.cfg.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	base := &tls.Config{
+		MinVersion:   minVersion,
+		CipherSuites: cipherSuites,
+	}
+
+	return &tls.Config{
+		MinVersion: minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mutex.RLock()
+			defer r.mutex.RUnlock()
+
+			cfg := base.Clone()
+			cfg.Certificates = []tls.Certificate{*r.cert}
+			if r.clientCAs != nil {
+				cfg.ClientCAs = r.clientCAs
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}, nil
+}
+
+// watch reloads the certificates on SIGHUP or when their files change until ctx is done
+func (r *certReloader) watch(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.C(ctx).WithError(err).Warn("Couldn't watch TLS certificates for changes, reload them with SIGHUP")
+	} else {
+		defer func() {
+			if err := watcher.Close(); err != nil {
+				log.C(ctx).WithError(err).Error("an error occurred while closing TLS certificates watcher")
+			}
+		}()
+
+		// Directories are watched because Kubernetes and most tools replace the files instead of writing them
+		for _, dir := range r.dirs() {
+			if err := watcher.Add(dir); err != nil {
+				log.C(ctx).WithError(err).Warnf("Couldn't watch %s for TLS certificate changes", dir)
+			}
+		}
+		events, errs = watcher.Events, watcher.Errors
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reloadAndLog(ctx, "SIGHUP")
+		case event := <-events:
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) != 0 {
+				r.reloadAndLog(ctx, event.Name)
+			}
+		case err := <-errs:
+			// The errors must be drained, otherwise the watcher blocks and stops delivering the events
+			log.C(ctx).WithError(err).Error("an error occurred while watching TLS certificates for changes")
+		}
+	}
+}
+
+func (r *certReloader) reloadAndLog(ctx context.Context, reason string) {
+	if err := r.reload(); err != nil {
+		log.C(ctx).WithError(err).Errorf("Couldn't reload TLS certificates after %s, keeping the old ones", reason)
+		return
+	}
+	log.C(ctx).Infof("Reloaded TLS certificates after %s", reason)
+}
+
+func (r *certReloader) dirs() []string {
+	unique := make(map[string]struct{})
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.ClientCAFile} {
+		if len(file) != 0 {
+			unique[filepath.Dir(file)] = struct{}{}
+		}
+	}
+
+	dirs := make([]string, 0, len(unique))
+	for dir := range unique {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
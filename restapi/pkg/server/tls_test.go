@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/tlsutil"
+)
+
+// testCA issues certificates for the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T, dir, name string) *testCA {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse CA: %v", err)
+	}
+
+	file := filepath.Join(dir, name+".pem")
+	writePEM(t, file, "CERTIFICATE", der)
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue writes certificate with the given common name and its key in dir and returns their paths
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+
+	key := newKey(t)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	return key
+}
+
+func writePEM(t *testing.T, file, blockType string, der []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("write %s: %v", file, err)
+	}
+}
+
+// newTLSServer serves 200 over TLS configured by the certReloader of cfg
+func newTLSServer(t *testing.T, cfg *TLSConfig) (*httptest.Server, *certReloader) {
+	t.Helper()
+
+	reloader, err := newCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("new cert reloader: %v", err)
+	}
+	tlsConfig, err := reloader.tlsConfig()
+	if err != nil {
+		t.Fatalf("TLS config: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv, reloader
+}
+
+// get sends GET request to srv with the client TLS settings and returns the common name of the server certificate
+func get(t *testing.T, srv *httptest.Server, caFile, certFile, keyFile string) (string, error) {
+	t.Helper()
+
+	tlsConfig, err := tlsutil.ClientConfig(caFile, certFile, keyFile, "localhost", false)
+	if err != nil {
+		t.Fatalf("client TLS config: %v", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true}}
+
+	response, err := client.Get(srv.URL)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	return response.TLS.PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestCertReloaderMTLS(t *testing.T) {
+	dir := t.TempDir()
+	serverCA := newTestCA(t, dir, "server-ca")
+	clientCA := newTestCA(t, dir, "client-ca")
+	otherCA := newTestCA(t, dir, "other-ca")
+
+	certFile, keyFile := serverCA.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := clientCA.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+	otherCert, otherKey := otherCA.issue(t, dir, "other", x509.ExtKeyUsageClientAuth)
+
+	srv, _ := newTLSServer(t, &TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2", ClientCAFile: clientCA.file})
+
+	if _, err := get(t, srv, serverCA.file, clientCert, clientKey); err != nil {
+		t.Errorf("expected the client certificate to be accepted, got %v", err)
+	}
+	if _, err := get(t, srv, serverCA.file, "", ""); err == nil {
+		t.Error("expected the request without client certificate to be rejected")
+	}
+	if _, err := get(t, srv, serverCA.file, otherCert, otherKey); err == nil {
+		t.Error("expected the client certificate of another CA to be rejected")
+	}
+}
+
+func TestCertReloaderReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+	certFile, keyFile := ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+
+	srv, reloader := newTLSServer(t, &TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"})
+
+	first := reloader.cert.Leaf
+	if _, err := get(t, srv, ca.file, "", ""); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+
+	// A broken certificate keeps the old one
+	if err := os.WriteFile(certFile, []byte("broken"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := reloader.reload(); err == nil {
+		t.Error("expected error for broken certificate")
+	}
+	if _, err := get(t, srv, ca.file, "", ""); err != nil {
+		t.Errorf("expected the old certificate to be kept, got %v", err)
+	}
+
+	ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+	if err := reloader.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if reloader.cert.Leaf == first {
+		t.Error("expected the new certificate to be loaded")
+	}
+	if _, err := get(t, srv, ca.file, "", ""); err != nil {
+		t.Errorf("expected the new certificate to be served, got %v", err)
+	}
+}
+
+func TestCertReloaderWatch(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t, dir, "ca")
+	certFile, keyFile := ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+
+	_, reloader := newTLSServer(t, &TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile, MinVersion: "1.2"})
+	loaded := func() *tls.Certificate {
+		reloader.mutex.RLock()
+		defer reloader.mutex.RUnlock()
+		return reloader.cert
+	}
+	first := loaded()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		reloader.watch(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	// The watcher may not be watching yet, so the certificate is issued until it is reloaded
+	deadline := time.Now().Add(5 * time.Second)
+	for loaded() == first {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the changed certificate to be reloaded")
+		}
+		ca.issue(t, dir, "localhost", x509.ExtKeyUsageServerAuth)
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestTLSConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *TLSConfig)
+		wantErr bool
+	}{
+		{name: "disabled", modify: func(c *TLSConfig) {}},
+		{name: "enabled", modify: func(c *TLSConfig) { c.Enabled, c.CertFile, c.KeyFile = true, "cert.pem", "key.pem" }},
+		{name: "without certificate", modify: func(c *TLSConfig) { c.Enabled, c.KeyFile = true, "key.pem" }, wantErr: true},
+		{name: "without key", modify: func(c *TLSConfig) { c.Enabled, c.CertFile = true, "cert.pem" }, wantErr: true},
+		{name: "unknown version", modify: func(c *TLSConfig) {
+			c.Enabled, c.CertFile, c.KeyFile, c.MinVersion = true, "cert.pem", "key.pem", "1.4"
+		}, wantErr: true},
+		{name: "unknown cipher suite", modify: func(c *TLSConfig) {
+			c.Enabled, c.CertFile, c.KeyFile, c.CipherSuites = true, "cert.pem", "key.pem", []string{"TLS_NULL"}
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultTLSConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package storage
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+// CacheConfig contains the settings of caching the reads of the repository
+type CacheConfig struct {
+	Enabled              bool          `mapstructure:"enabled" description:"whether to cache the reads of the repository in memory"`
+	MaxSize              int           `mapstructure:"max_size" description:"maximum number of cached entries, the least recently used are evicted"`
+	TTL                  time.Duration `mapstructure:"ttl" description:"how long a cached entry is fresh"`
+	StaleWhileRevalidate time.Duration `mapstructure:"stale_while_revalidate" description:"how long after the TTL a stale entry is served while it is refreshed in the background, 0 disables it"`
+	StaleIfError         time.Duration `mapstructure:"stale_if_error" description:"how long after the TTL a stale entry is served when the storage fails, 0 disables it"`
+}
+
+func DefaultCacheConfig() *CacheConfig {
+	return &CacheConfig{
+		Enabled: false,
+		MaxSize: 10000,
+		TTL:     30 * time.Second,
+	}
+}
+
+func (c *CacheConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if c.MaxSize <= 0 {
+		errs = append(errs, fmt.Errorf("validate Cache settings: MaxSize must be positive"))
+	}
+	if c.TTL <= 0 {
+		errs = append(errs, fmt.Errorf("validate Cache settings: TTL missing"))
+	}
+	if c.StaleWhileRevalidate < 0 || c.StaleIfError < 0 {
+		errs = append(errs, fmt.Errorf("validate Cache settings: StaleWhileRevalidate and StaleIfError must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// CacheStats are the counters of a CachingRepository since it was created
+type CacheStats struct {
+	// Hits is the number of reads served from the cache without going to the storage
+	Hits uint64
+	// Misses is the number of reads which went to the storage
+	Misses uint64
+	// Stale is the number of reads served with a stale entry, while it was refreshed or after the storage failed
+	Stale uint64
+	// Evictions is the number of entries evicted to keep the cache within its maximum size
+	Evictions uint64
+	// Size is the number of cached entries
+	Size int
+}
+
+// allCryptosKey is the CryptoID in the cache key of GetAllCryptos, CryptoIDs are never empty so it doesn't collide with them
+const allCryptosKey = ""
+
+// cacheKey returns the key of the entry of the crypto of the tenant. The IDs of the tenants have no slashes,
+// so the keys of different tenants never collide.
+func cacheKey(tenantID, cryptoID string) string {
+	return tenantID + "/" + cryptoID
+}
+
+// cacheEntry is a cached result of GetAllCryptos or GetSingleCrypto
+type cacheEntry struct {
+	key     string
+	cryptos []crypto.Cryptocurrency
+	expires time.Time
+}
+
+// CachingRepository is a Repository which serves the reads from an in-memory LRU cache shared by all the tenants.
+// The entries of the written cryptos are invalidated after every write, so the reads of
+// an instance see its own writes. Writes of other instances are seen once the entries expire.
+type CachingRepository struct {
+	Repository
+	config CacheConfig
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	// lru has the most recently used entries at its front
+	lru *list.List
+	// generation is incremented by every invalidation, so reads which started before it aren't cached
+	generation uint64
+	refreshing map[string]struct{}
+
+	hits, misses, stale, evictions atomic.Uint64
+}
+
+// NewCachingRepository returns CachingRepository which caches the reads of r as configured
+func NewCachingRepository(r Repository, c *CacheConfig) *CachingRepository {
+	return &CachingRepository{
+		Repository: r,
+		config:     *c,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+		refreshing: make(map[string]struct{}),
+	}
+}
+
+// Stats returns the counters of the cache
+func (r *CachingRepository) Stats() CacheStats {
+	r.mutex.Lock()
+	size := r.lru.Len()
+	r.mutex.Unlock()
+
+	return CacheStats{
+		Hits:      r.hits.Load(),
+		Misses:    r.misses.Load(),
+		Stale:     r.stale.Load(),
+		Evictions: r.evictions.Load(),
+		Size:      size,
+	}
+}
+
+func (r *CachingRepository) GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+	return r.read(ctx, cacheKey(tenant.IDFromContext(ctx), allCryptosKey), func(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+		return r.Repository.GetAllCryptos(ctx)
+	})
+}
+
+func (r *CachingRepository) GetSingleCrypto(ctx context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	cryptos, err := r.read(ctx, cacheKey(tenant.IDFromContext(ctx), cryptoID), func(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+		c, err := r.Repository.GetSingleCrypto(ctx, cryptoID)
+		if err != nil {
+			return nil, err
+		}
+		return []crypto.Cryptocurrency{c}, nil
+	})
+	if err != nil {
+		return crypto.Cryptocurrency{}, err
+	}
+
+	return cryptos[0], nil
+}
+
+func (r *CachingRepository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	defer r.invalidate(tenant.IDFromContext(ctx), c.CryptoID)
+	return r.Repository.AddCrypto(ctx, c)
+}
+
+func (r *CachingRepository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	defer r.invalidate(tenant.IDFromContext(ctx), oldCryptoID, c.CryptoID)
+	return r.Repository.UpdateCrypto(ctx, oldCryptoID, c)
+}
+
+func (r *CachingRepository) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	defer r.invalidate(tenant.IDFromContext(ctx), cryptoID)
+	return r.Repository.RemoveCrypto(ctx, cryptoID)
+}
+
+// WithTx doesn't cache the reads in the transaction and invalidates the cryptos written in it once it is done
+func (r *CachingRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	var written []string
+	defer func() { r.invalidate(tenant.IDFromContext(ctx), written...) }()
+
+	return r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(&txWrites{Repository: tx, written: &written})
+	})
+}
+
+// Follow invalidates the entries of the changes in feed until ctx is done, so that the cache sees
+// the writes of other instances. All the entries are invalidated when the feed had to resync.
+func (r *CachingRepository) Follow(ctx context.Context, feed Feed) {
+	changes, unsubscribe := feed.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case change := <-changes:
+			if change.Op == OpResync {
+				r.purge()
+				continue
+			}
+			r.invalidate(change.TenantID, change.CryptoID, change.OldCryptoID)
+		}
+	}
+}
+
+// txWrites is a Repository which collects the CryptoIDs written in a transaction
+type txWrites struct {
+	Repository
+	written *[]string
+}
+
+func (r *txWrites) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	*r.written = append(*r.written, c.CryptoID)
+	return r.Repository.AddCrypto(ctx, c)
+}
+
+func (r *txWrites) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	*r.written = append(*r.written, oldCryptoID, c.CryptoID)
+	return r.Repository.UpdateCrypto(ctx, oldCryptoID, c)
+}
+
+func (r *txWrites) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	*r.written = append(*r.written, cryptoID)
+	return r.Repository.RemoveCrypto(ctx, cryptoID)
+}
+
+func (r *txWrites) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(&txWrites{Repository: tx, written: r.written})
+	})
+}
+
+// read returns the cached entry of key if it is fresh and loads it otherwise.
+// Stale entries are served while they are refreshed or when loading fails, if configured.
+func (r *CachingRepository) read(ctx context.Context, key string, load func(context.Context) ([]crypto.Cryptocurrency, error)) ([]crypto.Cryptocurrency, error) {
+	now := time.Now()
+
+	r.mutex.Lock()
+	entry := r.get(key)
+	generation := r.generation
+	if entry != nil && now.Before(entry.expires) {
+		r.mutex.Unlock()
+		r.hits.Add(1)
+		return copyCryptos(entry.cryptos), nil
+	}
+	if entry != nil && now.Before(entry.expires.Add(r.config.StaleWhileRevalidate)) {
+		r.refresh(ctx, key, generation, load)
+		r.mutex.Unlock()
+		r.hits.Add(1)
+		r.stale.Add(1)
+		return copyCryptos(entry.cryptos), nil
+	}
+	r.mutex.Unlock()
+
+	r.misses.Add(1)
+	cryptos, err := load(ctx)
+	if err != nil {
+		if entry != nil && isStorageFailure(err) && now.Before(entry.expires.Add(r.config.StaleIfError)) {
+			log.C(ctx).WithError(err).Warnf("Serving a stale cache entry after the storage failed")
+			r.stale.Add(1)
+			return copyCryptos(entry.cryptos), nil
+		}
+		return nil, err
+	}
+
+	r.put(key, generation, cryptos)
+	return cryptos, nil
+}
+
+// refresh loads the entry of key in the background unless it is already being refreshed.
+// It must be called with the mutex locked.
+func (r *CachingRepository) refresh(ctx context.Context, key string, generation uint64, load func(context.Context) ([]crypto.Cryptocurrency, error)) {
+	if _, ok := r.refreshing[key]; ok {
+		return
+	}
+	r.refreshing[key] = struct{}{}
+
+	go func() {
+		defer func() {
+			r.mutex.Lock()
+			delete(r.refreshing, key)
+			r.mutex.Unlock()
+		}()
+
+		// The refresh outlives the request which triggered it
+		ctx := context.WithoutCancel(ctx)
+		cryptos, err := load(ctx)
+		if err != nil {
+			log.C(ctx).WithError(err).Warnf("Couldn't refresh a stale cache entry")
+			return
+		}
+		r.put(key, generation, cryptos)
+	}()
+}
+
+// get returns the entry of key and marks it as the most recently used, nil if there is none.
+// It must be called with the mutex locked.
+func (r *CachingRepository) get(key string) *cacheEntry {
+	element, ok := r.entries[key]
+	if !ok {
+		return nil
+	}
+
+	r.lru.MoveToFront(element)
+	return element.Value.(*cacheEntry)
+}
+
+// put caches a copy of cryptos under key unless they were invalidated since the generation they were read in
+func (r *CachingRepository) put(key string, generation uint64, cryptos []crypto.Cryptocurrency) {
+	entry := &cacheEntry{
+		key:     key,
+		cryptos: copyCryptos(cryptos),
+		expires: time.Now().Add(r.config.TTL),
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	if element, ok := r.entries[key]; ok {
+		element.Value = entry
+		r.lru.MoveToFront(element)
+		return
+	}
+
+	r.entries[key] = r.lru.PushFront(entry)
+	for r.lru.Len() > r.config.MaxSize {
+		oldest := r.lru.Back()
+		r.lru.Remove(oldest)
+		delete(r.entries, oldest.Value.(*cacheEntry).key)
+		r.evictions.Add(1)
+	}
+}
+
+// invalidate removes the entries of the cryptos of the tenant and the list of all its cryptos
+func (r *CachingRepository) invalidate(tenantID string, cryptoIDs ...string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	for _, cryptoID := range append(cryptoIDs, allCryptosKey) {
+		key := cacheKey(tenantID, cryptoID)
+		if element, ok := r.entries[key]; ok {
+			r.lru.Remove(element)
+			delete(r.entries, key)
+		}
+	}
+}
+
+// PurgeTenant removes the entries of the tenant, e.g. when it is removed with its cryptos
+func (r *CachingRepository) PurgeTenant(tenantID string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	prefix := cacheKey(tenantID, "")
+	for key, element := range r.entries {
+		if strings.HasPrefix(key, prefix) {
+			r.lru.Remove(element)
+			delete(r.entries, key)
+		}
+	}
+}
+
+// PurgingTenants returns tenants which purge the entries of the tenants they remove
+func (r *CachingRepository) PurgingTenants(tenants TenantRepository) TenantRepository {
+	return &purgingTenants{TenantRepository: tenants, cache: r}
+}
+
+// purgingTenants is a TenantRepository which purges the cached cryptos of the removed tenants
+type purgingTenants struct {
+	TenantRepository
+	cache *CachingRepository
+}
+
+func (r *purgingTenants) RemoveTenant(ctx context.Context, id string) error {
+	defer r.cache.PurgeTenant(id)
+	return r.TenantRepository.RemoveTenant(ctx, id)
+}
+
+// purge removes all the entries
+func (r *CachingRepository) purge() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.generation++
+	r.entries = make(map[string]*list.Element)
+	r.lru.Init()
+}
+
+// isStorageFailure reports whether err is a failure of the storage rather than an answer to the read
+func isStorageFailure(err error) bool {
+	return !errors.Is(err, ErrCryptoNotFound) && !errors.Is(err, context.Canceled)
+}
+
+// copyCryptos returns a deep copy of cryptos, so the cached entries can't be changed by the callers
+func copyCryptos(cryptos []crypto.Cryptocurrency) []crypto.Cryptocurrency {
+	if cryptos == nil {
+		return nil
+	}
+
+	copied := make([]crypto.Cryptocurrency, len(cryptos))
+	for i, c := range cryptos {
+		copied[i] = c
+		copied[i].Authors = append([]crypto.Author(nil), c.Authors...)
+	}
+	return copied
+}
@@ -0,0 +1,434 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+)
+
+// countingRepository is a Repository which counts the reads of every crypto and can fail them or hold them
+type countingRepository struct {
+	Repository
+
+	mutex sync.Mutex
+	reads map[string]int
+	err   error
+	// held, if set, blocks the reads until it is closed
+	held chan struct{}
+}
+
+func newCountingRepository(t *testing.T, cryptos ...crypto.Cryptocurrency) *countingRepository {
+	return &countingRepository{
+		Repository: newTestMemoryRepository(t, cryptos...),
+		reads:      make(map[string]int),
+	}
+}
+
+func (r *countingRepository) GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+	if err := r.count(allCryptosKey); err != nil {
+		return nil, err
+	}
+	return r.Repository.GetAllCryptos(ctx)
+}
+
+func (r *countingRepository) GetSingleCrypto(ctx context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	if err := r.count(cryptoID); err != nil {
+		return crypto.Cryptocurrency{}, err
+	}
+	return r.Repository.GetSingleCrypto(ctx, cryptoID)
+}
+
+func (r *countingRepository) count(cryptoID string) error {
+	r.mutex.Lock()
+	r.reads[cryptoID]++
+	err, held := r.err, r.held
+	r.mutex.Unlock()
+
+	if held != nil {
+		<-held
+	}
+	return err
+}
+
+func (r *countingRepository) readsOf(cryptoID string) int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.reads[cryptoID]
+}
+
+func (r *countingRepository) fail(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.err = err
+}
+
+func newTestCache(r Repository, c CacheConfig) *CachingRepository {
+	c.Enabled = true
+	if c.TTL == 0 {
+		c.TTL = time.Hour
+	}
+	if c.MaxSize == 0 {
+		c.MaxSize = 100
+	}
+	return NewCachingRepository(r, &c)
+}
+
+// expire makes the entry of the crypto of the default tenant expired for d
+func expire(t *testing.T, r *CachingRepository, cryptoID string, d time.Duration) {
+	t.Helper()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	element, ok := r.entries[cacheKey(tenant.DefaultID, cryptoID)]
+	if !ok {
+		t.Fatalf("expected %s to be cached", cryptoID)
+	}
+	element.Value.(*cacheEntry).expires = time.Now().Add(-d)
+}
+
+func mustGet(t *testing.T, r Repository, cryptoID string) crypto.Cryptocurrency {
+	t.Helper()
+
+	c, err := r.GetSingleCrypto(context.Background(), cryptoID)
+	if err != nil {
+		t.Fatalf("get %s: %v", cryptoID, err)
+	}
+	return c
+}
+
+func TestCachingRepositoryLRU(t *testing.T) {
+	storage := newCountingRepository(t, testCrypto("A", "1"), testCrypto("B", "2"), testCrypto("C", "3"))
+	r := newTestCache(storage, CacheConfig{MaxSize: 2})
+
+	mustGet(t, r, "A")
+	mustGet(t, r, "B")
+	// A becomes the most recently used, so caching C evicts B
+	mustGet(t, r, "A")
+	mustGet(t, r, "C")
+
+	mustGet(t, r, "A")
+	mustGet(t, r, "C")
+	if reads := storage.readsOf("A"); reads != 1 {
+		t.Errorf("expected A to be read once, got %d", reads)
+	}
+	if reads := storage.readsOf("C"); reads != 1 {
+		t.Errorf("expected C to be read once, got %d", reads)
+	}
+
+	mustGet(t, r, "B")
+	if reads := storage.readsOf("B"); reads != 2 {
+		t.Errorf("expected the evicted B to be read again, got %d reads", reads)
+	}
+
+	stats := r.Stats()
+	if stats.Size != 2 || stats.Evictions != 2 || stats.Hits != 3 || stats.Misses != 4 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestCachingRepositoryInvalidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(r Repository) error
+		// stale are the reads whose entries must be invalidated by the write
+		stale []string
+	}{
+		{
+			name:  "add",
+			write: func(r Repository) error { return r.AddCrypto(context.Background(), testCrypto("C", "3")) },
+			stale: []string{allCryptosKey},
+		},
+		{
+			name:  "update",
+			write: func(r Repository) error { return r.UpdateCrypto(context.Background(), "A", testCrypto("A", "5")) },
+			stale: []string{allCryptosKey, "A"},
+		},
+		{
+			name:  "rename",
+			write: func(r Repository) error { return r.UpdateCrypto(context.Background(), "A", testCrypto("Z", "5")) },
+			stale: []string{allCryptosKey, "A"},
+		},
+		{
+			name:  "remove",
+			write: func(r Repository) error { return r.RemoveCrypto(context.Background(), "B") },
+			stale: []string{allCryptosKey, "B"},
+		},
+		{
+			name: "transaction",
+			write: func(r Repository) error {
+				return r.WithTx(context.Background(), func(tx Repository) error {
+					return tx.WithTx(context.Background(), func(nested Repository) error {
+						return nested.RemoveCrypto(context.Background(), "A")
+					})
+				})
+			},
+			stale: []string{allCryptosKey, "A"},
+		},
+		{
+			name: "failed write",
+			write: func(r Repository) error {
+				return r.UpdateCrypto(context.Background(), "A", testCrypto("B", "5"))
+			},
+			stale: []string{allCryptosKey, "A", "B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newCountingRepository(t, testCrypto("A", "1"), testCrypto("B", "2"))
+			r := newTestCache(storage, CacheConfig{})
+
+			read := func() {
+				if _, err := r.GetAllCryptos(context.Background()); err != nil {
+					t.Fatalf("get cryptos: %v", err)
+				}
+				_, _ = r.GetSingleCrypto(context.Background(), "A")
+				_, _ = r.GetSingleCrypto(context.Background(), "B")
+			}
+			read()
+			_ = tt.write(r)
+			read()
+
+			stale := make(map[string]bool)
+			for _, cryptoID := range tt.stale {
+				stale[cryptoID] = true
+			}
+			for _, cryptoID := range []string{allCryptosKey, "A", "B"} {
+				want := 1
+				if stale[cryptoID] {
+					want = 2
+				}
+				if reads := storage.readsOf(cryptoID); reads != want {
+					t.Errorf("expected %d reads of %q, got %d", want, cryptoID, reads)
+				}
+			}
+		})
+	}
+}
+
+func TestCachingRepositoryGenerations(t *testing.T) {
+	storage := newCountingRepository(t, testCrypto("A", "1"))
+	r := newTestCache(storage, CacheConfig{})
+
+	// A read which started before a write must not cache what it read, which may be older than the write
+	storage.held = make(chan struct{})
+	read := make(chan crypto.Cryptocurrency)
+	go func() {
+		c, _ := r.GetSingleCrypto(context.Background(), "A")
+		read <- c
+	}()
+	waitFor(t, func() bool { return storage.readsOf("A") != 0 })
+	if err := r.UpdateCrypto(context.Background(), "A", testCrypto("A", "2")); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	close(storage.held)
+	<-read
+
+	storage.held = nil
+	if c := mustGet(t, r, "A"); c.Price.String() != "2" {
+		t.Errorf("expected the updated price 2, got %v", c.Price)
+	}
+	if reads := storage.readsOf("A"); reads != 2 {
+		t.Errorf("expected the read which raced with the write not to be cached, got %d reads", reads)
+	}
+}
+
+func TestCachingRepositoryStaleWhileRevalidate(t *testing.T) {
+	storage := newCountingRepository(t, testCrypto("A", "1"))
+	r := newTestCache(storage, CacheConfig{StaleWhileRevalidate: time.Minute})
+
+	mustGet(t, r, "A")
+	if err := storage.Repository.UpdateCrypto(context.Background(), "A", testCrypto("A", "2")); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	expire(t, r, "A", time.Second)
+	if c := mustGet(t, r, "A"); c.Price.String() != "1" {
+		t.Errorf("expected the stale price 1 while refreshing, got %v", c.Price)
+	}
+	waitFor(t, func() bool {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		return len(r.refreshing) == 0
+	})
+	if c := mustGet(t, r, "A"); c.Price.String() != "2" {
+		t.Errorf("expected the refreshed price 2, got %v", c.Price)
+	}
+	if reads := storage.readsOf("A"); reads != 2 {
+		t.Errorf("expected a single refresh, got %d reads", reads)
+	}
+
+	// Past the stale window the entry is loaded before it is served
+	if err := storage.Repository.UpdateCrypto(context.Background(), "A", testCrypto("A", "3")); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	expire(t, r, "A", 2*time.Minute)
+	if c := mustGet(t, r, "A"); c.Price.String() != "3" {
+		t.Errorf("expected the loaded price 3, got %v", c.Price)
+	}
+
+	if stats := r.Stats(); stats.Stale != 1 {
+		t.Errorf("expected a single stale read, got %+v", stats)
+	}
+}
+
+func TestCachingRepositoryStaleIfError(t *testing.T) {
+	errStorage := errors.New("connection refused")
+
+	tests := []struct {
+		name      string
+		err       error
+		expiredBy time.Duration
+		wantErr   error
+	}{
+		{name: "storage failure", err: errStorage, expiredBy: time.Second},
+		{name: "storage failure past the stale window", err: errStorage, expiredBy: 2 * time.Minute, wantErr: errStorage},
+		{name: "not found", err: ErrCryptoNotFound, expiredBy: time.Second, wantErr: ErrCryptoNotFound},
+		{name: "canceled", err: context.Canceled, expiredBy: time.Second, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := newCountingRepository(t, testCrypto("A", "1"))
+			r := newTestCache(storage, CacheConfig{StaleIfError: time.Minute})
+
+			mustGet(t, r, "A")
+			expire(t, r, "A", tt.expiredBy)
+			storage.fail(tt.err)
+
+			c, err := r.GetSingleCrypto(context.Background(), "A")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && c.Price.String() != "1" {
+				t.Errorf("expected the stale price 1, got %v", c.Price)
+			}
+		})
+	}
+}
+
+func TestCachingRepositoryCopies(t *testing.T) {
+	r := newTestCache(newCountingRepository(t, testCrypto("A", "1", crypto.Author{Firstname: "Satoshi"})), CacheConfig{})
+
+	c := mustGet(t, r, "A")
+	c.Authors[0].Firstname = "Hal"
+
+	if c := mustGet(t, r, "A"); c.Authors[0].Firstname != "Satoshi" {
+		t.Errorf("expected the cached entry to be unchanged by the caller, got %s", c.Authors[0].Firstname)
+	}
+}
+
+// subscribedFeed is a Feed which signals when it is subscribed to
+type subscribedFeed struct {
+	*broadcaster
+	subscribed chan struct{}
+}
+
+func (f *subscribedFeed) Subscribe() (<-chan Change, func()) {
+	defer close(f.subscribed)
+	return f.broadcaster.Subscribe()
+}
+
+// cached reports whether the entry of the crypto of the default tenant is cached
+func cached(r *CachingRepository, cryptoID string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	_, ok := r.entries[cacheKey(tenant.DefaultID, cryptoID)]
+	return ok
+}
+
+func TestCachingRepositoryFollow(t *testing.T) {
+	r := newTestCache(newCountingRepository(t, testCrypto("A", "1"), testCrypto("B", "2")), CacheConfig{})
+	mustGet(t, r, "A")
+	mustGet(t, r, "B")
+
+	feed := &subscribedFeed{broadcaster: newBroadcaster(), subscribed: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Follow(ctx, feed)
+	<-feed.subscribed
+
+	feed.publish(context.Background(), Change{Op: OpUpdate, TenantID: tenant.DefaultID, CryptoID: "A"})
+	waitFor(t, func() bool { return !cached(r, "A") })
+	if !cached(r, "B") {
+		t.Error("expected only the entry of the changed crypto to be invalidated")
+	}
+
+	feed.publish(context.Background(), Change{Op: OpResync})
+	waitFor(t, func() bool { return r.Stats().Size == 0 })
+}
+
+func TestCachingRepositoryTenants(t *testing.T) {
+	storage := newCountingRepository(t, testCrypto("A", "1"))
+	r := newTestCache(storage, CacheConfig{})
+	acme := tenant.ContextWithTenant(context.Background(), tenant.Tenant{ID: "acme"})
+
+	for _, ctx := range []context.Context{context.Background(), acme, context.Background(), acme} {
+		if _, err := r.GetAllCryptos(ctx); err != nil {
+			t.Fatalf("get cryptos: %v", err)
+		}
+	}
+	if reads := storage.readsOf(allCryptosKey); reads != 2 {
+		t.Errorf("expected a cache entry per tenant, got %d reads", reads)
+	}
+
+	if err := r.AddCrypto(acme, testCrypto("B", "2")); err != nil {
+		t.Fatalf("add crypto: %v", err)
+	}
+	if _, err := r.GetAllCryptos(context.Background()); err != nil {
+		t.Fatalf("get cryptos: %v", err)
+	}
+	if reads := storage.readsOf(allCryptosKey); reads != 2 {
+		t.Errorf("expected the write of a tenant to keep the entries of the others, got %d reads", reads)
+	}
+}
+
+// removedTenants is a TenantRepository which records the removed tenants
+type removedTenants struct {
+	TenantRepository
+	removed []string
+}
+
+func (r *removedTenants) RemoveTenant(_ context.Context, id string) error {
+	r.removed = append(r.removed, id)
+	return nil
+}
+
+func TestCachingRepositoryPurgingTenants(t *testing.T) {
+	r := newTestCache(newCountingRepository(t, testCrypto("A", "1")), CacheConfig{})
+	mustGet(t, r, "A")
+	for _, key := range []string{cacheKey("acme", "A"), cacheKey("acme", allCryptosKey), cacheKey("acme2", "A")} {
+		r.put(key, r.generation, []crypto.Cryptocurrency{testCrypto("A", "2")})
+	}
+
+	tenants := &removedTenants{}
+	if err := r.PurgingTenants(tenants).RemoveTenant(context.Background(), "acme"); err != nil {
+		t.Fatalf("remove tenant: %v", err)
+	}
+	if len(tenants.removed) != 1 || tenants.removed[0] != "acme" {
+		t.Fatalf("expected the tenant to be removed from the storage, got %v", tenants.removed)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, key := range []string{cacheKey("acme", "A"), cacheKey("acme", allCryptosKey)} {
+		if _, ok := r.entries[key]; ok {
+			t.Errorf("expected the entry %q of the removed tenant to be purged", key)
+		}
+	}
+	for _, key := range []string{cacheKey(tenant.DefaultID, "A"), cacheKey("acme2", "A")} {
+		if _, ok := r.entries[key]; !ok {
+			t.Errorf("expected the entry %q of another tenant to be kept", key)
+		}
+	}
+	if r.lru.Len() != len(r.entries) {
+		t.Errorf("expected %d entries in the LRU list, got %d", len(r.entries), r.lru.Len())
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+// Operation is the kind of write which produced a Change
+type Operation string
+
+const (
+	OpCreate Operation = "create"
+	OpUpdate Operation = "update"
+	OpDelete Operation = "delete"
+	// OpResync is published when changes were missed, e.g. by a subscriber which fell behind,
+	// so anything derived from the previous changes must be rebuilt. It has no tenant and crypto.
+	OpResync Operation = "resync"
+)
+
+// changeBufferSize is how many changes a subscriber can fall behind before its changes are replaced by OpResync
+const changeBufferSize = 64
+
+// Change describes a single write made to the cryptos
+type Change struct {
+	Op Operation
+	// TenantID is the tenant of the crypto
+	TenantID string
+	CryptoID string
+	// OldCryptoID is set when an update renamed the crypto
+	OldCryptoID string
+	// Crypto is the written crypto, nil for deletions
+	Crypto *crypto.Cryptocurrency
+}
+
+// Feed delivers Changes to its subscribers
+type Feed interface {
+	// Subscribe returns a channel with all changes made after the call and
+	// a function which cancels the subscription and closes the channel
+	Subscribe() (<-chan Change, func())
+}
+
+// broadcaster fans out published changes to all of its subscribers
+type broadcaster struct {
+	mutex       sync.Mutex
+	subscribers map[chan Change]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{
+		subscribers: make(map[chan Change]struct{}),
+	}
+}
+
+func (b *broadcaster) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, changeBufferSize)
+
+	b.mutex.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mutex.Unlock()
+
+	once := sync.Once{}
+	return ch, func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+
+			delete(b.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// publish sends the change to every subscriber without blocking on slow ones. The pending changes of
+// a subscriber whose buffer is full are replaced by a single OpResync, so it knows it missed changes.
+func (b *broadcaster) publish(ctx context.Context, change Change) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- change:
+			continue
+		default:
+		}
+
+		log.C(ctx).Warnf("A slow subscriber missed %d changes, resyncing it", len(ch)+1)
+		// Only publish sends on ch, so it has room once drained even if the subscriber doesn't receive meanwhile
+		for len(ch) != 0 {
+			select {
+			case <-ch:
+			default:
+			}
+		}
+		ch <- Change{Op: OpResync}
+	}
+}
+
+// ObservableRepository is a Repository which publishes a Change after every successful write.
+// The changes made in a transaction are published after it is committed.
+type ObservableRepository struct {
+	observed
+	*broadcaster
+}
+
+// NewObservableRepository returns ObservableRepository which wraps r
+func NewObservableRepository(r Repository) *ObservableRepository {
+	b := newBroadcaster()
+	return &ObservableRepository{
+		observed:    observed{Repository: r, notify: b.publish},
+		broadcaster: b,
+	}
+}
+
+// observed is a Repository which calls notify after every successful write
+type observed struct {
+	Repository
+	notify func(ctx context.Context, change Change)
+}
+
+func (r observed) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	if err := r.Repository.AddCrypto(ctx, c); err != nil {
+		return err
+	}
+
+	r.notify(ctx, Change{Op: OpCreate, TenantID: tenant.IDFromContext(ctx), CryptoID: c.CryptoID, Crypto: &c})
+	return nil
+}
+
+func (r observed) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	if err := r.Repository.UpdateCrypto(ctx, oldCryptoID, c); err != nil {
+		return err
+	}
+
+	change := Change{Op: OpUpdate, TenantID: tenant.IDFromContext(ctx), CryptoID: c.CryptoID, Crypto: &c}
+	if oldCryptoID != c.CryptoID {
+		change.OldCryptoID = oldCryptoID
+	}
+	r.notify(ctx, change)
+	return nil
+}
+
+func (r observed) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	if err := r.Repository.RemoveCrypto(ctx, cryptoID); err != nil {
+		return err
+	}
+
+	r.notify(ctx, Change{Op: OpDelete, TenantID: tenant.IDFromContext(ctx), CryptoID: cryptoID})
+	return nil
+}
+
+// WithTx collects the changes made in the transaction and notifies them only once it succeeds
+func (r observed) WithTx(ctx context.Context, fn func(Repository) error) error {
+	var pending []Change
+	if err := r.Repository.WithTx(ctx, func(tx Repository) error {
+		// The changes of a failed attempt of a retried transaction were rolled back
+		pending = nil
+		return fn(observed{Repository: tx, notify: func(_ context.Context, change Change) {
+			pending = append(pending, change)
+		}})
+	}); err != nil {
+		return err
+	}
+
+	for _, change := range pending {
+		r.notify(ctx, change)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+
+	"github.com/lib/pq"
+)
+
+// stubRepository is a Repository whose writes succeed unless err is set
+type stubRepository struct {
+	Repository
+	err error
+}
+
+func (r *stubRepository) AddCrypto(context.Context, crypto.Cryptocurrency) error {
+	return r.err
+}
+
+func (r *stubRepository) UpdateCrypto(context.Context, string, crypto.Cryptocurrency) error {
+	return r.err
+}
+
+func (r *stubRepository) RemoveCrypto(context.Context, string) error {
+	return r.err
+}
+
+func TestObservableRepository(t *testing.T) {
+	errWrite := errors.New("connection refused")
+
+	tests := []struct {
+		name  string
+		err   error
+		write func(r Repository) error
+		want  []Change
+	}{
+		{
+			name: "add",
+			write: func(r Repository) error {
+				return r.AddCrypto(context.Background(), crypto.Cryptocurrency{CryptoID: "BTC"})
+			},
+			want: []Change{{Op: OpCreate, CryptoID: "BTC"}},
+		},
+		{
+			name: "update",
+			write: func(r Repository) error {
+				return r.UpdateCrypto(context.Background(), "BTC", crypto.Cryptocurrency{CryptoID: "BTC"})
+			},
+			want: []Change{{Op: OpUpdate, CryptoID: "BTC"}},
+		},
+		{
+			name: "rename",
+			write: func(r Repository) error {
+				return r.UpdateCrypto(context.Background(), "BTC", crypto.Cryptocurrency{CryptoID: "XBT"})
+			},
+			want: []Change{{Op: OpUpdate, CryptoID: "XBT", OldCryptoID: "BTC"}},
+		},
+		{
+			name:  "remove",
+			write: func(r Repository) error { return r.RemoveCrypto(context.Background(), "BTC") },
+			want:  []Change{{Op: OpDelete, CryptoID: "BTC"}},
+		},
+		{
+			name:  "failed write",
+			err:   errWrite,
+			write: func(r Repository) error { return r.RemoveCrypto(context.Background(), "BTC") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewObservableRepository(&stubRepository{err: tt.err})
+			changes, unsubscribe := r.Subscribe()
+			defer unsubscribe()
+
+			if err := tt.write(r); !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+
+			if len(changes) != len(tt.want) {
+				t.Fatalf("expected %d changes, got %d", len(tt.want), len(changes))
+			}
+			for _, want := range tt.want {
+				got := <-changes
+				if got.Op != want.Op || got.CryptoID != want.CryptoID || got.OldCryptoID != want.OldCryptoID {
+					t.Errorf("expected change %+v, got %+v", want, got)
+				}
+				if (got.Crypto == nil) != (want.Op == OpDelete) {
+					t.Errorf("expected the written crypto only in the changes other than deletions, got %+v", got)
+				}
+			}
+		})
+	}
+}
+
+func TestBroadcaster(t *testing.T) {
+	b := newBroadcaster()
+	slow, unsubscribeSlow := b.Subscribe()
+	fast, unsubscribeFast := b.Subscribe()
+	defer unsubscribeFast()
+
+	// The publishing never blocks on a subscriber which doesn't receive its changes
+	for i := 0; i < changeBufferSize+1; i++ {
+		b.publish(context.Background(), Change{Op: OpCreate, CryptoID: benchmarkCryptoID(i)})
+		if change := <-fast; change.Op != OpCreate {
+			t.Fatalf("expected the fast subscriber to get every change, got %s", change.Op)
+		}
+	}
+	b.publish(context.Background(), Change{Op: OpDelete, CryptoID: "next"})
+	<-fast
+
+	// The changes missed by the slow subscriber are replaced by a resync
+	want := []Change{{Op: OpResync}, {Op: OpDelete, CryptoID: "next"}}
+	if len(slow) != len(want) {
+		t.Fatalf("expected the missed changes to be replaced by a resync, got %d changes", len(slow))
+	}
+	for _, w := range want {
+		if got := <-slow; got.Op != w.Op || got.CryptoID != w.CryptoID {
+			t.Errorf("expected change %+v, got %+v", w, got)
+		}
+	}
+
+	unsubscribeSlow()
+	unsubscribeSlow()
+	for range slow {
+	}
+	b.publish(context.Background(), Change{Op: OpDelete, CryptoID: "BTC"})
+	if change := <-fast; change.Op != OpDelete {
+		t.Errorf("expected the remaining subscriber to get the change, got %s", change.Op)
+	}
+}
+
+func TestObservableRepositoryWithTx(t *testing.T) {
+	errRollback := errors.New("rollback")
+	r := NewObservableRepository(NewMemoryRepository())
+	changes, unsubscribe := r.Subscribe()
+	defer unsubscribe()
+
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		if err := tx.AddCrypto(context.Background(), testCrypto("BTC", "1")); err != nil {
+			return err
+		}
+		if len(changes) != 0 {
+			t.Errorf("expected no changes before the commit, got %d", len(changes))
+		}
+		return tx.AddCrypto(context.Background(), testCrypto("ETH", "1"))
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+	for _, want := range []string{"BTC", "ETH"} {
+		if got := <-changes; got.CryptoID != want {
+			t.Errorf("expected the change of %s, got %+v", want, got)
+		}
+	}
+
+	err = r.WithTx(context.Background(), func(tx Repository) error {
+		if err := tx.RemoveCrypto(context.Background(), "BTC"); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Fatalf("expected error %v, got %v", errRollback, err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes of the rolled back transaction, got %d", len(changes))
+	}
+}
+
+func TestObservableRepositoryRetriedTx(t *testing.T) {
+	r := NewObservableRepository(withRetries(&flakyRepository{errs: []error{nil, &pq.Error{Code: pqSerializationFailure}}}, testRetryConfig()))
+	changes, unsubscribe := r.Subscribe()
+	defer unsubscribe()
+
+	attempt := 0
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		attempt++
+		return tx.RemoveCrypto(context.Background(), fmt.Sprintf("ATTEMPT%d", attempt))
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("expected only the changes of the last attempt, got %d", len(changes))
+	}
+	if got := <-changes; got.CryptoID != "ATTEMPT2" {
+		t.Errorf("expected the change of ATTEMPT2, got %+v", got)
+	}
+}
+
+// waitFor waits up to a second until condition holds
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
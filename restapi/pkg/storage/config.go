@@ -1,26 +1,169 @@
 package storage
 
-import "fmt"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Types of the storage
+const (
+	TypePostgres = "postgres"
+	TypeMemory   = "memory"
+	TypeSQLite   = "sqlite"
+)
 
 type Config struct {
-	Type       string     `mapstructure:"type" description:"Type of the storage"`
+	Type       string     `mapstructure:"type" description:"Type of the storage, one of postgres, sqlite or memory"`
 	DataSource DataSource `mapstructure:"data_source" description:"Data source name of the storage"`
+	// Replicas can be set only in the configuration files
+	Replicas       []DataSource   `mapstructure:"replicas" description:"data sources of the postgres read replicas"`
+	ReplicaRouting *ReplicaConfig `mapstructure:"replica_routing" description:"routing of the reads to the replicas"`
+	SQLite         *SQLiteConfig  `mapstructure:"sqlite" description:"settings of the sqlite storage"`
+	Memory         *MemoryConfig  `mapstructure:"memory" description:"settings of the memory storage"`
+	// IsolationLevel is one of the keys of isolationLevels
+	IsolationLevel string          `mapstructure:"isolation_level" description:"isolation level of the transactions, one of default, read_uncommitted, read_committed, repeatable_read or serializable"`
+	QueryTimeouts  *QueryTimeouts  `mapstructure:"query_timeouts" description:"deadlines of the repository calls"`
+	Retry          *RetryConfig    `mapstructure:"retry" description:"retries of the repository calls failing with transient errors"`
+	Cache          *CacheConfig    `mapstructure:"cache" description:"in-memory cache of the repository reads"`
+	Listener       *ListenerConfig `mapstructure:"listener" description:"listener of the changes made by all the instances"`
+	Prices         *PriceConfig    `mapstructure:"prices" description:"precision of the prices of the cryptos"`
+	AutoMigrate    bool            `mapstructure:"auto_migrate" description:"whether to apply the pending postgres migrations on startup, sqlite is always migrated"`
+
+	MaxOpenConns    int           `mapstructure:"max_open_conns" description:"maximum number of open database connections, 0 for unlimited"`
+	MaxIdleConns    int           `mapstructure:"max_idle_conns" description:"maximum number of idle database connections, 0 for none"`
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime" description:"maximum time a database connection is reused, 0 for unlimited"`
+	ConnMaxIdleTime time.Duration `mapstructure:"conn_max_idle_time" description:"maximum time a database connection stays idle, 0 for unlimited"`
+	StartupTimeout  time.Duration `mapstructure:"startup_timeout" description:"how long to wait for the database to answer on startup"`
+}
+
+// SQLiteConfig contains the settings of the sqlite storage
+type SQLiteConfig struct {
+	Path        string        `mapstructure:"path" description:"path to the SQLite database file, created and migrated if missing"`
+	BusyTimeout time.Duration `mapstructure:"busy_timeout" description:"how long to wait for a locked database before failing"`
+}
+
+// MemoryConfig contains the settings of the memory storage
+type MemoryConfig struct {
+	Fixture  string `mapstructure:"fixture" description:"JSON or YAML file with the cryptos loaded on startup, none if empty"`
+	Snapshot string `mapstructure:"snapshot" description:"JSON or YAML file to which the cryptos are saved on shutdown, none if empty"`
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		Type:       "postgres",
+		Type:       TypePostgres,
 		DataSource: DefaultDataSource(),
+		SQLite: &SQLiteConfig{
+			Path:        "restapi.db",
+			BusyTimeout: 5 * time.Second,
+		},
+		Memory:         &MemoryConfig{},
+		ReplicaRouting: DefaultReplicaConfig(),
+		IsolationLevel: "default",
+		QueryTimeouts:  DefaultQueryTimeouts(),
+		Retry:          DefaultRetryConfig(),
+		Cache:          DefaultCacheConfig(),
+		Listener:       DefaultListenerConfig(),
+		Prices:         DefaultPriceConfig(),
+
+		MaxOpenConns:    25,
+		MaxIdleConns:    25,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		StartupTimeout:  30 * time.Second,
 	}
 }
 
+// isolationLevels are the supported transaction isolation levels
+var isolationLevels = map[string]sql.IsolationLevel{
+	"default":          sql.LevelDefault,
+	"read_uncommitted": sql.LevelReadUncommitted,
+	"read_committed":   sql.LevelReadCommitted,
+	"repeatable_read":  sql.LevelRepeatableRead,
+	"serializable":     sql.LevelSerializable,
+}
+
 func (c *Config) Validate() error {
-	if len(c.Type) == 0 {
-		return fmt.Errorf("validate Storage settings: Type missing")
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("validate Storage settings: "+format, args...))
+	}
+
+	if _, ok := isolationLevels[c.IsolationLevel]; !ok {
+		fail("unknown IsolationLevel %s", c.IsolationLevel)
+	}
+	subsystems := []struct {
+		name      string
+		missing   bool
+		validator interface{ Validate() error }
+	}{
+		{"QueryTimeouts", c.QueryTimeouts == nil, c.QueryTimeouts},
+		{"Retry", c.Retry == nil, c.Retry},
+		{"Cache", c.Cache == nil, c.Cache},
+		{"Listener", c.Listener == nil, c.Listener},
+		{"ReplicaRouting", c.ReplicaRouting == nil, c.ReplicaRouting},
+		{"Prices", c.Prices == nil, c.Prices},
+	}
+	for _, subsystem := range subsystems {
+		if subsystem.missing {
+			fail("%s missing", subsystem.name)
+			continue
+		}
+		if err := subsystem.validator.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.Listener != nil && c.Listener.Enabled && c.Type != TypePostgres {
+		fail("Listener is supported only by the postgres storage")
+	}
+	if len(c.Replicas) != 0 && c.Type != TypePostgres {
+		fail("Replicas are supported only by the postgres storage")
 	}
-	if err := c.DataSource.Validate(); err != nil {
-		return fmt.Errorf("validate Storage settings: %v", err.Error())
+	for i, replica := range c.Replicas {
+		if len(replica.Host) == 0 {
+			fail("Host of replica %d missing", i)
+		}
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		fail("MaxOpenConns and MaxIdleConns must not be negative")
+	}
+	if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+		fail("ConnMaxLifetime and ConnMaxIdleTime must not be negative")
+	}
+	if c.StartupTimeout <= 0 {
+		fail("StartupTimeout missing")
+	}
+
+	switch c.Type {
+	case "":
+		fail("Type missing")
+	case TypePostgres:
+		if err := c.DataSource.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	case TypeSQLite:
+		if c.SQLite == nil {
+			fail("SQLite missing")
+			break
+		}
+		if len(c.SQLite.Path) == 0 {
+			fail("SQLite Path missing")
+		}
+		if c.SQLite.BusyTimeout < 0 {
+			fail("SQLite BusyTimeout must not be negative")
+		}
+		// SQLite transactions are always serializable
+		if level := isolationLevels[c.IsolationLevel]; level != sql.LevelDefault && level != sql.LevelSerializable {
+			fail("IsolationLevel %s is not supported by sqlite", c.IsolationLevel)
+		}
+	case TypeMemory:
+		if c.Memory == nil {
+			fail("Memory missing")
+		}
+	default:
+		fail("unknown Type %s", c.Type)
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
@@ -1,19 +1,61 @@
 package storage
 
-import "fmt"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type DataSource struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `mapstructure:"host" description:"host of the database"`
+	Port     string `mapstructure:"port" description:"port of the database"`
+	User     string `mapstructure:"user" description:"user to connect to the database with"`
+	Password string `mapstructure:"password" description:"password of the database user"`
+	DBName   string `mapstructure:"dbname" description:"name of the database"`
+	SSLMode  string `mapstructure:"sslmode" description:"SSL mode of the database connection"`
 }
 
+// maskedPassword replaces the password wherever a DataSource is printed
+const maskedPassword = "******"
+
+// DSN returns the connection string of the data source. It contains the password and must never be logged,
+// use String instead.
+func (ds DataSource) DSN() string {
+	return ds.format(ds.Password)
+}
+
+// String returns the connection string of the data source with the password masked
 func (ds DataSource) String() string {
+	return ds.format(maskedPassword)
+}
+
+// GoString masks the password when the data source is printed with %#v
+func (ds DataSource) GoString() string {
+	return fmt.Sprintf("storage.DataSource{%s}", ds)
+}
+
+// MarshalJSON masks the password when the data source is logged in JSON format
+func (ds DataSource) MarshalJSON() ([]byte, error) {
+	masked := ds
+	masked.Password = maskedPassword
+	return json.Marshal(dataSource(masked))
+}
+
+// dataSource has the fields of DataSource without its methods
+type dataSource DataSource
+
+func (ds DataSource) format(password string) string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		ds.Host, ds.Port, ds.User, ds.Password, ds.DBName, ds.SSLMode)
+		quoteDSN(ds.Host), quoteDSN(ds.Port), quoteDSN(ds.User), quoteDSN(password), quoteDSN(ds.DBName), quoteDSN(ds.SSLMode))
+}
+
+// dsnEscaper escapes the characters which lib/pq unescapes in the quoted values of a connection string
+var dsnEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+// quoteDSN quotes value as lib/pq expects, so that spaces, quotes and backslashes in it are kept as they are
+func quoteDSN(value string) string {
+	return "'" + dsnEscaper.Replace(value) + "'"
 }
 
 func DefaultDataSource() DataSource {
@@ -36,26 +78,27 @@ func NewDSN(opts ...Option) *DataSource {
 }
 
 func (ds *DataSource) Validate() error {
+	var errs []error
 	if len(ds.Host) == 0 {
-		return fmt.Errorf("validate DataSource settings: Host missing")
+		errs = append(errs, fmt.Errorf("validate DataSource settings: Host missing"))
 	}
 	if len(ds.Port) == 0 {
-		return fmt.Errorf("validate DataSource settings: Port missing")
+		errs = append(errs, fmt.Errorf("validate DataSource settings: Port missing"))
 	}
 	if len(ds.User) == 0 {
-		return fmt.Errorf("validate DataSource settings: User missing")
+		errs = append(errs, fmt.Errorf("validate DataSource settings: User missing"))
 	}
 	if len(ds.Password) == 0 {
-		return fmt.Errorf("validate DataSource settings: Password missing")
+		errs = append(errs, fmt.Errorf("validate DataSource settings: Password missing"))
 	}
 	if len(ds.DBName) == 0 {
-		return fmt.Errorf("validate DataSource settings: Database name missing")
+		errs = append(errs, fmt.Errorf("validate DataSource settings: Database name missing"))
 	}
 	if len(ds.SSLMode) == 0 {
-		return fmt.Errorf("validate DataSource settings: SSL Mode missing")
+		errs = append(errs, fmt.Errorf("validate DataSource settings: SSL Mode missing"))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // Functional options
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDataSourceMasksPassword(t *testing.T) {
+	ds := DefaultDataSource()
+	ds.Password = "secret"
+
+	if !strings.Contains(ds.DSN(), "password='secret'") {
+		t.Errorf("expected the DSN to contain the password, got %s", ds.DSN())
+	}
+
+	b, err := json.Marshal(ds)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	printed := map[string]string{
+		"String":   ds.String(),
+		"%v":       fmt.Sprintf("%v", ds),
+		"%+v":      fmt.Sprintf("%+v", ds),
+		"%#v":      fmt.Sprintf("%#v", ds),
+		"JSON":     string(b),
+		"pointer":  fmt.Sprintf("%v", &ds),
+		"embedded": fmt.Sprintf("%v", Config{DataSource: ds}),
+	}
+	for name, s := range printed {
+		if strings.Contains(s, "secret") || !strings.Contains(s, maskedPassword) {
+			t.Errorf("%s: expected the password to be masked, got %s", name, s)
+		}
+	}
+}
+
+func TestDataSourceQuotesValues(t *testing.T) {
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{password: "secret", want: `password='secret'`},
+		{password: "", want: `password=''`},
+		{password: "two words", want: `password='two words'`},
+		{password: "it's", want: `password='it\'s'`},
+		{password: `back\slash`, want: `password='back\\slash'`},
+		{password: "a dbname=evil", want: `password='a dbname=evil' dbname='postgres'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			ds := DefaultDataSource()
+			ds.Password = tt.password
+
+			if dsn := ds.DSN(); !strings.Contains(dsn, tt.want) {
+				t.Errorf("expected the DSN to contain %s, got %s", tt.want, dsn)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Tables of the Cryptos schema
+const (
+	cryptocurrenciesTable = "CRYPTOCURRENCIES"
+	authorsTable          = "AUTHORS"
+)
+
+// dialect contains what differs between the SQL of the supported databases
+type dialect struct {
+	// driver is the name of the database/sql driver
+	driver string
+	// system identifies the database in the traces
+	system attribute.KeyValue
+	// schema qualifies the tables, empty for databases without schemas
+	schema string
+	// rowSecurity is whether the tables of the cryptos have row-level security policies
+	// whose writes need the tenant in the tenantSetting of the transaction
+	rowSecurity bool
+}
+
+// tenantSetting is the setting in which the postgres policies look for the tenant of the transaction
+const tenantSetting = "cryptos.tenant_id"
+
+var dialects = map[string]dialect{
+	TypePostgres: {driver: "postgres", system: semconv.DBSystemNamePostgreSQL, schema: "CRYPTOS", rowSecurity: true},
+	TypeSQLite:   {driver: "sqlite", system: semconv.DBSystemNameSQLite},
+}
+
+// table returns the name of the table qualified with the schema of the dialect, e.g. CRYPTOS.AUTHORS
+func (d dialect) table(name string) string {
+	if len(d.schema) == 0 {
+		return name
+	}
+	return d.schema + "." + name
+}
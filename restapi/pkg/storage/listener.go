@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"github.com/lib/pq"
+)
+
+// listenerPingInterval is how often the connection of the Listener is checked while there are no notifications
+const listenerPingInterval = 90 * time.Second
+
+// ListenerConfig contains the settings of listening for the changes made by all the instances
+type ListenerConfig struct {
+	Enabled              bool          `mapstructure:"enabled" description:"whether to listen for the changes notified by the postgres storage"`
+	Channel              string        `mapstructure:"channel" description:"channel on which the changes are notified"`
+	MinReconnectInterval time.Duration `mapstructure:"min_reconnect_interval" description:"time to wait before reconnecting after the connection is lost"`
+	MaxReconnectInterval time.Duration `mapstructure:"max_reconnect_interval" description:"maximum time to wait between the reconnection attempts"`
+}
+
+func DefaultListenerConfig() *ListenerConfig {
+	return &ListenerConfig{
+		Enabled:              false,
+		Channel:              "cryptos_changes",
+		MinReconnectInterval: time.Second,
+		MaxReconnectInterval: time.Minute,
+	}
+}
+
+func (c *ListenerConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if len(c.Channel) == 0 {
+		errs = append(errs, fmt.Errorf("validate Listener settings: Channel missing"))
+	}
+	if c.MinReconnectInterval <= 0 {
+		errs = append(errs, fmt.Errorf("validate Listener settings: MinReconnectInterval missing"))
+	}
+	if c.MaxReconnectInterval < c.MinReconnectInterval {
+		errs = append(errs, fmt.Errorf("validate Listener settings: MaxReconnectInterval must not be less than MinReconnectInterval"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// notification is the payload of the notifications sent by the triggers of the cryptos and their authors
+type notification struct {
+	Table       string `json:"table"`
+	Op          string `json:"op"`
+	TenantID    string `json:"tenant_id"`
+	CryptoID    string `json:"crypto_id"`
+	OldCryptoID string `json:"old_crypto_id"`
+}
+
+// change returns the Change of the crypto described by the notification. The changes of the authors
+// are updates of their crypto. Changes have no Crypto since the notifications carry only the CryptoIDs.
+func (n notification) change() Change {
+	change := Change{Op: OpUpdate, TenantID: n.TenantID, CryptoID: n.CryptoID}
+	// The triggers of the migrations before the tenants don't notify them
+	if len(change.TenantID) == 0 {
+		change.TenantID = tenant.DefaultID
+	}
+	if n.Table == "authors" {
+		return change
+	}
+
+	switch n.Op {
+	case "INSERT":
+		change.Op = OpCreate
+	case "DELETE":
+		change.Op = OpDelete
+	}
+	if n.OldCryptoID != n.CryptoID {
+		change.OldCryptoID = n.OldCryptoID
+	}
+	return change
+}
+
+// Listener is a Feed of the changes made to the postgres storage by any instance.
+// It reconnects automatically when its connection is lost and publishes OpResync once reconnected.
+type Listener struct {
+	*broadcaster
+	listener *pq.Listener
+	channel  string
+}
+
+// NewListener returns Listener of the changes notified on the configured channel of the database in ds
+func NewListener(ds DataSource, c *ListenerConfig) *Listener {
+	logger := log.C(context.Background())
+	return &Listener{
+		broadcaster: newBroadcaster(),
+		listener: pq.NewListener(ds.DSN(), c.MinReconnectInterval, c.MaxReconnectInterval, func(event pq.ListenerEventType, err error) {
+			switch event {
+			case pq.ListenerEventConnected:
+				logger.Infof("Listening for changes on %s", c.Channel)
+			case pq.ListenerEventDisconnected:
+				logger.WithError(err).Warn("Lost the connection of the changes listener, reconnecting...")
+			case pq.ListenerEventReconnected:
+				logger.Info("Reconnected the changes listener")
+			case pq.ListenerEventConnectionAttemptFailed:
+				logger.WithError(err).Warn("Couldn't connect the changes listener")
+			}
+		}),
+		channel: c.Channel,
+	}
+}
+
+// Start listens for changes and publishes them to the subscribers until ctx is done
+func (l *Listener) Start(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		if err := l.listener.Close(); err != nil {
+			log.C(ctx).WithError(err).Error("an error occurred while closing the changes listener")
+		}
+	}()
+
+	// Listen waits for the first connection, so it fails only once the listener is closed
+	if err := l.listener.Listen(l.channel); err != nil {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("couldn't listen on %s: %v", l.channel, err)
+	}
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.C(ctx).Info("Changes listener stopped.")
+			return nil
+		case <-ticker.C:
+			// A failed ping makes the listener notice a dead connection and reconnect
+			go l.listener.Ping()
+		case n, ok := <-l.listener.Notify:
+			if !ok {
+				return nil
+			}
+			// A nil notification means that the connection was reestablished
+			if n == nil {
+				l.publish(ctx, Change{Op: OpResync})
+				continue
+			}
+
+			var payload notification
+			if err := json.Unmarshal([]byte(n.Extra), &payload); err != nil {
+				log.C(ctx).WithError(err).Errorf("an error occurred while decoding change notification %q", n.Extra)
+				continue
+			}
+			l.publish(ctx, payload.change())
+		}
+	}
+}
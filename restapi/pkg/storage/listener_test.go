@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestNotificationChange(t *testing.T) {
+	tests := []struct {
+		name         string
+		notification notification
+		want         Change
+	}{
+		{name: "insert", notification: notification{Table: "cryptocurrencies", Op: "INSERT", CryptoID: "BTC", OldCryptoID: "BTC"}, want: Change{Op: OpCreate, CryptoID: "BTC"}},
+		{name: "update", notification: notification{Table: "cryptocurrencies", Op: "UPDATE", CryptoID: "BTC", OldCryptoID: "BTC"}, want: Change{Op: OpUpdate, CryptoID: "BTC"}},
+		{name: "rename", notification: notification{Table: "cryptocurrencies", Op: "UPDATE", CryptoID: "XBT", OldCryptoID: "BTC"}, want: Change{Op: OpUpdate, CryptoID: "XBT", OldCryptoID: "BTC"}},
+		{name: "delete", notification: notification{Table: "cryptocurrencies", Op: "DELETE", CryptoID: "BTC", OldCryptoID: "BTC"}, want: Change{Op: OpDelete, CryptoID: "BTC"}},
+		{name: "author", notification: notification{Table: "authors", Op: "DELETE", CryptoID: "BTC", OldCryptoID: "BTC"}, want: Change{Op: OpUpdate, CryptoID: "BTC"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.notification.change(); got.Op != tt.want.Op || got.CryptoID != tt.want.CryptoID || got.OldCryptoID != tt.want.OldCryptoID || got.Crypto != nil {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestListenerConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "disabled", modify: func(c *Config) { c.Listener.Channel = "" }},
+		{name: "postgres", modify: func(c *Config) { c.Listener.Enabled = true }},
+		{name: "sqlite", modify: func(c *Config) { c.Type = TypeSQLite; c.Listener.Enabled = true }, wantErr: true},
+		{name: "no channel", modify: func(c *Config) { c.Listener.Enabled = true; c.Listener.Channel = "" }, wantErr: true},
+		{name: "no reconnect interval", modify: func(c *Config) { c.Listener.Enabled = true; c.Listener.MinReconnectInterval = 0 }, wantErr: true},
+		{name: "max reconnect interval less than min", modify: func(c *Config) {
+			c.Listener.Enabled = true
+			c.Listener.MaxReconnectInterval = c.Listener.MinReconnectInterval / 2
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Limits of the columns of the Cryptos schema which the memory repository enforces too
+const (
+	maxNameLength     = 20
+	maxCryptoIDLength = 10
+	maxAuthorLength   = 20
+)
+
+// MemoryRepository is a concurrency-safe Repository which keeps the cryptos in memory
+// with the semantics of the Cryptos schema: unique CryptoIDs, positive prices,
+// authors deleted with their crypto and renamed with it on update
+type MemoryRepository struct {
+	mutex   sync.RWMutex
+	cryptos map[string]crypto.Cryptocurrency
+	// order keeps the CryptoIDs in insertion order, as the rows are returned by the database
+	order []string
+}
+
+// NewMemoryRepository returns an empty MemoryRepository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		cryptos: make(map[string]crypto.Cryptocurrency),
+	}
+}
+
+func (r *MemoryRepository) GetAllCryptos(_ context.Context) ([]crypto.Cryptocurrency, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var cryptos []crypto.Cryptocurrency
+	for _, cryptoID := range r.order {
+		cryptos = append(cryptos, clone(r.cryptos[cryptoID]))
+	}
+	return cryptos, nil
+}
+
+func (r *MemoryRepository) GetSingleCrypto(_ context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	c, ok := r.cryptos[cryptoID]
+	if !ok {
+		return crypto.Cryptocurrency{}, fmt.Errorf("an error occurred while querying cryptos from memory: %w", ErrCryptoNotFound)
+	}
+	return clone(c), nil
+}
+
+func (r *MemoryRepository) AddCrypto(_ context.Context, c crypto.Cryptocurrency) error {
+	c, err := normalize(c)
+	if err != nil {
+		return fmt.Errorf("an error occurred while inserting crypto in memory: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.cryptos[c.CryptoID]; exists {
+		return fmt.Errorf("an error occurred while inserting crypto in memory: %w", ErrCryptoAlreadyExists)
+	}
+
+	r.cryptos[c.CryptoID] = c
+	r.order = append(r.order, c.CryptoID)
+	return nil
+}
+
+func (r *MemoryRepository) UpdateCrypto(_ context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	c, err := normalize(c)
+	if err != nil {
+		return fmt.Errorf("an error occurred while updating crypto in memory: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.cryptos[oldCryptoID]; !exists {
+		return fmt.Errorf("an error occurred while updating crypto in memory: %w", ErrCryptoNotFound)
+	}
+	if _, exists := r.cryptos[c.CryptoID]; exists && c.CryptoID != oldCryptoID {
+		return fmt.Errorf("an error occurred while updating crypto in memory: %w", ErrCryptoAlreadyExists)
+	}
+
+	delete(r.cryptos, oldCryptoID)
+	r.cryptos[c.CryptoID] = c
+	for i := range r.order {
+		if r.order[i] == oldCryptoID {
+			r.order[i] = c.CryptoID
+			break
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) RemoveCrypto(_ context.Context, cryptoID string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.cryptos[cryptoID]; !exists {
+		return fmt.Errorf("an error occurred while deleting crypto in memory: %w", ErrCryptoNotFound)
+	}
+
+	delete(r.cryptos, cryptoID)
+	for i := range r.order {
+		if r.order[i] == cryptoID {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) PingWithContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// WithTx calls fn with a copy of the repository and replaces the cryptos with the ones in the copy if fn succeeds.
+// The other calls wait for the transaction, which makes the transactions serializable.
+func (r *MemoryRepository) WithTx(_ context.Context, fn func(Repository) error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	tx := &MemoryRepository{
+		cryptos: make(map[string]crypto.Cryptocurrency, len(r.cryptos)),
+		order:   append([]string(nil), r.order...),
+	}
+	// The stored cryptos are never modified in place, so they can be shared with the copy
+	for cryptoID, c := range r.cryptos {
+		tx.cryptos[cryptoID] = c
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	r.cryptos, r.order = tx.cryptos, tx.order
+	return nil
+}
+
+// Load adds the cryptos in the JSON or YAML file to the repository
+func (r *MemoryRepository) Load(ctx context.Context, path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("could not read cryptos: %v", err)
+	}
+
+	var cryptos []crypto.Cryptocurrency
+	if isYAML(path) {
+		err = yaml.Unmarshal(content, &cryptos)
+	} else {
+		err = json.Unmarshal(content, &cryptos)
+	}
+	if err != nil {
+		return fmt.Errorf("could not decode cryptos from %s: %v", path, err)
+	}
+
+	for _, c := range cryptos {
+		if err := r.AddCrypto(ctx, c); err != nil {
+			return fmt.Errorf("could not load crypto with CryptoID=%s from %s: %v", c.CryptoID, path, err)
+		}
+	}
+
+	log.C(ctx).Infof("Loaded %d cryptos from %s", len(cryptos), path)
+	return nil
+}
+
+// Snapshot writes all the cryptos in the repository to the JSON or YAML file.
+// The file is replaced atomically so a failed snapshot never leaves a partial file behind.
+func (r *MemoryRepository) Snapshot(ctx context.Context, path string) error {
+	cryptos, err := r.GetAllCryptos(ctx)
+	if err != nil {
+		return err
+	}
+
+	var content []byte
+	if isYAML(path) {
+		content, err = yaml.Marshal(cryptos)
+	} else {
+		content, err = json.MarshalIndent(cryptos, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("could not encode cryptos: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not write cryptos snapshot: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write cryptos snapshot: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write cryptos snapshot: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("could not write cryptos snapshot: %v", err)
+	}
+
+	log.C(ctx).Infof("Saved %d cryptos to %s", len(cryptos), path)
+	return nil
+}
+
+func isYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// normalize applies the constraints of the Cryptos schema to c and returns the crypto as it would be stored
+func normalize(c crypto.Cryptocurrency) (crypto.Cryptocurrency, error) {
+	if len(c.Name) == 0 || len(c.Name) > maxNameLength {
+		return c, fmt.Errorf("name must be between 1 and %d characters", maxNameLength)
+	}
+	if len(c.CryptoID) == 0 || len(c.CryptoID) > maxCryptoIDLength {
+		return c, fmt.Errorf("crypto_id must be between 1 and %d characters", maxCryptoIDLength)
+	}
+
+	if !c.Price.IsPositive() {
+		return c, fmt.Errorf("price must be positive")
+	}
+
+	seen := make(map[crypto.Author]bool, len(c.Authors))
+	authors := make([]crypto.Author, 0, len(c.Authors))
+	for _, a := range c.Authors {
+		if len(a.Firstname) > maxAuthorLength || len(a.Lastname) > maxAuthorLength {
+			return c, fmt.Errorf("author names must be at most %d characters", maxAuthorLength)
+		}
+		if seen[a] {
+			return c, fmt.Errorf("duplicate author %s %s", a.Firstname, a.Lastname)
+		}
+		seen[a] = true
+		authors = append(authors, a)
+	}
+	c.Authors = authors
+
+	return c, nil
+}
+
+// clone returns a copy of c which doesn't share the authors with it, without authors if c has none as the database
+func clone(c crypto.Cryptocurrency) crypto.Cryptocurrency {
+	if len(c.Authors) == 0 {
+		c.Authors = nil
+		return c
+	}
+	c.Authors = append([]crypto.Author(nil), c.Authors...)
+	return c
+}
+
+// memoryTenants is a Repository and a TenantRepository which keeps the cryptos of every tenant in a MemoryRepository
+// of its own. The default tenant has the MemoryRepository of the storage, which is loaded from the fixture and
+// saved to the snapshot, so the other tenants and their cryptos are lost on shutdown.
+type memoryTenants struct {
+	mutex   sync.RWMutex
+	tenants map[string]tenant.Tenant
+	cryptos map[string]*MemoryRepository
+}
+
+// newMemoryTenants returns memoryTenants with the default tenant, whose cryptos are in memory
+func newMemoryTenants(memory *MemoryRepository) *memoryTenants {
+	return &memoryTenants{
+		tenants: map[string]tenant.Tenant{tenant.DefaultID: {ID: tenant.DefaultID, Name: "Default"}},
+		cryptos: map[string]*MemoryRepository{tenant.DefaultID: memory},
+	}
+}
+
+// of returns the cryptos of the tenant of ctx
+func (r *memoryTenants) of(ctx context.Context) (*MemoryRepository, error) {
+	id := tenant.IDFromContext(ctx)
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	cryptos, ok := r.cryptos[id]
+	if !ok {
+		return nil, fmt.Errorf("an error occurred while querying tenant %s from memory: %w", id, ErrTenantNotFound)
+	}
+	return cryptos, nil
+}
+
+func (r *memoryTenants) GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+	cryptos, err := r.of(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cryptos.GetAllCryptos(ctx)
+}
+
+func (r *memoryTenants) GetSingleCrypto(ctx context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	cryptos, err := r.of(ctx)
+	if err != nil {
+		return crypto.Cryptocurrency{}, err
+	}
+	return cryptos.GetSingleCrypto(ctx, cryptoID)
+}
+
+func (r *memoryTenants) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	cryptos, err := r.of(ctx)
+	if err != nil {
+		return err
+	}
+	return cryptos.AddCrypto(ctx, c)
+}
+
+func (r *memoryTenants) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	cryptos, err := r.of(ctx)
+	if err != nil {
+		return err
+	}
+	return cryptos.UpdateCrypto(ctx, oldCryptoID, c)
+}
+
+func (r *memoryTenants) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	cryptos, err := r.of(ctx)
+	if err != nil {
+		return err
+	}
+	return cryptos.RemoveCrypto(ctx, cryptoID)
+}
+
+func (r *memoryTenants) PingWithContext(ctx context.Context) error {
+	return ctx.Err()
+}
+
+// WithTx makes the transaction on the cryptos of the tenant of ctx
+func (r *memoryTenants) WithTx(ctx context.Context, fn func(Repository) error) error {
+	cryptos, err := r.of(ctx)
+	if err != nil {
+		return err
+	}
+	return cryptos.WithTx(ctx, fn)
+}
+
+func (r *memoryTenants) GetAllTenants(_ context.Context) ([]tenant.Tenant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	tenants := make([]tenant.Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	// The tenants are ordered by ID as the database returns them
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants, nil
+}
+
+func (r *memoryTenants) GetTenant(_ context.Context, id string) (tenant.Tenant, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	t, ok := r.tenants[id]
+	if !ok {
+		return t, fmt.Errorf("an error occurred while querying tenant from memory: %w", ErrTenantNotFound)
+	}
+	return t, nil
+}
+
+func (r *memoryTenants) AddTenant(_ context.Context, t tenant.Tenant) error {
+	if err := ValidateTenant(t); err != nil {
+		return fmt.Errorf("an error occurred while inserting tenant in memory: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.tenants[t.ID]; exists {
+		return fmt.Errorf("an error occurred while inserting tenant in memory: %w", ErrTenantAlreadyExists)
+	}
+	r.tenants[t.ID] = t
+	r.cryptos[t.ID] = NewMemoryRepository()
+	return nil
+}
+
+func (r *memoryTenants) UpdateTenant(_ context.Context, t tenant.Tenant) error {
+	if err := ValidateTenant(t); err != nil {
+		return fmt.Errorf("an error occurred while updating tenant in memory: %v", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.tenants[t.ID]; !exists {
+		return fmt.Errorf("an error occurred while updating tenant in memory: %w", ErrTenantNotFound)
+	}
+	r.tenants[t.ID] = t
+	return nil
+}
+
+func (r *memoryTenants) RemoveTenant(_ context.Context, id string) error {
+	if id == tenant.DefaultID {
+		return ErrDefaultTenant
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.tenants[id]; !exists {
+		return fmt.Errorf("an error occurred while deleting tenant in memory: %w", ErrTenantNotFound)
+	}
+	delete(r.tenants, id)
+	delete(r.cryptos, id)
+	return nil
+}
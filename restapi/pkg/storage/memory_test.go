@@ -0,0 +1,399 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+
+	"github.com/shopspring/decimal"
+)
+
+func testCrypto(cryptoID, price string, authors ...crypto.Author) crypto.Cryptocurrency {
+	return crypto.Cryptocurrency{
+		Name:     "Crypto " + cryptoID,
+		CryptoID: cryptoID,
+		Price:    decimal.RequireFromString(price),
+		Authors:  authors,
+	}
+}
+
+// newTestMemoryRepository returns a MemoryRepository with cryptos
+func newTestMemoryRepository(t *testing.T, cryptos ...crypto.Cryptocurrency) *MemoryRepository {
+	t.Helper()
+
+	r := NewMemoryRepository()
+	for _, c := range cryptos {
+		if err := r.AddCrypto(context.Background(), c); err != nil {
+			t.Fatalf("add crypto %s: %v", c.CryptoID, err)
+		}
+	}
+	return r
+}
+
+func TestMemoryRepository(t *testing.T) {
+	satoshi := crypto.Author{Firstname: "Satoshi", Lastname: "Nakamoto"}
+
+	tests := []struct {
+		name    string
+		call    func(r *MemoryRepository) error
+		wantErr error
+		want    []crypto.Cryptocurrency
+	}{
+		{
+			name: "add keeps the exact price",
+			call: func(r *MemoryRepository) error {
+				return r.AddCrypto(context.Background(), testCrypto("ETH", "0.00000001"))
+			},
+			want: []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi), testCrypto("ETH", "0.00000001")},
+		},
+		{
+			name:    "add existing",
+			call:    func(r *MemoryRepository) error { return r.AddCrypto(context.Background(), testCrypto("BTC", "2")) },
+			wantErr: ErrCryptoAlreadyExists,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi)},
+		},
+		{
+			name: "update keeps the order",
+			call: func(r *MemoryRepository) error {
+				if err := r.AddCrypto(context.Background(), testCrypto("ETH", "2")); err != nil {
+					return err
+				}
+				return r.UpdateCrypto(context.Background(), "BTC", testCrypto("XBT", "3", satoshi))
+			},
+			want: []crypto.Cryptocurrency{testCrypto("XBT", "3", satoshi), testCrypto("ETH", "2")},
+		},
+		{
+			name: "update missing",
+			call: func(r *MemoryRepository) error {
+				return r.UpdateCrypto(context.Background(), "ETH", testCrypto("ETH", "2"))
+			},
+			wantErr: ErrCryptoNotFound,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi)},
+		},
+		{
+			name: "update to existing",
+			call: func(r *MemoryRepository) error {
+				if err := r.AddCrypto(context.Background(), testCrypto("ETH", "2")); err != nil {
+					return err
+				}
+				return r.UpdateCrypto(context.Background(), "ETH", testCrypto("BTC", "3"))
+			},
+			wantErr: ErrCryptoAlreadyExists,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi), testCrypto("ETH", "2")},
+		},
+		{
+			name: "remove",
+			call: func(r *MemoryRepository) error { return r.RemoveCrypto(context.Background(), "BTC") },
+		},
+		{
+			name:    "remove missing",
+			call:    func(r *MemoryRepository) error { return r.RemoveCrypto(context.Background(), "ETH") },
+			wantErr: ErrCryptoNotFound,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestMemoryRepository(t, testCrypto("BTC", "1", satoshi))
+
+			if err := tt.call(r); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			got, err := r.GetAllCryptos(context.Background())
+			if err != nil {
+				t.Fatalf("get cryptos: %v", err)
+			}
+			if !equalCryptos(got, tt.want) {
+				t.Errorf("expected cryptos %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryRepositoryRejectsInvalid(t *testing.T) {
+	satoshi := crypto.Author{Firstname: "Satoshi", Lastname: "Nakamoto"}
+
+	tests := []struct {
+		name   string
+		crypto crypto.Cryptocurrency
+	}{
+		{name: "name missing", crypto: crypto.Cryptocurrency{CryptoID: "BTC", Price: decimal.RequireFromString("1")}},
+		{name: "name too long", crypto: crypto.Cryptocurrency{Name: "A name longer than 20", CryptoID: "BTC", Price: decimal.RequireFromString("1")}},
+		{name: "CryptoID missing", crypto: crypto.Cryptocurrency{Name: "Bitcoin", Price: decimal.RequireFromString("1")}},
+		{name: "CryptoID too long", crypto: testCrypto("BTCBTCBTCBTC", "1")},
+		{name: "zero price", crypto: testCrypto("BTC", "0")},
+		{name: "negative price", crypto: testCrypto("BTC", "-1")},
+		{name: "author too long", crypto: testCrypto("BTC", "1", crypto.Author{Firstname: "A name longer than 20"})},
+		{name: "duplicate author", crypto: testCrypto("BTC", "1", satoshi, satoshi)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewMemoryRepository()
+			if err := r.AddCrypto(context.Background(), tt.crypto); err == nil {
+				t.Fatal("expected error")
+			}
+			if cryptos, _ := r.GetAllCryptos(context.Background()); len(cryptos) != 0 {
+				t.Errorf("expected no cryptos, got %v", cryptos)
+			}
+		})
+	}
+}
+
+func TestMemoryRepositoryReturnsCopies(t *testing.T) {
+	r := newTestMemoryRepository(t, testCrypto("BTC", "1", crypto.Author{Firstname: "Satoshi", Lastname: "Nakamoto"}))
+
+	c, err := r.GetSingleCrypto(context.Background(), "BTC")
+	if err != nil {
+		t.Fatalf("get crypto: %v", err)
+	}
+	c.Authors[0].Firstname = "Hal"
+
+	if c, _ := r.GetSingleCrypto(context.Background(), "BTC"); c.Authors[0].Firstname != "Satoshi" {
+		t.Errorf("expected the stored author not to change, got %v", c.Authors[0])
+	}
+	if _, err := r.GetSingleCrypto(context.Background(), "ETH"); !errors.Is(err, ErrCryptoNotFound) {
+		t.Errorf("expected ErrCryptoNotFound, got %v", err)
+	}
+}
+
+func TestMemoryRepositoryWithTx(t *testing.T) {
+	satoshi := crypto.Author{Firstname: "Satoshi", Lastname: "Nakamoto"}
+	errRollback := errors.New("rollback")
+
+	tests := []struct {
+		name    string
+		fn      func(tx Repository) error
+		wantErr error
+		want    []crypto.Cryptocurrency
+	}{
+		{
+			name: "commit",
+			fn: func(tx Repository) error {
+				if err := tx.AddCrypto(context.Background(), testCrypto("ETH", "2")); err != nil {
+					return err
+				}
+				return tx.UpdateCrypto(context.Background(), "BTC", testCrypto("XBT", "3", satoshi))
+			},
+			want: []crypto.Cryptocurrency{testCrypto("XBT", "3", satoshi), testCrypto("ETH", "2")},
+		},
+		{
+			name: "rollback on error",
+			fn: func(tx Repository) error {
+				if err := tx.AddCrypto(context.Background(), testCrypto("ETH", "2")); err != nil {
+					return err
+				}
+				if err := tx.RemoveCrypto(context.Background(), "BTC"); err != nil {
+					return err
+				}
+				return errRollback
+			},
+			wantErr: errRollback,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi)},
+		},
+		{
+			name: "rollback on failed call",
+			fn: func(tx Repository) error {
+				if err := tx.UpdateCrypto(context.Background(), "BTC", testCrypto("BTC", "5")); err != nil {
+					return err
+				}
+				return tx.AddCrypto(context.Background(), testCrypto("BTC", "2"))
+			},
+			wantErr: ErrCryptoAlreadyExists,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi)},
+		},
+		{
+			name: "authors changed in the transaction",
+			fn: func(tx Repository) error {
+				c, err := tx.GetSingleCrypto(context.Background(), "BTC")
+				if err != nil {
+					return err
+				}
+				c.Authors[0].Firstname = "Hal"
+				return errRollback
+			},
+			wantErr: errRollback,
+			want:    []crypto.Cryptocurrency{testCrypto("BTC", "1", satoshi)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestMemoryRepository(t, testCrypto("BTC", "1", satoshi))
+
+			err := r.WithTx(context.Background(), tt.fn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+
+			got, err := r.GetAllCryptos(context.Background())
+			if err != nil {
+				t.Fatalf("get cryptos: %v", err)
+			}
+			if !equalCryptos(got, tt.want) {
+				t.Errorf("expected cryptos %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMemoryRepositoryWithTxIsolation(t *testing.T) {
+	r := newTestMemoryRepository(t, testCrypto("BTC", "1"))
+
+	read := make(chan []crypto.Cryptocurrency)
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		if err := tx.AddCrypto(context.Background(), testCrypto("ETH", "2")); err != nil {
+			return err
+		}
+		// The calls outside the transaction wait for it, so they never see its changes before it commits
+		go func() {
+			cryptos, _ := r.GetAllCryptos(context.Background())
+			read <- cryptos
+		}()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("transaction: %v", err)
+	}
+
+	want := []crypto.Cryptocurrency{testCrypto("BTC", "1"), testCrypto("ETH", "2")}
+	if got := <-read; !equalCryptos(got, want) {
+		t.Errorf("expected the concurrent read to see %v, got %v", want, got)
+	}
+}
+
+func TestMemoryRepositorySnapshotAndLoad(t *testing.T) {
+	cryptos := []crypto.Cryptocurrency{
+		testCrypto("BTC", "45000.94", crypto.Author{Firstname: "Satoshi", Lastname: "Nakamoto"}),
+		testCrypto("ETH", "3000"),
+	}
+
+	for _, file := range []string{"cryptos.json", "cryptos.yaml", "cryptos.yml"} {
+		t.Run(file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), file)
+			if err := newTestMemoryRepository(t, cryptos...).Snapshot(context.Background(), path); err != nil {
+				t.Fatalf("snapshot: %v", err)
+			}
+
+			loaded := NewMemoryRepository()
+			if err := loaded.Load(context.Background(), path); err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			got, _ := loaded.GetAllCryptos(context.Background())
+			if !equalCryptos(got, cryptos) {
+				t.Errorf("expected the loaded cryptos %v, got %v", cryptos, got)
+			}
+		})
+	}
+}
+
+func TestMemoryRepositorySnapshotIsAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cryptos.json")
+	r := newTestMemoryRepository(t, testCrypto("BTC", "1"))
+	if err := r.Snapshot(context.Background(), path); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	previous, _ := os.ReadFile(path)
+
+	// A directory in place of the snapshot fails the rename after the temporary file is written
+	failing := filepath.Join(dir, "failing.json")
+	if err := os.MkdirAll(filepath.Join(failing, "taken"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Snapshot(context.Background(), failing); err == nil {
+		t.Fatal("expected the snapshot to fail")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected only the snapshot and the directory, the temporary file is left behind: %v", entries)
+	}
+	if current, _ := os.ReadFile(path); string(current) != string(previous) {
+		t.Errorf("expected the previous snapshot to be intact")
+	}
+}
+
+func TestMemoryRepositoryLoadFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "missing file", file: "missing.json"},
+		{name: "malformed", file: "cryptos.json", content: `[{"crypto_id":`},
+		{name: "invalid crypto", file: "cryptos.yaml", content: "- name: Bitcoin\n  crypto_id: BTC\n  price: -1\n"},
+		{name: "duplicate crypto", file: "cryptos.json", content: `[{"name":"Bitcoin","crypto_id":"BTC","price":1},{"name":"Bitcoin","crypto_id":"BTC","price":2}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if len(tt.content) != 0 {
+				if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := NewMemoryRepository().Load(context.Background(), path); err == nil {
+				t.Error("expected the load to fail")
+			}
+		})
+	}
+}
+
+func TestNewMemoryStorage(t *testing.T) {
+	dir := t.TempDir()
+	c := DefaultConfig()
+	c.Type = TypeMemory
+	c.Memory.Fixture = filepath.Join("..", "..", "fixtures", "cryptos.yaml")
+	c.Memory.Snapshot = filepath.Join(dir, "snapshot.json")
+
+	s, err := New(context.Background(), c)
+	if err != nil {
+		t.Fatalf("new storage: %v", err)
+	}
+	if s.Memory == nil {
+		t.Fatal("expected the memory repository")
+	}
+	cryptos, _ := NewRepository(*s).GetAllCryptos(context.Background())
+	if len(cryptos) == 0 {
+		t.Error("expected the cryptos of the fixture")
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if _, err := os.Stat(c.Memory.Snapshot); err != nil {
+		t.Errorf("expected the snapshot on close: %v", err)
+	}
+}
+
+// equalCryptos reports whether the cryptos are the same
+func equalCryptos(got, want []crypto.Cryptocurrency) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		g, w := got[i], want[i]
+		if len(g.Authors) == 0 && len(w.Authors) == 0 {
+			g.Authors, w.Authors = nil, nil
+		}
+		// Equal prices may differ in their exponent, e.g. 2.01 and 2.010
+		if !g.Price.Equal(w.Price) {
+			return false
+		}
+		g.Price, w.Price = decimal.Zero, decimal.Zero
+		if !reflect.DeepEqual(g, w) {
+			return false
+		}
+	}
+	return true
+}
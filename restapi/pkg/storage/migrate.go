@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrations are the schema migrations of the SQL storages, in a directory per type
+//
+//go:embed migrations/postgres/*.sql migrations/sqlite/*.sql
+var migrations embed.FS
+
+// Migrator applies the embedded migrations to the database of a storage
+type Migrator struct {
+	m *migrate.Migrate
+	// close releases the connection of the Migrator
+	close func() error
+	// db is closed with the Migrator, nil if the database is not owned by it
+	db *sql.DB
+}
+
+// NewMigrator opens the database of the configured SQL storage and returns its Migrator.
+// The database is closed with the Migrator.
+func NewMigrator(ctx context.Context, c *Config) (*Migrator, error) {
+	db, err := Open(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := newMigrator(ctx, c.Type, db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	migrator.db = db
+	return migrator, nil
+}
+
+// newMigrator returns Migrator of db of the storage type. For postgres it uses a single connection of db,
+// which is released by Close, so that the advisory lock taken by the migrations is held on it.
+func newMigrator(ctx context.Context, storageType string, db *sql.DB) (*Migrator, error) {
+	source, err := iofs.New(migrations, "migrations/"+storageType)
+	if err != nil {
+		return nil, err
+	}
+
+	var driver database.Driver
+	close := func() error { return nil }
+	switch storageType {
+	case TypeSQLite:
+		// Closing the sqlite driver closes db, so it is never closed
+		if driver, err = sqlite.WithInstance(db, &sqlite.Config{}); err != nil {
+			return nil, err
+		}
+	default:
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if driver, err = postgres.WithConnection(ctx, conn, &postgres.Config{}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		close = driver.Close
+	}
+
+	m, err := migrate.NewWithInstance("iofs", source, storageType, driver)
+	if err != nil {
+		close()
+		return nil, err
+	}
+	m.Log = migrateLogger{ctx: ctx}
+
+	return &Migrator{m: m, close: close}, nil
+}
+
+// Up applies all the pending migrations
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down rolls back the latest n migrations
+func (m *Migrator) Down(n int) error {
+	return ignoreNoChange(m.m.Steps(-n))
+}
+
+// Goto migrates up or down to the version
+func (m *Migrator) Goto(version uint) error {
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+// Force sets the version without migrating, to recover from a failed migration which left the schema dirty.
+// -1 means that no migration is applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Version returns the applied version, 0 if none, and whether its migration failed and left the schema dirty
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Close releases the connection of the Migrator and closes its database if it owns it
+func (m *Migrator) Close() error {
+	err := m.close()
+	if m.db != nil {
+		if dbErr := m.db.Close(); err == nil {
+			err = dbErr
+		}
+	}
+	return err
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
+
+// migrate applies the pending migrations to the database of the storage. The migrations of postgres
+// are applied under an advisory lock, so instances starting together wait for each other.
+func (s *Storage) migrate(ctx context.Context, storageType string, c *Config) error {
+	migrator, err := newMigrator(ctx, storageType, s.DB)
+	if err != nil {
+		return fmt.Errorf("unable to migrate the %s database: %v", storageType, err)
+	}
+	defer migrator.Close()
+	migrator.m.LockTimeout = c.StartupTimeout
+
+	if err := migrator.Up(); err != nil {
+		return fmt.Errorf("unable to migrate the %s database: %v", storageType, err)
+	}
+
+	version, _, err := migrator.Version()
+	if err != nil {
+		return fmt.Errorf("unable to read the version of the %s database: %v", storageType, err)
+	}
+	log.C(ctx).Infof("Database schema is at version %d", version)
+	return nil
+}
+
+// migrateLogger logs the progress of the migrations
+type migrateLogger struct {
+	ctx context.Context
+}
+
+func (l migrateLogger) Printf(format string, v ...interface{}) {
+	log.C(l.ctx).Info(strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func (l migrateLogger) Verbose() bool {
+	return false
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func newTestMigrator(t *testing.T, path string) *Migrator {
+	t.Helper()
+
+	c := DefaultConfig()
+	c.Type = TypeSQLite
+	c.SQLite.Path = path
+
+	m, err := NewMigrator(context.Background(), c)
+	if err != nil {
+		t.Fatalf("create migrator: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestMigrator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	m := newTestMigrator(t, path)
+
+	steps := []struct {
+		name        string
+		migrate     func() error
+		wantVersion uint
+		wantDirty   bool
+	}{
+		{name: "up", migrate: m.Up, wantVersion: 3},
+		{name: "up again", migrate: m.Up, wantVersion: 3},
+		{name: "down", migrate: func() error { return m.Down(1) }, wantVersion: 2},
+		{name: "down to none", migrate: func() error { return m.Down(2) }},
+		{name: "goto", migrate: func() error { return m.Goto(1) }, wantVersion: 1},
+		{name: "goto current", migrate: func() error { return m.Goto(1) }, wantVersion: 1},
+		{name: "force", migrate: func() error { return m.Force(-1) }},
+	}
+
+	for _, step := range steps {
+		if err := step.migrate(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		version, dirty, err := m.Version()
+		if err != nil {
+			t.Fatalf("%s: version: %v", step.name, err)
+		}
+		if version != step.wantVersion || dirty != step.wantDirty {
+			t.Errorf("%s: expected version %d, got %d (dirty %t)", step.name, step.wantVersion, version, dirty)
+		}
+	}
+}
+
+func TestMigratorDownWithoutMigrations(t *testing.T) {
+	if err := newTestMigrator(t, filepath.Join(t.TempDir(), "test.db")).Down(1); err == nil {
+		t.Error("expected error rolling back without migrations")
+	}
+}
+
+func TestNewMigratorWithoutMigrations(t *testing.T) {
+	c := DefaultConfig()
+	c.Type = TypeMemory
+	if _, err := NewMigrator(context.Background(), c); err == nil {
+		t.Error("expected error for the memory storage")
+	}
+}
+
+func TestStorageMigratesSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	newTestSQLiteStorage(t, path)
+
+	version, dirty, err := newTestMigrator(t, path).Version()
+	if err != nil {
+		t.Fatalf("version: %v", err)
+	}
+	if version != 3 || dirty {
+		t.Errorf("expected the storage to be migrated to version 3, got %d (dirty %t)", version, dirty)
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, storageType := range []string{TypePostgres, TypeSQLite} {
+		files, err := migrations.ReadDir("migrations/" + storageType)
+		if err != nil {
+			t.Fatalf("read %s migrations: %v", storageType, err)
+		}
+		if len(files) == 0 || len(files)%2 != 0 {
+			t.Errorf("expected pairs of up and down %s migrations, got %d files", storageType, len(files))
+		}
+	}
+
+	if err := fstest.TestFS(migrations, "migrations/postgres/000001_initialize_schema.up.sql", "migrations/sqlite/000001_initialize_schema.up.sql"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTenantsMigrationGrants(t *testing.T) {
+	up, err := migrations.ReadFile("migrations/postgres/000004_tenants.up.sql")
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+	_, grants, found := strings.Cut(strings.ToUpper(string(up)), "GRANT")
+
+	if !found || strings.Contains(grants, "LACHEZAR") || !strings.Contains(grants, "TO CURRENT_USER") {
+		t.Errorf("expected the privileges to be granted to the current user, got %s", grants)
+	}
+	for table := range tablePrivileges {
+		if !strings.Contains(grants, "CRYPTOS."+table) {
+			t.Errorf("expected privileges on table %s to be granted", table)
+		}
+	}
+}
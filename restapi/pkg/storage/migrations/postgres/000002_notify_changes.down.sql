@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS Authors_Notify_Change_Trigger ON Cryptos.Authors;
+DROP TRIGGER IF EXISTS Cryptocurrencies_Notify_Change_Trigger ON Cryptos.Cryptocurrencies;
+DROP FUNCTION IF EXISTS Cryptos.Notify_Change_Fnc();
@@ -0,0 +1,34 @@
+-- Notifies the changes of the cryptos and their authors on the cryptos_changes channel,
+-- so that every instance of the API learns about the writes of the others.
+-- The payload is a JSON object with the table, the operation and the CryptoIDs of the changed row.
+
+CREATE OR REPLACE FUNCTION Cryptos.Notify_Change_Fnc()
+    RETURNS TRIGGER AS
+    $$
+    DECLARE
+        Payload json;
+    BEGIN
+        IF (TG_OP = 'INSERT') THEN
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'crypto_id', NEW.CryptoID);
+        ELSIF (TG_OP = 'DELETE') THEN
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'crypto_id', OLD.CryptoID);
+        ELSE
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'crypto_id', NEW.CryptoID,
+                                        'old_crypto_id', OLD.CryptoID);
+        END IF;
+
+        PERFORM pg_notify('cryptos_changes', Payload::text);
+        RETURN NULL;
+    END;
+    $$
+    LANGUAGE plpgsql;
+
+CREATE TRIGGER Cryptocurrencies_Notify_Change_Trigger
+    AFTER INSERT OR DELETE OR UPDATE ON Cryptos.Cryptocurrencies
+    FOR EACH ROW
+    EXECUTE PROCEDURE Cryptos.Notify_Change_Fnc();
+
+CREATE TRIGGER Authors_Notify_Change_Trigger
+    AFTER INSERT OR DELETE OR UPDATE ON Cryptos.Authors
+    FOR EACH ROW
+    EXECUTE PROCEDURE Cryptos.Notify_Change_Fnc();
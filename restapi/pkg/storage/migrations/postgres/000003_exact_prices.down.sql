@@ -0,0 +1,3 @@
+-- The prices are rounded back to cents and the prices over 99999999.99 fail the rollback
+ALTER TABLE Cryptos.Cryptocurrencies_Audit ALTER COLUMN Price TYPE numeric(10, 2);
+ALTER TABLE Cryptos.Cryptocurrencies ALTER COLUMN Price TYPE numeric(10, 2);
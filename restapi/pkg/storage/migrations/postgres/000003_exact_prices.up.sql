@@ -0,0 +1,6 @@
+-- Widens the prices to 18 decimal places, so that the prices of the assets with a finer precision
+-- than cents, e.g. 0.00000001 BTC, are stored exactly. The precision of every asset is configured
+-- in the API, which rounds the prices before writing them.
+
+ALTER TABLE Cryptos.Cryptocurrencies ALTER COLUMN Price TYPE numeric(38, 18);
+ALTER TABLE Cryptos.Cryptocurrencies_Audit ALTER COLUMN Price TYPE numeric(38, 18);
@@ -0,0 +1,82 @@
+-- The cryptos of the tenants other than the default one are deleted, since their CryptoIDs may collide with its ones
+
+DROP POLICY IF EXISTS Cryptocurrencies_Audit_Tenant_Isolation ON Cryptos.Cryptocurrencies_Audit;
+DROP POLICY IF EXISTS Cryptocurrencies_Audit_Reads ON Cryptos.Cryptocurrencies_Audit;
+DROP POLICY IF EXISTS Authors_Tenant_Isolation ON Cryptos.Authors;
+DROP POLICY IF EXISTS Authors_Reads ON Cryptos.Authors;
+DROP POLICY IF EXISTS Cryptocurrencies_Tenant_Isolation ON Cryptos.Cryptocurrencies;
+DROP POLICY IF EXISTS Cryptocurrencies_Reads ON Cryptos.Cryptocurrencies;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit DISABLE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Authors NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Authors DISABLE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Cryptocurrencies NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Cryptocurrencies DISABLE ROW LEVEL SECURITY;
+
+DELETE FROM Cryptos.Tenants WHERE TenantID <> 'default';
+
+ALTER TABLE Cryptos.Authors DROP CONSTRAINT FK_Authors_CryptoID;
+ALTER TABLE Cryptos.Authors DROP CONSTRAINT PK_Authors;
+ALTER TABLE Cryptos.Cryptocurrencies DROP CONSTRAINT FK_Cryptocurrencies_TenantID;
+ALTER TABLE Cryptos.Cryptocurrencies DROP CONSTRAINT PK_Cryptocurrencies;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit DROP CONSTRAINT PK_Cryptocurrencies_Audit;
+
+ALTER TABLE Cryptos.Cryptocurrencies_Audit DROP COLUMN TenantID;
+ALTER TABLE Cryptos.Authors DROP COLUMN TenantID;
+ALTER TABLE Cryptos.Cryptocurrencies DROP COLUMN TenantID;
+
+ALTER TABLE Cryptos.Cryptocurrencies
+    ADD CONSTRAINT PK_Cryptocurrencies_CryptoID PRIMARY KEY (CryptoID);
+ALTER TABLE Cryptos.Authors
+    ADD CONSTRAINT PK_Authors PRIMARY KEY (CryptoID, Firstname, Lastname),
+    ADD CONSTRAINT FK_Authors_CryptoID
+        FOREIGN KEY (CryptoID) REFERENCES Cryptos.Cryptocurrencies(CryptoID)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit
+    ADD CONSTRAINT PK_Cryptocurrencies_Audit_CryptoID PRIMARY KEY (CryptoID);
+
+CREATE OR REPLACE FUNCTION Cryptocurrencies_Insert_Delete_Update_Fnc()
+    RETURNS TRIGGER AS
+    $$
+    BEGIN
+        IF (TG_OP = 'INSERT') THEN
+            INSERT INTO Cryptos.Cryptocurrencies_Audit(Name, CryptoID, Price, Doer, CryptoAdditionTime)
+            VALUES (NEW.Name, NEW.CryptoID, New.Price, current_user, current_date);
+
+            RETURN NEW;
+        ELSIF (TG_OP = 'DELETE') THEN
+            DELETE FROM Cryptos.Cryptocurrencies_Audit WHERE CryptoID = OLD.CryptoID;
+            RETURN OLD;
+        ELSIF (TG_OP = 'UPDATE') THEN
+            UPDATE Cryptos.Cryptocurrencies_Audit SET Name = NEW.Name, CryptoID = NEW.CryptoID, Price = NEW.Price,
+                                                      Doer = current_user, CryptoAdditionTime = current_date
+            WHERE CryptoID = OLD.CryptoID;
+            RETURN NEW;
+        END IF;
+    END;
+    $$
+    LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION Cryptos.Notify_Change_Fnc()
+    RETURNS TRIGGER AS
+    $$
+    DECLARE
+        Payload json;
+    BEGIN
+        IF (TG_OP = 'INSERT') THEN
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'crypto_id', NEW.CryptoID);
+        ELSIF (TG_OP = 'DELETE') THEN
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'crypto_id', OLD.CryptoID);
+        ELSE
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'crypto_id', NEW.CryptoID,
+                                        'old_crypto_id', OLD.CryptoID);
+        END IF;
+
+        PERFORM pg_notify('cryptos_changes', Payload::text);
+        RETURN NULL;
+    END;
+    $$
+    LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS Cryptos.Tenants;
@@ -0,0 +1,122 @@
+-- Adds the tenants, which share the deployment but have isolated cryptos. Every crypto, author and audit row
+-- belongs to a tenant and the existing ones are moved to the default tenant. The reads are isolated by the TenantID
+-- of their queries, so they don't need a transaction, and row level security limits the writes to the rows of the
+-- tenant in the cryptos.tenant_id setting of their transaction. It is forced, so that it applies to the owner of
+-- the tables too.
+
+CREATE TABLE Cryptos.Tenants (
+    TenantID varchar(36) NOT NULL
+        CONSTRAINT PK_Tenants_TenantID PRIMARY KEY,
+    Name varchar(50) NOT NULL,
+    Settings jsonb NOT NULL
+        CONSTRAINT DK_Tenants_Settings_Empty
+        DEFAULT '{}'
+);
+
+INSERT INTO Cryptos.Tenants (TenantID, Name) VALUES ('default', 'Default');
+
+ALTER TABLE Cryptos.Authors DROP CONSTRAINT FK_Authors_CryptoID;
+ALTER TABLE Cryptos.Authors DROP CONSTRAINT PK_Authors;
+ALTER TABLE Cryptos.Cryptocurrencies DROP CONSTRAINT PK_Cryptocurrencies_CryptoID;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit DROP CONSTRAINT PK_Cryptocurrencies_Audit_CryptoID;
+
+ALTER TABLE Cryptos.Cryptocurrencies ADD COLUMN TenantID varchar(36) NOT NULL DEFAULT 'default';
+ALTER TABLE Cryptos.Authors ADD COLUMN TenantID varchar(36) NOT NULL DEFAULT 'default';
+ALTER TABLE Cryptos.Cryptocurrencies_Audit ADD COLUMN TenantID varchar(36) NOT NULL DEFAULT 'default';
+
+-- The default only moves the existing rows, the new ones are written with their tenant
+ALTER TABLE Cryptos.Cryptocurrencies ALTER COLUMN TenantID DROP DEFAULT;
+ALTER TABLE Cryptos.Authors ALTER COLUMN TenantID DROP DEFAULT;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit ALTER COLUMN TenantID DROP DEFAULT;
+
+ALTER TABLE Cryptos.Cryptocurrencies
+    ADD CONSTRAINT PK_Cryptocurrencies PRIMARY KEY (TenantID, CryptoID),
+    ADD CONSTRAINT FK_Cryptocurrencies_TenantID
+        FOREIGN KEY (TenantID) REFERENCES Cryptos.Tenants(TenantID)
+        ON DELETE CASCADE;
+
+ALTER TABLE Cryptos.Authors
+    ADD CONSTRAINT PK_Authors PRIMARY KEY (TenantID, CryptoID, Firstname, Lastname),
+    ADD CONSTRAINT FK_Authors_CryptoID
+        FOREIGN KEY (TenantID, CryptoID) REFERENCES Cryptos.Cryptocurrencies(TenantID, CryptoID)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE;
+
+ALTER TABLE Cryptos.Cryptocurrencies_Audit
+    ADD CONSTRAINT PK_Cryptocurrencies_Audit PRIMARY KEY (TenantID, CryptoID);
+
+CREATE OR REPLACE FUNCTION Cryptocurrencies_Insert_Delete_Update_Fnc()
+    RETURNS TRIGGER AS
+    $$
+    BEGIN
+        IF (TG_OP = 'INSERT') THEN
+            INSERT INTO Cryptos.Cryptocurrencies_Audit(TenantID, Name, CryptoID, Price, Doer, CryptoAdditionTime)
+            VALUES (NEW.TenantID, NEW.Name, NEW.CryptoID, New.Price, current_user, current_date);
+
+            RETURN NEW;
+        ELSIF (TG_OP = 'DELETE') THEN
+            DELETE FROM Cryptos.Cryptocurrencies_Audit WHERE TenantID = OLD.TenantID AND CryptoID = OLD.CryptoID;
+            RETURN OLD;
+        ELSIF (TG_OP = 'UPDATE') THEN
+            UPDATE Cryptos.Cryptocurrencies_Audit SET Name = NEW.Name, CryptoID = NEW.CryptoID, Price = NEW.Price,
+                                                      Doer = current_user, CryptoAdditionTime = current_date
+            WHERE TenantID = OLD.TenantID AND CryptoID = OLD.CryptoID;
+            RETURN NEW;
+        END IF;
+    END;
+    $$
+    LANGUAGE plpgsql;
+
+-- The changes carry their tenant, so that the instances invalidate and stream the right cryptos
+CREATE OR REPLACE FUNCTION Cryptos.Notify_Change_Fnc()
+    RETURNS TRIGGER AS
+    $$
+    DECLARE
+        Payload json;
+    BEGIN
+        IF (TG_OP = 'INSERT') THEN
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'tenant_id', NEW.TenantID,
+                                        'crypto_id', NEW.CryptoID);
+        ELSIF (TG_OP = 'DELETE') THEN
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'tenant_id', OLD.TenantID,
+                                        'crypto_id', OLD.CryptoID);
+        ELSE
+            Payload = json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'tenant_id', NEW.TenantID,
+                                        'crypto_id', NEW.CryptoID, 'old_crypto_id', OLD.CryptoID);
+        END IF;
+
+        PERFORM pg_notify('cryptos_changes', Payload::text);
+        RETURN NULL;
+    END;
+    $$
+    LANGUAGE plpgsql;
+
+-- Every row is readable, while without the setting current_setting returns NULL, so no rows are writable.
+-- The updates and deletes see only the rows which pass the isolation policy too, not just the ones of the reads.
+ALTER TABLE Cryptos.Cryptocurrencies ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Cryptocurrencies FORCE ROW LEVEL SECURITY;
+CREATE POLICY Cryptocurrencies_Reads ON Cryptos.Cryptocurrencies FOR SELECT
+    USING (TRUE);
+CREATE POLICY Cryptocurrencies_Tenant_Isolation ON Cryptos.Cryptocurrencies
+    USING (TenantID = current_setting('cryptos.tenant_id', TRUE));
+
+ALTER TABLE Cryptos.Authors ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Authors FORCE ROW LEVEL SECURITY;
+CREATE POLICY Authors_Reads ON Cryptos.Authors FOR SELECT
+    USING (TRUE);
+CREATE POLICY Authors_Tenant_Isolation ON Cryptos.Authors
+    USING (TenantID = current_setting('cryptos.tenant_id', TRUE));
+
+ALTER TABLE Cryptos.Cryptocurrencies_Audit ENABLE ROW LEVEL SECURITY;
+ALTER TABLE Cryptos.Cryptocurrencies_Audit FORCE ROW LEVEL SECURITY;
+CREATE POLICY Cryptocurrencies_Audit_Reads ON Cryptos.Cryptocurrencies_Audit FOR SELECT
+    USING (TRUE);
+CREATE POLICY Cryptocurrencies_Audit_Tenant_Isolation ON Cryptos.Cryptocurrencies_Audit
+    USING (TenantID = current_setting('cryptos.tenant_id', TRUE));
+
+-- The privileges are granted to the user which migrates the schema, as the server connects with it,
+-- so that they don't depend on the role the schema was initialized for. The triggers write the audit
+-- rows with the privileges of the user and look them up to update and delete them.
+GRANT USAGE ON SCHEMA Cryptos TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE, DELETE ON Cryptos.Tenants, Cryptos.Cryptocurrencies, Cryptos.Authors,
+    Cryptos.Cryptocurrencies_Audit TO CURRENT_USER;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS Cryptocurrencies_Insert_Trigger;
+DROP TRIGGER IF EXISTS Cryptocurrencies_Delete_Trigger;
+DROP TRIGGER IF EXISTS Cryptocurrencies_Update_Trigger;
+
+DROP TABLE IF EXISTS Authors;
+DROP TABLE IF EXISTS Cryptocurrencies;
+DROP TABLE IF EXISTS Cryptocurrencies_Audit;
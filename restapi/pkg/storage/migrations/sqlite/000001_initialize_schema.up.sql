@@ -0,0 +1,91 @@
+-- The schema of migrations/postgres/000001_initialize_schema for SQLite.
+-- SQLite has no schemas, so the tables are not qualified with Cryptos, and doesn't enforce
+-- the length of varchar columns, so the lengths are checked explicitly.
+
+CREATE TABLE Cryptocurrencies (
+                                  Name varchar(20) NOT NULL
+                                      CONSTRAINT CK_Cryptocurrencies_Name_Length
+                                          CHECK (length(Name) <= 20),
+                                  CryptoID varchar(10) NOT NULL
+                                      CONSTRAINT PK_Cryptocurrencies_CryptoID PRIMARY KEY
+                                      CONSTRAINT CK_Cryptocurrencies_CryptoID_Length
+                                          CHECK (length(CryptoID) <= 10),
+                                  Price numeric(10, 2) NOT NULL
+                                      CONSTRAINT CK_Cryptocurrencies_Price_must_be_positive
+                                          CHECK (Price > 0)
+);
+
+CREATE TABLE Authors (
+                         CryptoID varchar(10) NULL
+                             CONSTRAINT FK_Authors_CryptoID
+                             REFERENCES Cryptocurrencies(CryptoID)
+                             ON DELETE CASCADE
+                             ON UPDATE CASCADE,
+                         Firstname varchar(20) NULL
+                             CONSTRAINT DK_Authors_Firstname_Unknown
+                             DEFAULT 'Unknown'
+                             CONSTRAINT CK_Authors_Firstname_Length
+                                 CHECK (length(Firstname) <= 20),
+                         Lastname varchar(20) NULL
+                             CONSTRAINT DK_Authors_Lastname_Unknown
+                             DEFAULT 'Unknown'
+                             CONSTRAINT CK_Authors_Lastname_Length
+                                 CHECK (length(Lastname) <= 20),
+                         CONSTRAINT PK_Authors PRIMARY KEY (CryptoID, Firstname, Lastname)
+);
+
+CREATE TABLE IF NOT EXISTS Cryptocurrencies_Audit (
+    Name varchar(20) NOT NULL,
+    CryptoID varchar(10) NOT NULL
+    CONSTRAINT PK_Cryptocurrencies_Audit_CryptoID PRIMARY KEY,
+    Price numeric(10, 2) NOT NULL
+    CONSTRAINT CK_Cryptocurrencies_Audit_Price_must_be_positive
+    CHECK (Price > 0),
+    Doer varchar(20) NOT NULL,
+    CryptoAdditionTime DATE
+);
+
+-- SQLite has neither database users nor trigger functions, so the audit is kept by a trigger
+-- per operation and the Doer is always the service
+CREATE TRIGGER Cryptocurrencies_Insert_Trigger
+    AFTER INSERT ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    INSERT INTO Cryptocurrencies_Audit(Name, CryptoID, Price, Doer, CryptoAdditionTime)
+    VALUES (NEW.Name, NEW.CryptoID, NEW.Price, 'restapi', date('now'));
+END;
+
+CREATE TRIGGER Cryptocurrencies_Delete_Trigger
+    AFTER DELETE ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    DELETE FROM Cryptocurrencies_Audit WHERE CryptoID = OLD.CryptoID;
+END;
+
+CREATE TRIGGER Cryptocurrencies_Update_Trigger
+    AFTER UPDATE ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    UPDATE Cryptocurrencies_Audit SET Name = NEW.Name, CryptoID = NEW.CryptoID, Price = NEW.Price,
+                                      Doer = 'restapi', CryptoAdditionTime = date('now')
+    WHERE CryptoID = OLD.CryptoID;
+END;
+
+INSERT INTO Cryptocurrencies (
+    Name,
+    CryptoID,
+    Price)
+VALUES
+('Bitcoin', 'BTC', '45000.94'),
+('Ethereum', 'ETH', '2500.12'),
+('DefaultCoin', 'DFC', '0.03');
+
+
+INSERT INTO Authors (
+    CryptoID,
+    Firstname,
+    Lastname)
+VALUES
+('BTC', 'Satoshi', 'Nakamoto'),
+('ETH', 'Vitalik', 'Buterin'),
+('ETH', 'Gavin', 'Wood');
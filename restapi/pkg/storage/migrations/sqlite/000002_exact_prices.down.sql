@@ -0,0 +1,84 @@
+-- The prices are stored as numbers again and lose the digits which don't fit in a float
+
+CREATE TABLE Cryptocurrencies_New (
+    Name varchar(20) NOT NULL
+        CONSTRAINT CK_Cryptocurrencies_Name_Length
+            CHECK (length(Name) <= 20),
+    CryptoID varchar(10) NOT NULL
+        CONSTRAINT PK_Cryptocurrencies_CryptoID PRIMARY KEY
+        CONSTRAINT CK_Cryptocurrencies_CryptoID_Length
+            CHECK (length(CryptoID) <= 10),
+    Price numeric(10, 2) NOT NULL
+        CONSTRAINT CK_Cryptocurrencies_Price_must_be_positive
+            CHECK (Price > 0)
+);
+INSERT INTO Cryptocurrencies_New (Name, CryptoID, Price)
+SELECT Name, CryptoID, CAST(Price AS NUMERIC) FROM Cryptocurrencies;
+
+-- The authors are moved to a table referencing the new cryptos, since dropping the old ones
+-- would delete them by the cascade
+CREATE TABLE Authors_New (
+    CryptoID varchar(10) NULL
+        CONSTRAINT FK_Authors_CryptoID
+        REFERENCES Cryptocurrencies_New(CryptoID)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    Firstname varchar(20) NULL
+        CONSTRAINT DK_Authors_Firstname_Unknown
+        DEFAULT 'Unknown'
+        CONSTRAINT CK_Authors_Firstname_Length
+            CHECK (length(Firstname) <= 20),
+    Lastname varchar(20) NULL
+        CONSTRAINT DK_Authors_Lastname_Unknown
+        DEFAULT 'Unknown'
+        CONSTRAINT CK_Authors_Lastname_Length
+            CHECK (length(Lastname) <= 20),
+    CONSTRAINT PK_Authors PRIMARY KEY (CryptoID, Firstname, Lastname)
+);
+INSERT INTO Authors_New (CryptoID, Firstname, Lastname)
+SELECT CryptoID, Firstname, Lastname FROM Authors;
+
+CREATE TABLE Cryptocurrencies_Audit_New (
+    Name varchar(20) NOT NULL,
+    CryptoID varchar(10) NOT NULL
+    CONSTRAINT PK_Cryptocurrencies_Audit_CryptoID PRIMARY KEY,
+    Price numeric(10, 2) NOT NULL
+    CONSTRAINT CK_Cryptocurrencies_Audit_Price_must_be_positive
+    CHECK (Price > 0),
+    Doer varchar(20) NOT NULL,
+    CryptoAdditionTime DATE
+);
+INSERT INTO Cryptocurrencies_Audit_New (Name, CryptoID, Price, Doer, CryptoAdditionTime)
+SELECT Name, CryptoID, CAST(Price AS NUMERIC), Doer, CryptoAdditionTime FROM Cryptocurrencies_Audit;
+
+-- Dropping the cryptos drops their triggers too
+DROP TABLE Authors;
+DROP TABLE Cryptocurrencies;
+DROP TABLE Cryptocurrencies_Audit;
+ALTER TABLE Cryptocurrencies_New RENAME TO Cryptocurrencies;
+ALTER TABLE Authors_New RENAME TO Authors;
+ALTER TABLE Cryptocurrencies_Audit_New RENAME TO Cryptocurrencies_Audit;
+
+CREATE TRIGGER Cryptocurrencies_Insert_Trigger
+    AFTER INSERT ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    INSERT INTO Cryptocurrencies_Audit(Name, CryptoID, Price, Doer, CryptoAdditionTime)
+    VALUES (NEW.Name, NEW.CryptoID, NEW.Price, 'restapi', date('now'));
+END;
+
+CREATE TRIGGER Cryptocurrencies_Delete_Trigger
+    AFTER DELETE ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    DELETE FROM Cryptocurrencies_Audit WHERE CryptoID = OLD.CryptoID;
+END;
+
+CREATE TRIGGER Cryptocurrencies_Update_Trigger
+    AFTER UPDATE ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    UPDATE Cryptocurrencies_Audit SET Name = NEW.Name, CryptoID = NEW.CryptoID, Price = NEW.Price,
+                                      Doer = 'restapi', CryptoAdditionTime = date('now')
+    WHERE CryptoID = OLD.CryptoID;
+END;
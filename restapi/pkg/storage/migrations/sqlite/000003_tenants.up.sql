@@ -0,0 +1,107 @@
+-- The tenants of migrations/postgres/000004_tenants for SQLite.
+-- SQLite has no row level security, so the tenants are isolated only by the TenantID of the queries.
+-- SQLite can't change the keys of a table, so the tables are rebuilt.
+
+CREATE TABLE Tenants (
+    TenantID varchar(36) NOT NULL
+        CONSTRAINT PK_Tenants_TenantID PRIMARY KEY
+        CONSTRAINT CK_Tenants_TenantID_Length
+            CHECK (length(TenantID) <= 36),
+    Name varchar(50) NOT NULL
+        CONSTRAINT CK_Tenants_Name_Length
+            CHECK (length(Name) <= 50),
+    Settings text NOT NULL
+        CONSTRAINT DK_Tenants_Settings_Empty
+        DEFAULT '{}'
+);
+
+INSERT INTO Tenants (TenantID, Name) VALUES ('default', 'Default');
+
+CREATE TABLE Cryptocurrencies_New (
+    TenantID varchar(36) NOT NULL
+        CONSTRAINT FK_Cryptocurrencies_TenantID
+        REFERENCES Tenants(TenantID)
+        ON DELETE CASCADE,
+    Name varchar(20) NOT NULL
+        CONSTRAINT CK_Cryptocurrencies_Name_Length
+            CHECK (length(Name) <= 20),
+    CryptoID varchar(10) NOT NULL
+        CONSTRAINT CK_Cryptocurrencies_CryptoID_Length
+            CHECK (length(CryptoID) <= 10),
+    Price text NOT NULL
+        CONSTRAINT CK_Cryptocurrencies_Price_must_be_positive
+            CHECK (CAST(Price AS NUMERIC) > 0),
+    CONSTRAINT PK_Cryptocurrencies PRIMARY KEY (TenantID, CryptoID)
+);
+INSERT INTO Cryptocurrencies_New (TenantID, Name, CryptoID, Price)
+SELECT 'default', Name, CryptoID, Price FROM Cryptocurrencies;
+
+-- The authors are moved to a table referencing the new cryptos, since dropping the old ones
+-- would delete them by the cascade
+CREATE TABLE Authors_New (
+    TenantID varchar(36) NOT NULL,
+    CryptoID varchar(10) NOT NULL,
+    Firstname varchar(20) NULL
+        CONSTRAINT DK_Authors_Firstname_Unknown
+        DEFAULT 'Unknown'
+        CONSTRAINT CK_Authors_Firstname_Length
+            CHECK (length(Firstname) <= 20),
+    Lastname varchar(20) NULL
+        CONSTRAINT DK_Authors_Lastname_Unknown
+        DEFAULT 'Unknown'
+        CONSTRAINT CK_Authors_Lastname_Length
+            CHECK (length(Lastname) <= 20),
+    CONSTRAINT PK_Authors PRIMARY KEY (TenantID, CryptoID, Firstname, Lastname),
+    CONSTRAINT FK_Authors_CryptoID
+        FOREIGN KEY (TenantID, CryptoID) REFERENCES Cryptocurrencies_New(TenantID, CryptoID)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+INSERT INTO Authors_New (TenantID, CryptoID, Firstname, Lastname)
+SELECT 'default', CryptoID, Firstname, Lastname FROM Authors;
+
+CREATE TABLE Cryptocurrencies_Audit_New (
+    TenantID varchar(36) NOT NULL,
+    Name varchar(20) NOT NULL,
+    CryptoID varchar(10) NOT NULL,
+    Price text NOT NULL
+    CONSTRAINT CK_Cryptocurrencies_Audit_Price_must_be_positive
+    CHECK (CAST(Price AS NUMERIC) > 0),
+    Doer varchar(20) NOT NULL,
+    CryptoAdditionTime DATE,
+    CONSTRAINT PK_Cryptocurrencies_Audit PRIMARY KEY (TenantID, CryptoID)
+);
+INSERT INTO Cryptocurrencies_Audit_New (TenantID, Name, CryptoID, Price, Doer, CryptoAdditionTime)
+SELECT 'default', Name, CryptoID, Price, Doer, CryptoAdditionTime FROM Cryptocurrencies_Audit;
+
+-- Dropping the cryptos drops their triggers too
+DROP TABLE Authors;
+DROP TABLE Cryptocurrencies;
+DROP TABLE Cryptocurrencies_Audit;
+ALTER TABLE Cryptocurrencies_New RENAME TO Cryptocurrencies;
+ALTER TABLE Authors_New RENAME TO Authors;
+ALTER TABLE Cryptocurrencies_Audit_New RENAME TO Cryptocurrencies_Audit;
+
+CREATE TRIGGER Cryptocurrencies_Insert_Trigger
+    AFTER INSERT ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    INSERT INTO Cryptocurrencies_Audit(TenantID, Name, CryptoID, Price, Doer, CryptoAdditionTime)
+    VALUES (NEW.TenantID, NEW.Name, NEW.CryptoID, NEW.Price, 'restapi', date('now'));
+END;
+
+CREATE TRIGGER Cryptocurrencies_Delete_Trigger
+    AFTER DELETE ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    DELETE FROM Cryptocurrencies_Audit WHERE TenantID = OLD.TenantID AND CryptoID = OLD.CryptoID;
+END;
+
+CREATE TRIGGER Cryptocurrencies_Update_Trigger
+    AFTER UPDATE ON Cryptocurrencies
+    FOR EACH ROW
+BEGIN
+    UPDATE Cryptocurrencies_Audit SET Name = NEW.Name, CryptoID = NEW.CryptoID, Price = NEW.Price,
+                                      Doer = 'restapi', CryptoAdditionTime = date('now')
+    WHERE TenantID = OLD.TenantID AND CryptoID = OLD.CryptoID;
+END;
@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+// ErrPoolSaturated is returned by PingWithContext when all the connections are in use and calls wait for one
+var ErrPoolSaturated = errors.New("connection pool saturated")
+
+// startupBackoff bounds the waits between the pings of the database on startup
+const (
+	startupInitialBackoff = 250 * time.Millisecond
+	startupMaxBackoff     = 5 * time.Second
+)
+
+// configurePool applies the connection pool settings to db
+func configurePool(db *sql.DB, c *Config) {
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+}
+
+// waitForDB pings db with exponential backoff until it answers or the timeout elapses
+func waitForDB(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	b := &backoff{next: startupInitialBackoff, max: startupMaxBackoff}
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			log.C(ctx).Info("Connected to the database")
+			return nil
+		}
+
+		log.C(ctx).WithError(err).Warnf("Waiting for the database, attempt %d", attempt)
+		if !b.wait(ctx) {
+			return fmt.Errorf("database is not reachable after %s: %v", timeout, err)
+		}
+	}
+}
+
+// poolMonitor detects the saturation of the connection pool between two checks
+type poolMonitor struct {
+	waitCount int64
+}
+
+// check returns ErrPoolSaturated if all the connections of db are in use
+// and calls had to wait for a connection since the last check
+func (p *poolMonitor) check(db *sql.DB) error {
+	stats := db.Stats()
+	previous := atomic.SwapInt64(&p.waitCount, stats.WaitCount)
+
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections && stats.WaitCount > previous {
+		return fmt.Errorf("%w: %d of %d connections in use and %d calls waited for a connection",
+			ErrPoolSaturated, stats.InUse, stats.MaxOpenConnections, stats.WaitCount-previous)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPoolMonitor(t *testing.T) {
+	db, err := sql.Open(dialects[TypeSQLite].driver, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	p := &poolMonitor{}
+	if err := p.check(db); err != nil {
+		t.Fatalf("expected an idle pool, got %v", err)
+	}
+
+	// Hold the only connection and make another call wait for it
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("get connection: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_ = db.PingContext(ctx)
+
+	if err := p.check(db); !errors.Is(err, ErrPoolSaturated) {
+		t.Errorf("expected ErrPoolSaturated, got %v", err)
+	}
+	if err := p.check(db); err != nil {
+		t.Errorf("expected no new waits since the last check, got %v", err)
+	}
+
+	conn.Close()
+	if err := p.check(db); err != nil {
+		t.Errorf("expected the pool to be released, got %v", err)
+	}
+}
+
+func TestWaitForDB(t *testing.T) {
+	db, err := sql.Open(dialects[TypeSQLite].driver, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	if err := waitForDB(context.Background(), db, time.Second); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	db.Close()
+	if err := waitForDB(context.Background(), db, 10*time.Millisecond); err == nil {
+		t.Error("expected error for a closed database")
+	}
+}
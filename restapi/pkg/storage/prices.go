@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+)
+
+// maxPriceDecimals is the scale of the Price columns
+const maxPriceDecimals = 18
+
+// PriceConfig contains the precision of the prices of the cryptos
+type PriceConfig struct {
+	Decimals int            `mapstructure:"decimals" description:"decimal places of the prices of the cryptos without their own, at most 18"`
+	Assets   map[string]int `mapstructure:"assets" description:"decimal places of the prices by CryptoID, at most 18"`
+}
+
+func DefaultPriceConfig() *PriceConfig {
+	return &PriceConfig{
+		Decimals: 8,
+		Assets:   map[string]int{},
+	}
+}
+
+func (c *PriceConfig) Validate() error {
+	var errs []error
+	if c.Decimals < 0 || c.Decimals > maxPriceDecimals {
+		errs = append(errs, fmt.Errorf("validate Prices settings: Decimals must be between 0 and %d", maxPriceDecimals))
+	}
+	for _, cryptoID := range slices.Sorted(maps.Keys(c.Assets)) {
+		if decimals := c.Assets[cryptoID]; decimals < 0 || decimals > maxPriceDecimals {
+			errs = append(errs, fmt.Errorf("validate Prices settings: Decimals of %s must be between 0 and %d", cryptoID, maxPriceDecimals))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// decimalsOf returns the decimal places of the price of the crypto of the tenant of ctx.
+// The settings of the tenant take precedence over the configured ones.
+func (c *PriceConfig) decimalsOf(ctx context.Context, cryptoID string) int {
+	t, _ := tenant.FromContext(ctx)
+	// The keys of the configuration are lower case, while the CryptoIDs usually aren't
+	for _, assets := range []map[string]int{t.Settings.Assets, c.Assets} {
+		for id, decimals := range assets {
+			if strings.EqualFold(id, cryptoID) {
+				return decimals
+			}
+		}
+	}
+	if t.Settings.Decimals != nil {
+		return *t.Settings.Decimals
+	}
+	return c.Decimals
+}
+
+// priceRepository is a Repository which rounds the prices of the written cryptos to the decimal places of their asset
+type priceRepository struct {
+	Repository
+	config *PriceConfig
+}
+
+// withPrices returns Repository which writes to r the prices rounded as configured
+func withPrices(r Repository, c *PriceConfig) Repository {
+	if c == nil {
+		return r
+	}
+
+	return &priceRepository{
+		Repository: r,
+		config:     c,
+	}
+}
+
+func (r *priceRepository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	return r.Repository.AddCrypto(ctx, r.round(ctx, c))
+}
+
+func (r *priceRepository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	return r.Repository.UpdateCrypto(ctx, oldCryptoID, r.round(ctx, c))
+}
+
+func (r *priceRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(withPrices(tx, r.config))
+	})
+}
+
+func (r *priceRepository) round(ctx context.Context, c crypto.Cryptocurrency) crypto.Cryptocurrency {
+	c.Price = c.Price.Round(int32(r.config.decimalsOf(ctx, c.CryptoID)))
+	return c
+}
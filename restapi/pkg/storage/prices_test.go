@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+)
+
+func TestPriceDecimals(t *testing.T) {
+	two, ten := 2, 10
+	config := &PriceConfig{Decimals: 8, Assets: map[string]int{"btc": 4, "eth": 6}}
+
+	tests := []struct {
+		name     string
+		settings *tenant.Settings
+		cryptoID string
+		want     int
+	}{
+		{name: "default", cryptoID: "SOL", want: 8},
+		{name: "asset", cryptoID: "btc", want: 4},
+		{name: "asset in another case", cryptoID: "BTC", want: 4},
+		{name: "tenant without settings", settings: &tenant.Settings{}, cryptoID: "ETH", want: 6},
+		{name: "tenant default", settings: &tenant.Settings{Decimals: &two}, cryptoID: "SOL", want: 2},
+		{name: "asset over tenant default", settings: &tenant.Settings{Decimals: &two}, cryptoID: "ETH", want: 6},
+		{name: "tenant asset", settings: &tenant.Settings{Assets: map[string]int{"Eth": 0}}, cryptoID: "ETH", want: 0},
+		{name: "tenant asset over asset", settings: &tenant.Settings{Decimals: &ten, Assets: map[string]int{"BTC": 12}}, cryptoID: "btc", want: 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.settings != nil {
+				ctx = tenant.ContextWithTenant(ctx, tenant.Tenant{ID: "acme", Settings: *tt.settings})
+			}
+
+			if got := config.decimalsOf(ctx, tt.cryptoID); got != tt.want {
+				t.Errorf("expected %d decimals, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPriceRepository(t *testing.T) {
+	config := &PriceConfig{Decimals: 8, Assets: map[string]int{"usdt": 2}}
+
+	tests := []struct {
+		name      string
+		cryptoID  string
+		price     string
+		wantPrice string
+		wantErr   bool
+	}{
+		{name: "exact", cryptoID: "BTC", price: "45000.94", wantPrice: "45000.94"},
+		{name: "rounded half up", cryptoID: "BTC", price: "0.123456785", wantPrice: "0.12345679"},
+		{name: "rounded down", cryptoID: "BTC", price: "0.123456784", wantPrice: "0.12345678"},
+		{name: "smallest", cryptoID: "SAT", price: "0.00000001", wantPrice: "0.00000001"},
+		{name: "asset", cryptoID: "USDT", price: "1.005", wantPrice: "1.01"},
+		{name: "rounded to zero", cryptoID: "BTC", price: "0.000000004", wantErr: true},
+		{name: "asset rounded to zero", cryptoID: "USDT", price: "0.004", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		for _, op := range []string{"add", "update", "transaction"} {
+			t.Run(tt.name+"/"+op, func(t *testing.T) {
+				ctx := context.Background()
+				memory := newTestMemoryRepository(t, testCrypto(tt.cryptoID, "1"))
+				r := withPrices(memory, config)
+
+				var err error
+				switch op {
+				case "add":
+					_ = memory.RemoveCrypto(ctx, tt.cryptoID)
+					err = r.AddCrypto(ctx, testCrypto(tt.cryptoID, tt.price))
+				case "update":
+					err = r.UpdateCrypto(ctx, tt.cryptoID, testCrypto(tt.cryptoID, tt.price))
+				case "transaction":
+					err = r.WithTx(ctx, func(tx Repository) error {
+						return tx.UpdateCrypto(ctx, tt.cryptoID, testCrypto(tt.cryptoID, tt.price))
+					})
+				}
+				if tt.wantErr {
+					if err == nil {
+						t.Error("expected the price rounded to zero to fail the positive check")
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("write: %v", err)
+				}
+
+				if c := mustGet(t, memory, tt.cryptoID); c.Price.String() != tt.wantPrice {
+					t.Errorf("expected the stored price %s, got %s", tt.wantPrice, c.Price)
+				}
+			})
+		}
+	}
+}
+
+func TestPriceConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *PriceConfig)
+		wantErr bool
+	}{
+		{name: "default", modify: func(c *PriceConfig) {}},
+		{name: "most decimals", modify: func(c *PriceConfig) { c.Decimals = maxPriceDecimals; c.Assets["btc"] = 0 }},
+		{name: "negative decimals", modify: func(c *PriceConfig) { c.Decimals = -1 }, wantErr: true},
+		{name: "too many decimals", modify: func(c *PriceConfig) { c.Decimals = maxPriceDecimals + 1 }, wantErr: true},
+		{name: "too many asset decimals", modify: func(c *PriceConfig) { c.Assets["btc"] = maxPriceDecimals + 1 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultPriceConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
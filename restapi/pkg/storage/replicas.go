@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+// SessionHeader is the header with the session which is pinned to the primary after its writes. Every response
+// has the session of the request in it, so that the client can send it with its next requests. The sessions are
+// issued by the server, requests without one or with one the server didn't issue get a new session.
+const SessionHeader = "X-Session-ID"
+
+// ReplicaConfig contains the settings of routing the reads to the replicas
+type ReplicaConfig struct {
+	ReadYourWrites      time.Duration `mapstructure:"read_your_writes" description:"how long a session reads from the primary after it wrote, 0 disables it"`
+	HealthCheckInterval time.Duration `mapstructure:"health_check_interval" description:"how often the replicas are pinged"`
+	HealthCheckTimeout  time.Duration `mapstructure:"health_check_timeout" description:"deadline of pinging a replica"`
+	FailureThreshold    int           `mapstructure:"failure_threshold" description:"number of consecutive failed pings after which a replica is ejected"`
+}
+
+func DefaultReplicaConfig() *ReplicaConfig {
+	return &ReplicaConfig{
+		ReadYourWrites:      5 * time.Second,
+		HealthCheckInterval: 5 * time.Second,
+		HealthCheckTimeout:  time.Second,
+		FailureThreshold:    3,
+	}
+}
+
+func (c *ReplicaConfig) Validate() error {
+	var errs []error
+	if c.ReadYourWrites < 0 {
+		errs = append(errs, fmt.Errorf("validate Replica settings: ReadYourWrites must not be negative"))
+	}
+	if c.HealthCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("validate Replica settings: HealthCheckInterval missing"))
+	}
+	if c.HealthCheckTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("validate Replica settings: HealthCheckTimeout missing"))
+	}
+	if c.FailureThreshold < 1 {
+		errs = append(errs, fmt.Errorf("validate Replica settings: FailureThreshold must be at least 1"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// replica is a read replica which is ejected from the routing while it fails its health checks
+type replica struct {
+	name string
+	db   *sql.DB
+
+	healthy  atomic.Bool
+	failures atomic.Int32
+}
+
+// check pings the replica and ejects it after threshold consecutive failures or readmits it once it answers
+func (r *replica) check(ctx context.Context, timeout time.Duration, threshold int) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := r.db.PingContext(ctx)
+	if err == nil {
+		r.failures.Store(0)
+		if !r.healthy.Swap(true) {
+			log.C(ctx).Infof("Replica %s is healthy, routing reads to it", r.name)
+		}
+		return nil
+	}
+
+	if int(r.failures.Add(1)) >= threshold && r.healthy.Swap(false) {
+		log.C(ctx).WithError(err).Warnf("Replica %s failed %d health checks, ejecting it", r.name, threshold)
+	}
+	return err
+}
+
+// replicaSet routes the reads to its healthy replicas in turn and pins the sessions which wrote to the primary
+type replicaSet struct {
+	config   ReplicaConfig
+	replicas []*replica
+	next     atomic.Uint64
+
+	mutex sync.Mutex
+	// pins are the times until which the sessions read from the primary
+	pins map[string]time.Time
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newReplicaSet opens the replicas in sources and starts checking their health until close is called
+func newReplicaSet(ctx context.Context, sources []DataSource, c *Config) (*replicaSet, error) {
+	s := &replicaSet{
+		config: *c.ReplicaRouting,
+		pins:   make(map[string]time.Time),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	for _, ds := range sources {
+		db, err := sql.Open(dialects[TypePostgres].driver, ds.DSN())
+		if err != nil {
+			s.closeReplicas()
+			return nil, fmt.Errorf("an error occurred while opening replica %s: %v", ds.Host, err)
+		}
+		configurePool(db, c)
+		s.replicas = append(s.replicas, &replica{name: fmt.Sprintf("%s:%s", ds.Host, ds.Port), db: db})
+	}
+
+	// A replica which is down on startup is ejected right away and readmitted by the health checks
+	for i, err := range s.checkAll(ctx, 1) {
+		if err != nil {
+			log.C(ctx).WithError(err).Warnf("Replica %s is not reachable", s.replicas[i].name)
+		}
+	}
+
+	go s.checkHealth(context.WithoutCancel(ctx))
+	return s, nil
+}
+
+func (s *replicaSet) checkHealth(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkAll(ctx, s.config.FailureThreshold)
+			s.prunePins()
+		}
+	}
+}
+
+// reader returns the database to read from in ctx, the primary if the session is pinned
+// or there is no healthy replica
+func (s *replicaSet) reader(ctx context.Context) *sql.DB {
+	if s.pinned(ctx) {
+		return nil
+	}
+
+	start := s.next.Add(1)
+	for i := range s.replicas {
+		r := s.replicas[(start+uint64(i))%uint64(len(s.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return nil
+}
+
+// pin makes the session of ctx read from the primary for the configured time
+func (s *replicaSet) pin(ctx context.Context) {
+	session, ok := ctx.Value(sessionKey{}).(string)
+	if !ok || s.config.ReadYourWrites == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.pins[session] = time.Now().Add(s.config.ReadYourWrites)
+}
+
+func (s *replicaSet) pinned(ctx context.Context) bool {
+	session, ok := ctx.Value(sessionKey{}).(string)
+	if !ok {
+		return false
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	until, ok := s.pins[session]
+	return ok && time.Now().Before(until)
+}
+
+// prunePins removes the expired pins
+func (s *replicaSet) prunePins() {
+	now := time.Now()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for session, until := range s.pins {
+		if !now.Before(until) {
+			delete(s.pins, session)
+		}
+	}
+}
+
+// ping checks every replica, so that a failing one is ejected. The failures of the replicas are only logged
+// since the reads fall back to the primary without them.
+func (s *replicaSet) ping(ctx context.Context) {
+	for i, err := range s.checkAll(ctx, s.config.FailureThreshold) {
+		if err != nil {
+			log.C(ctx).WithError(err).Warnf("Replica %s failed its health check", s.replicas[i].name)
+		}
+	}
+}
+
+// checkAll checks the replicas concurrently, so that the unreachable ones don't delay the others,
+// and returns the error of every replica in their order
+func (s *replicaSet) checkAll(ctx context.Context, threshold int) []error {
+	errs := make([]error, len(s.replicas))
+
+	var wg sync.WaitGroup
+	for i, r := range s.replicas {
+		wg.Go(func() {
+			errs[i] = r.check(ctx, s.config.HealthCheckTimeout, threshold)
+		})
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// close stops the health checks and closes the replicas
+func (s *replicaSet) close() error {
+	close(s.stop)
+	<-s.done
+	return s.closeReplicas()
+}
+
+func (s *replicaSet) closeReplicas() error {
+	var result error
+	for _, r := range s.replicas {
+		if err := r.db.Close(); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// sessionKey is the context key of the session of a request
+type sessionKey struct{}
+
+// WithSession returns ctx in which the reads are made from the primary for a while after the writes of the session
+func WithSession(ctx context.Context, session string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, session)
+}
+
+// SessionMiddleware puts the session of SessionHeader in the request context and in the response, so that
+// the reads after a write see it when the reads are routed to the replicas. The sessions are signed with a key
+// of the middleware, so that the clients can't pin the sessions of others or make up their own. The pins
+// are kept by every instance, so the sessions of another instance are replaced as well.
+func SessionMiddleware() func(http.Handler) http.Handler {
+	key := make([]byte, sha256.Size)
+	_, _ = rand.Read(key)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session := r.Header.Get(SessionHeader)
+			if !validSession(key, session) {
+				session = newSession(key)
+			}
+			w.Header().Set(SessionHeader, session)
+			next.ServeHTTP(w, r.WithContext(WithSession(r.Context(), session)))
+		})
+	}
+}
+
+// newSession returns a random session signed with key
+func newSession(key []byte) string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+
+	id := hex.EncodeToString(b)
+	return id + "." + signSession(key, id)
+}
+
+// validSession reports whether session was returned by newSession with key
+func validSession(key []byte, session string) bool {
+	id, signature, ok := strings.Cut(session, ".")
+	return ok && hmac.Equal([]byte(signature), []byte(signSession(key, id)))
+}
+
+func signSession(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return hex.EncodeToString(mac.Sum(nil))
+}
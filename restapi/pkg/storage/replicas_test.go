@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestReplica returns a healthy replica with a SQLite database
+func newTestReplica(t *testing.T, name string) *replica {
+	t.Helper()
+
+	db, err := sql.Open(dialects[TypeSQLite].driver, filepath.Join(t.TempDir(), name+".db"))
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	r := &replica{name: name, db: db}
+	r.healthy.Store(true)
+	return r
+}
+
+// newTestReplicaSet returns replicaSet of the replicas without the health checks
+func newTestReplicaSet(replicas ...*replica) *replicaSet {
+	s := &replicaSet{
+		config:   *DefaultReplicaConfig(),
+		replicas: replicas,
+		pins:     make(map[string]time.Time),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	close(s.done)
+	return s
+}
+
+func TestReplicaSetReader(t *testing.T) {
+	a, b := newTestReplica(t, "a"), newTestReplica(t, "b")
+	s := newTestReplicaSet(a, b)
+	ctx := WithSession(context.Background(), "session")
+
+	first, second := s.reader(ctx), s.reader(ctx)
+	if first == nil || second == nil || first == second {
+		t.Errorf("expected the reads to be spread over the replicas")
+	}
+
+	b.healthy.Store(false)
+	for i := 0; i < 3; i++ {
+		if db := s.reader(ctx); db != a.db {
+			t.Fatal("expected the reads to skip the ejected replica")
+		}
+	}
+
+	s.pin(ctx)
+	if db := s.reader(ctx); db != nil {
+		t.Error("expected the pinned session to read from the primary")
+	}
+	if db := s.reader(WithSession(context.Background(), "another")); db != a.db {
+		t.Error("expected another session to read from the replica")
+	}
+
+	a.healthy.Store(false)
+	if db := s.reader(context.Background()); db != nil {
+		t.Error("expected the reads to fall back to the primary without healthy replicas")
+	}
+}
+
+func TestReplicaSetPins(t *testing.T) {
+	s := newTestReplicaSet(newTestReplica(t, "a"))
+	ctx := WithSession(context.Background(), "session")
+
+	s.pin(context.Background())
+	if len(s.pins) != 0 {
+		t.Error("expected no pin without a session")
+	}
+
+	s.config.ReadYourWrites = 0
+	s.pin(ctx)
+	if s.pinned(ctx) {
+		t.Error("expected no pin with ReadYourWrites disabled")
+	}
+
+	s.config.ReadYourWrites = time.Hour
+	s.pin(ctx)
+	s.pins["expired"] = time.Now().Add(-time.Second)
+	s.prunePins()
+	if !s.pinned(ctx) || len(s.pins) != 1 {
+		t.Errorf("expected only the pin of the session to be kept, got %v", s.pins)
+	}
+}
+
+func TestReplicaCheck(t *testing.T) {
+	r := newTestReplica(t, "a")
+	ctx := context.Background()
+
+	if err := r.check(ctx, time.Second, 2); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	r.db.Close()
+	if err := r.check(ctx, time.Second, 2); err == nil || !r.healthy.Load() {
+		t.Errorf("expected the replica to stay healthy after one failure, got %v", err)
+	}
+	if err := r.check(ctx, time.Second, 2); err == nil || r.healthy.Load() {
+		t.Errorf("expected the replica to be ejected after two failures, got %v", err)
+	}
+
+	db, err := sql.Open(dialects[TypeSQLite].driver, filepath.Join(t.TempDir(), "b.db"))
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	defer db.Close()
+	r.db = db
+	if err := r.check(ctx, time.Second, 2); err != nil || !r.healthy.Load() || r.failures.Load() != 0 {
+		t.Errorf("expected the replica to be readmitted, got %v", err)
+	}
+}
+
+func TestRepositoryReadsFromReplicas(t *testing.T) {
+	primary := newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "primary.db"))
+	replica := newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "replica.db"))
+	r := newTestReplica(t, "replica")
+	r.db = replica.DB
+	primary.replicas = newTestReplicaSet(r)
+	repository := NewRepository(*primary)
+
+	writer := WithSession(context.Background(), "writer")
+	if err := repository.AddCrypto(writer, testCrypto("TXA", "1")); err != nil {
+		t.Fatalf("add crypto: %v", err)
+	}
+
+	if _, err := repository.GetSingleCrypto(writer, "TXA"); err != nil {
+		t.Errorf("expected the writer to read its write from the primary, got %v", err)
+	}
+	if _, err := repository.GetSingleCrypto(WithSession(context.Background(), "reader"), "TXA"); !errors.Is(err, ErrCryptoNotFound) {
+		t.Errorf("expected another session to read from the replica, got %v", err)
+	}
+	err := repository.WithTx(WithSession(context.Background(), "reader"), func(tx Repository) error {
+		_, err := tx.GetSingleCrypto(context.Background(), "TXA")
+		return err
+	})
+	if err != nil {
+		t.Errorf("expected the transaction to read from the primary, got %v", err)
+	}
+}
+
+func TestSessionMiddleware(t *testing.T) {
+	mw := SessionMiddleware()
+	serve := func(session string) (inContext, inResponse string) {
+		handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			inContext, _ = r.Context().Value(sessionKey{}).(string)
+		}))
+
+		r := httptest.NewRequest(http.MethodGet, "/api/cryptos", nil)
+		if len(session) != 0 {
+			r.Header.Set(SessionHeader, session)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return inContext, w.Header().Get(SessionHeader)
+	}
+
+	issued, response := serve("")
+	if len(issued) == 0 || issued != response {
+		t.Fatalf("expected a new session in the context and the response, got %q and %q", issued, response)
+	}
+	if other, _ := serve(""); other == issued {
+		t.Errorf("expected every request without a session to get a new one")
+	}
+	if session, response := serve(issued); session != issued || response != issued {
+		t.Errorf("expected the issued session %q to be kept, got %q and %q", issued, session, response)
+	}
+
+	forged := []string{"victim", issued + "0", "0" + issued, newSession([]byte("another key"))}
+	for _, session := range forged {
+		if got, _ := serve(session); got == session {
+			t.Errorf("expected the session %q which wasn't issued by the middleware to be replaced", session)
+		}
+	}
+}
+
+func TestReplicaSetCheckAll(t *testing.T) {
+	down := newTestReplica(t, "down")
+	down.db.Close()
+	s := newTestReplicaSet(newTestReplica(t, "a"), down, newTestReplica(t, "b"))
+
+	errs := s.checkAll(context.Background(), 1)
+	if len(errs) != 3 || errs[0] != nil || errs[1] == nil || errs[2] != nil {
+		t.Errorf("expected only the error of the replica which is down, got %v", errs)
+	}
+	if down.healthy.Load() {
+		t.Error("expected the replica which is down to be ejected")
+	}
+}
+
+func TestReplicaConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "postgres", modify: func(c *Config) { c.Replicas = []DataSource{{Host: "replica"}} }},
+		{name: "sqlite", modify: func(c *Config) { c.Type = TypeSQLite; c.Replicas = []DataSource{{Host: "replica"}} }, wantErr: true},
+		{name: "no host", modify: func(c *Config) { c.Replicas = []DataSource{{Port: "5432"}} }, wantErr: true},
+		{name: "negative read your writes", modify: func(c *Config) { c.ReplicaRouting.ReadYourWrites = -1 }, wantErr: true},
+		{name: "no health check interval", modify: func(c *Config) { c.ReplicaRouting.HealthCheckInterval = 0 }, wantErr: true},
+		{name: "no health check timeout", modify: func(c *Config) { c.ReplicaRouting.HealthCheckTimeout = 0 }, wantErr: true},
+		{name: "no failure threshold", modify: func(c *Config) { c.ReplicaRouting.FailureThreshold = 0 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
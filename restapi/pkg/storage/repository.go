@@ -2,168 +2,240 @@ package storage
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/la4ezar/restapi/internal/crypto"
-	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/internal/tenant"
 )
 
+// ErrCryptoNotFound is returned when there is no crypto with the requested CryptoID
+var ErrCryptoNotFound = errors.New("crypto not found")
+
+// ErrCryptoAlreadyExists is returned when there is already a crypto with the written CryptoID
+var ErrCryptoAlreadyExists = errors.New("crypto already exists")
+
 type Repository interface {
-	GetAllCryptos() ([]crypto.Cryptocurrency, error)
-	GetSingleCrypto(cryptoID string) (crypto.Cryptocurrency, error)
-	AddCrypto(c crypto.Cryptocurrency) error
-	UpdateCrypto(oldCryptoID string, c crypto.Cryptocurrency) error
-	RemoveCrypto(cryptoID string) error
+	GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error)
+	GetSingleCrypto(ctx context.Context, cryptoID string) (crypto.Cryptocurrency, error)
+	AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error
+	UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error
+	RemoveCrypto(ctx context.Context, cryptoID string) error
 	PingWithContext(ctx context.Context) error
+	// WithTx calls fn with a Repository which makes all its calls in a single transaction.
+	// The transaction is committed if fn returns nil and rolled back otherwise.
+	// Nested calls are atomic on their own within the outer transaction.
+	// fn must make all its calls through the Repository it is called with.
+	WithTx(ctx context.Context, fn func(Repository) error) error
 }
 
 type RepositoryImpl struct {
 	storage Storage
+	// db executes the statements, the transaction of the repository inside WithTx
+	db querier
+	tx *sql.Tx
+	// savepoints is the number of nested transactions inside tx
+	savepoints int
+	// tenantID is the tenant of tx, whose calls are scoped to it whatever their context
+	tenantID string
+}
+
+// querier executes statements on a database or in a transaction
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
 }
 
+// NewRepository returns the Repository of the storage s which is scoped to the tenant of the calls, applies
+// its settings, rounds the prices to the configured precision, retries the calls failing with transient errors
+// and cancels the calls exceeding the configured query timeouts
 func NewRepository(s Storage) Repository {
-	return &RepositoryImpl{
-		storage: s,
+	if s.Memory != nil {
+		return withTenantSettings(withPrices(withTimeouts(s.tenants, s.timeouts), s.prices))
 	}
+
+	return withTenantSettings(withPrices(withTimeouts(withRetries(&RepositoryImpl{
+		storage: s,
+		db:      s.DB,
+	}, s.retry), s.timeouts), s.prices))
 }
 
-// GetAllCryptos retrieves all cryptos from a sql.r.storage.DB
-func (r *RepositoryImpl) GetAllCryptos() ([]crypto.Cryptocurrency, error) {
-	var cryptos []crypto.Cryptocurrency
+// cryptoColumns are the columns of the cryptos joined with their authors, in the order scanCryptos scans them
+const cryptoColumns = "C.NAME, C.CRYPTOID, C.PRICE, A.CRYPTOID, A.FIRSTNAME, A.LASTNAME"
 
-	cryptoRows, err := r.storage.DB.Query("SELECT * FROM CRYPTOS.CRYPTOCURRENCIES")
+// GetAllCryptos retrieves all cryptos of the tenant with their authors in a single query
+func (r *RepositoryImpl) GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+	cryptos, err := r.reading(ctx).queryCryptos(ctx, "")
 	if err != nil {
-		return cryptos, fmt.Errorf("an error occurred while querying cryptos from DB: %v", err)
+		return cryptos, fmt.Errorf("an error occurred while querying cryptos from DB: %w", err)
 	}
-	defer func() {
-		if err := cryptoRows.Close(); err != nil {
-			log.D().WithError(err).Errorf("an error occurred while closing DB cryptos rows: %v", err)
-		}
-	}()
 
-	for cryptoRows.Next() {
-		cryptocurrency := &crypto.Cryptocurrency{}
-		if err := cryptoRows.Scan(&cryptocurrency.Name, &cryptocurrency.CryptoID, &cryptocurrency.Price); err != nil {
-			return cryptos, fmt.Errorf("an error occurred while scanning cryptos row: %v", err)
-		}
-		cryptos = append(cryptos, *cryptocurrency)
+	return cryptos, nil
+}
+
+// GetSingleCrypto retrieves single crypto of the tenant with its authors in a single query
+func (r *RepositoryImpl) GetSingleCrypto(ctx context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	cryptos, err := r.reading(ctx).queryCryptos(ctx, "AND C.CRYPTOID = $2", cryptoID)
+	if err != nil {
+		return crypto.Cryptocurrency{}, fmt.Errorf("an error occurred while querying cryptos from DB: %w", err)
 	}
-	if err := cryptoRows.Err(); err != nil {
-		return cryptos, fmt.Errorf("an error occurred while iterating cryptos rows: %v", err)
+	if len(cryptos) == 0 {
+		return crypto.Cryptocurrency{}, fmt.Errorf("an error occurred while querying cryptos from DB: %w", ErrCryptoNotFound)
 	}
-	if err := cryptoRows.Close(); err != nil {
-		return cryptos, fmt.Errorf("an error occurred while closing DB cryptos rows: %v", err)
+
+	return cryptos[0], nil
+}
+
+// reading returns the repository to read with in ctx. Outside of transactions it reads from a healthy replica
+// unless the session of ctx is pinned to the primary.
+func (r *RepositoryImpl) reading(ctx context.Context) *RepositoryImpl {
+	if r.tx != nil || r.storage.replicas == nil {
+		return r
 	}
 
-	authorsRows, err := r.storage.DB.Query("SELECT * FROM CRYPTOS.AUTHORS")
-	if err != nil {
-		return cryptos, fmt.Errorf("an error occurred while querying authors from DB: %v", err)
+	db := r.storage.replicas.reader(ctx)
+	if db == nil {
+		return r
 	}
-	defer func() {
-		if err := authorsRows.Close(); err != nil {
-			log.D().WithError(err).Errorf("an error occurred while closing DB author rows: %v", err)
+	return &RepositoryImpl{storage: r.storage, db: db}
+}
+
+// tenant returns the tenant which the calls in ctx are scoped to
+func (r *RepositoryImpl) tenant(ctx context.Context) string {
+	if r.tx != nil {
+		return r.tenantID
+	}
+	return tenant.IDFromContext(ctx)
+}
+
+// queryCryptos returns the cryptos of the tenant matching the condition, which follows the tenant in the
+// where clause and starts its arguments from $2, with their authors. The cryptos are left joined with
+// their authors and grouped by CryptoID in the order they are returned.
+func (r *RepositoryImpl) queryCryptos(ctx context.Context, condition string, args ...interface{}) ([]crypto.Cryptocurrency, error) {
+	var cryptos []crypto.Cryptocurrency
+	indexes := make(map[string]int)
+
+	query := fmt.Sprintf("SELECT %s FROM %s C LEFT JOIN %s A ON A.TENANTID = C.TENANTID AND A.CRYPTOID = C.CRYPTOID WHERE C.TENANTID = $1 %s",
+		cryptoColumns, r.table(cryptocurrenciesTable), r.table(authorsTable), condition)
+	err := r.query(ctx, func(rows *sql.Rows) error {
+		var c crypto.Cryptocurrency
+		var authorCryptoID, firstname, lastname sql.NullString
+		if err := rows.Scan(&c.Name, &c.CryptoID, &c.Price, &authorCryptoID, &firstname, &lastname); err != nil {
+			return err
 		}
-	}()
-	for authorsRows.Next() {
-		currentCrypto := &crypto.Cryptocurrency{}
-		author := crypto.Author{}
-		if err := authorsRows.Scan(&currentCrypto.CryptoID, &author.Firstname, &author.Lastname); err != nil {
-			return cryptos, fmt.Errorf("an error occurred while scanning authors row: %v", err)
+
+		i, ok := indexes[c.CryptoID]
+		if !ok {
+			i = len(cryptos)
+			indexes[c.CryptoID] = i
+			cryptos = append(cryptos, c)
 		}
-		for i := range cryptos {
-			if cryptos[i].CryptoID == currentCrypto.CryptoID {
-				cryptos[i].Authors = append(cryptos[i].Authors, author)
-			}
+		// A crypto without authors is joined with a single row of NULLs
+		if authorCryptoID.Valid {
+			cryptos[i].Authors = append(cryptos[i].Authors, crypto.Author{Firstname: firstname.String, Lastname: lastname.String})
 		}
-	}
-	if err := authorsRows.Err(); err != nil {
-		return cryptos, fmt.Errorf("an error occurred while iterating authors rows: %v", err)
-	}
-	if err := authorsRows.Close(); err != nil {
-		return cryptos, fmt.Errorf("an error occurred while closing DB authors rows: %v", err)
-	}
+		return nil
+	}, strings.TrimSpace(query), append([]interface{}{r.tenant(ctx)}, args...)...)
 
-	return cryptos, nil
+	return cryptos, err
 }
 
-// GetSingleCrypto retrieves single crypto from sql.r.storage.DB
-func (r *RepositoryImpl) GetSingleCrypto(cryptoID string) (crypto.Cryptocurrency, error) {
-	var cryptocurrency crypto.Cryptocurrency
+// AddCrypto inserts the crypto of the tenant and its authors in a single transaction
+func (r *RepositoryImpl) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	return r.transaction(ctx, func(tx *RepositoryImpl) error {
+		if _, err := tx.exec(ctx, fmt.Sprintf("INSERT INTO %s(TENANTID, NAME, CRYPTOID, PRICE) VALUES ($1, $2, $3, $4)", tx.table(cryptocurrenciesTable)), tx.tenantID, c.Name, c.CryptoID, c.Price); err != nil {
+			if isUniqueViolation(err) {
+				err = ErrCryptoAlreadyExists
+			}
+			return fmt.Errorf("an error occurred while inserting crypto in DB: %w", err)
+		}
 
-	if err := r.storage.DB.QueryRow("SELECT * FROM CRYPTOS.CRYPTOCURRENCIES WHERE CRYPTOID = $1",
-		cryptoID).Scan(&cryptocurrency.Name, &cryptocurrency.CryptoID, &cryptocurrency.Price); err != nil {
-		return cryptocurrency, fmt.Errorf("an error occurred while querying cryptos from DB: %v", err)
-	}
+		return tx.insertAuthors(ctx, c)
+	})
+}
 
-	// Get crypto authors
-	authorsRows, err := r.storage.DB.Query("SELECT * FROM CRYPTOS.AUTHORS WHERE CRYPTOID = $1", cryptoID)
-	if err != nil {
-		return cryptocurrency, fmt.Errorf("an error occurred while querying authors from DB: %v", err)
-	}
-	defer func() {
-		if err := authorsRows.Close(); err != nil {
-			log.D().WithError(err).Errorf("an error occurred while closing DB author rows: %v", err)
+// UpdateCrypto updates the crypto of the tenant, renaming its authors with it if the CryptoID changes,
+// and replaces its authors in a single transaction
+func (r *RepositoryImpl) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	return r.transaction(ctx, func(tx *RepositoryImpl) error {
+		result, err := tx.exec(ctx, fmt.Sprintf("UPDATE %s SET NAME = $1, CRYPTOID = $2, PRICE = $3 WHERE TENANTID = $4 AND CRYPTOID = $5", tx.table(cryptocurrenciesTable)), c.Name, c.CryptoID, c.Price, tx.tenantID, oldCryptoID)
+		if err != nil {
+			if isUniqueViolation(err) {
+				err = ErrCryptoAlreadyExists
+			}
+			return fmt.Errorf("an error occurred while updating crypto in DB: %w", err)
 		}
-	}()
-	for authorsRows.Next() {
-		currentCrypto := &crypto.Cryptocurrency{}
-		author := crypto.Author{}
-		if err := authorsRows.Scan(&currentCrypto.CryptoID, &author.Firstname, &author.Lastname); err != nil {
-			return cryptocurrency, fmt.Errorf("an error occurred while scanning authors row: %v", err)
+		if err := expectAffected(result); err != nil {
+			return fmt.Errorf("an error occurred while updating crypto in DB: %w", err)
 		}
-		if cryptocurrency.CryptoID == currentCrypto.CryptoID {
-			cryptocurrency.Authors = append(cryptocurrency.Authors, author)
+
+		// The authors were renamed with the crypto by the ON UPDATE CASCADE of their foreign key
+		if _, err := tx.exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE TENANTID = $1 AND CRYPTOID = $2", tx.table(authorsTable)), tx.tenantID, c.CryptoID); err != nil {
+			return fmt.Errorf("an error occurred while deleting authors in DB: %w", err)
 		}
-	}
-	if err := authorsRows.Err(); err != nil {
-		return cryptocurrency, fmt.Errorf("an error occurred while iterating authors rows: %v", err)
-	}
-	if err := authorsRows.Close(); err != nil {
-		return cryptocurrency, fmt.Errorf("an error occurred while closing DB authors rows: %v", err)
-	}
 
-	return cryptocurrency, nil
+		return tx.insertAuthors(ctx, c)
+	})
 }
 
-func (r *RepositoryImpl) AddCrypto(c crypto.Cryptocurrency) error {
-	if _, err := r.storage.DB.Exec("INSERT INTO CRYPTOS.CRYPTOCURRENCIES(NAME, CRYPTOID, PRICE) VALUES ($1, $2, $3)", c.Name, c.CryptoID, c.Price); err != nil {
-		return fmt.Errorf("an error occurred while inserting crypto in DB: %v", err)
-	}
-
+func (r *RepositoryImpl) insertAuthors(ctx context.Context, c crypto.Cryptocurrency) error {
 	for _, a := range c.Authors {
-		if _, err := r.storage.DB.Exec("INSERT INTO CRYPTOS.AUTHORS(CRYPTOID, FIRSTNAME, LASTNAME) VALUES ($1, $2, $3)", c.CryptoID, a.Firstname, a.Lastname); err != nil {
-			return fmt.Errorf("an error occurred while inserting author in DB: %v", err)
+		if _, err := r.exec(ctx, fmt.Sprintf("INSERT INTO %s(TENANTID, CRYPTOID, FIRSTNAME, LASTNAME) VALUES ($1, $2, $3, $4)", r.table(authorsTable)), r.tenantID, c.CryptoID, a.Firstname, a.Lastname); err != nil {
+			return fmt.Errorf("an error occurred while inserting author in DB: %w", err)
 		}
 	}
 
 	return nil
 }
 
-func (r *RepositoryImpl) UpdateCrypto(oldCryptoID string, c crypto.Cryptocurrency) error {
-	if _, err := r.storage.DB.Exec("UPDATE CRYPTOS.CRYPTOCURRENCIES SET NAME = $1, CRYPTOID = $2, PRICE = $3 WHERE CRYPTOID = $4", c.Name, c.CryptoID, c.Price, oldCryptoID); err != nil {
-		return fmt.Errorf("an error occurred while updating crypto in DB: %v", err)
-	}
+// RemoveCrypto deletes the crypto of the tenant, whose authors are deleted by the cascade of their foreign key
+func (r *RepositoryImpl) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	return r.transaction(ctx, func(tx *RepositoryImpl) error {
+		result, err := tx.exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE TENANTID = $1 AND CRYPTOID = $2", tx.table(cryptocurrenciesTable)), tx.tenantID, cryptoID)
+		if err != nil {
+			return fmt.Errorf("an error occurred while deleting crypto in DB: %w", err)
+		}
+		if err := expectAffected(result); err != nil {
+			return fmt.Errorf("an error occurred while deleting crypto in DB: %w", err)
+		}
+		return nil
+	})
+}
 
-	if _, err := r.storage.DB.Exec("DELETE FROM CRYPTOS.AUTHORS WHERE CRYPTOID = $1", c.CryptoID); err != nil {
-		return fmt.Errorf("an error occurred while deleting authors in DB: %v", err)
-	}
+// table returns the name of the table in the dialect of the storage
+func (r *RepositoryImpl) table(name string) string {
+	return r.storage.dialect.table(name)
+}
 
-	for _, a := range c.Authors {
-		if _, err := r.storage.DB.Exec("INSERT INTO CRYPTOS.AUTHORS(CRYPTOID, FIRSTNAME, LASTNAME) VALUES ($1, $2, $3)", c.CryptoID, a.Firstname, a.Lastname); err != nil {
-			return fmt.Errorf("an error occurred while inserting author in DB: %v", err)
+// PingWithContext pings the database and fails if the connection pool is saturated.
+// The replicas are checked too, so that the failing ones are ejected, but don't fail the ping.
+func (r *RepositoryImpl) PingWithContext(ctx context.Context) error {
+	if r.storage.pool != nil {
+		if err := r.storage.pool.check(r.storage.DB); err != nil {
+			return err
 		}
 	}
+	if err := r.storage.DB.PingContext(ctx); err != nil {
+		return err
+	}
 
+	if r.storage.replicas != nil {
+		r.storage.replicas.ping(ctx)
+	}
 	return nil
 }
 
-func (r *RepositoryImpl) RemoveCrypto(cryptoID string) error {
-	if _, err := r.storage.DB.Exec("DELETE FROM CRYPTOS.CRYPTOCURRENCIES WHERE CRYPTOID = $1", cryptoID); err != nil {
-		return fmt.Errorf("an error occurred while deleting crypto in DB: %v", err)
+// expectAffected returns ErrCryptoNotFound if the statement didn't change any row
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrCryptoNotFound
 	}
 	return nil
 }
-
-func (r *RepositoryImpl) PingWithContext(ctx context.Context) error {
-	return r.storage.DB.PingContext(ctx)
-}
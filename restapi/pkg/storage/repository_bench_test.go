@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+)
+
+// benchmarkCryptos is the number of cryptos, each with two authors, the benchmarks query
+const benchmarkCryptos = 100000
+
+// seedBatch is the number of rows inserted by a single statement
+const seedBatch = 500
+
+// newBenchmarkRepository returns a Repository backed by a SQLite database with benchmarkCryptos cryptos
+func newBenchmarkRepository(b *testing.B) Repository {
+	b.Helper()
+
+	c := DefaultConfig()
+	c.Type = TypeSQLite
+	c.SQLite.Path = filepath.Join(b.TempDir(), "benchmark.db")
+
+	s, err := New(context.Background(), c)
+	if err != nil {
+		b.Fatalf("create storage: %v", err)
+	}
+	b.Cleanup(func() { s.Close() })
+
+	if err := seed(s, benchmarkCryptos); err != nil {
+		b.Fatalf("seed storage: %v", err)
+	}
+
+	return NewRepository(*s)
+}
+
+// seed inserts n cryptos with two authors each in batches
+func seed(s *Storage, n int) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for start := 0; start < n; start += seedBatch {
+		var cryptos, authors []string
+		var cryptoArgs, authorArgs []interface{}
+		for i := start; i < start+seedBatch && i < n; i++ {
+			cryptoID := benchmarkCryptoID(i)
+			cryptos = append(cryptos, "(?, ?, ?, ?)")
+			cryptoArgs = append(cryptoArgs, tenant.DefaultID, fmt.Sprintf("Crypto %d", i), cryptoID, fmt.Sprintf("%d.12345678", i%1000+1))
+			authors = append(authors, "(?, ?, ?, ?)", "(?, ?, ?, ?)")
+			authorArgs = append(authorArgs, tenant.DefaultID, cryptoID, "Satoshi", "Nakamoto", tenant.DefaultID, cryptoID, "Vitalik", "Buterin")
+		}
+
+		if _, err := tx.Exec("INSERT INTO "+s.dialect.table(cryptocurrenciesTable)+"(TENANTID, NAME, CRYPTOID, PRICE) VALUES "+strings.Join(cryptos, ", "), cryptoArgs...); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO "+s.dialect.table(authorsTable)+"(TENANTID, CRYPTOID, FIRSTNAME, LASTNAME) VALUES "+strings.Join(authors, ", "), authorArgs...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func benchmarkCryptoID(i int) string {
+	return fmt.Sprintf("B%07d", i)
+}
+
+func BenchmarkGetAllCryptos(b *testing.B) {
+	r := newBenchmarkRepository(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cryptos, err := r.GetAllCryptos(ctx)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(cryptos) < benchmarkCryptos {
+			b.Fatalf("got %d cryptos, want at least %d", len(cryptos), benchmarkCryptos)
+		}
+	}
+}
+
+func BenchmarkGetSingleCrypto(b *testing.B) {
+	r := newBenchmarkRepository(b)
+	ctx := context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c, err := r.GetSingleCrypto(ctx, benchmarkCryptoID(i%benchmarkCryptos))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(c.Authors) != 2 {
+			b.Fatalf("got %d authors, want 2", len(c.Authors))
+		}
+	}
+}
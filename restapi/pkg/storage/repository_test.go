@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+// affectedResult is a sql.Result of a statement which changed affected rows
+type affectedResult struct {
+	affected int64
+	err      error
+}
+
+func (r affectedResult) LastInsertId() (int64, error) {
+	return 0, errors.New("not supported")
+}
+
+func (r affectedResult) RowsAffected() (int64, error) {
+	return r.affected, r.err
+}
+
+func TestExpectAffected(t *testing.T) {
+	errDriver := errors.New("RowsAffected not supported")
+
+	tests := []struct {
+		name    string
+		result  affectedResult
+		wantErr error
+	}{
+		{name: "changed", result: affectedResult{affected: 1}},
+		{name: "unchanged", result: affectedResult{}, wantErr: ErrCryptoNotFound},
+		{name: "unknown", result: affectedResult{err: errDriver}, wantErr: errDriver},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := expectAffected(tt.result); !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
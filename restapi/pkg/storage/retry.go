@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// RetryConfig contains the settings of retrying the repository calls which failed with a transient error
+type RetryConfig struct {
+	MaxAttempts    int           `mapstructure:"max_attempts" description:"maximum number of attempts of a repository call, 1 disables the retries"`
+	InitialBackoff time.Duration `mapstructure:"initial_backoff" description:"time to wait before the first retry, doubled after every retry"`
+	MaxBackoff     time.Duration `mapstructure:"max_backoff" description:"maximum time to wait between the retries"`
+}
+
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     time.Second,
+	}
+}
+
+func (c *RetryConfig) Validate() error {
+	var errs []error
+	if c.MaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("validate Retry settings: MaxAttempts must be at least 1"))
+	}
+	if c.InitialBackoff <= 0 {
+		errs = append(errs, fmt.Errorf("validate Retry settings: InitialBackoff missing"))
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		errs = append(errs, fmt.Errorf("validate Retry settings: MaxBackoff must not be less than InitialBackoff"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Postgres error classes and codes which are worth retrying
+const (
+	pqClassConnectionException   = "08"
+	pqClassInsufficientResources = "53"
+	pqConnectionFailed           = "08001"
+	pqConnectionRejected         = "08004"
+	pqSerializationFailure       = "40001"
+	pqDeadlockDetected           = "40P01"
+	pqAdminShutdown              = "57P01"
+	pqCannotConnectNow           = "57P03"
+)
+
+// isTransient reports whether a read failing with err is likely to succeed if it is retried
+func isTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case pqClassConnectionException, pqClassInsufficientResources:
+			return true
+		}
+		switch pqErr.Code {
+		case pqSerializationFailure, pqDeadlockDetected, pqAdminShutdown, pqCannotConnectNow:
+			return true
+		}
+		return false
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// isRetryableWrite reports whether a write failing with err was certainly not applied and is likely to succeed
+// if it is retried. A connection lost or timed out after the statement was sent, even by a COMMIT, leaves
+// the write possibly applied, so it is not retried to avoid applying it twice.
+func isRetryableWrite(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case pqSerializationFailure, pqDeadlockDetected, pqCannotConnectNow, pqConnectionFailed, pqConnectionRejected:
+			return true
+		}
+		return false
+	}
+
+	// SQLite fails the statements it can't lock the database for before applying them
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		switch sqliteErr.Code() & 0xff {
+		case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+			return true
+		}
+		return false
+	}
+
+	var opErr *net.OpError
+	return errors.Is(err, syscall.ECONNREFUSED) || (errors.As(err, &opErr) && opErr.Op == "dial")
+}
+
+// backoff returns the exponentially growing, jittered waits between attempts
+type backoff struct {
+	next time.Duration
+	max  time.Duration
+}
+
+// wait blocks for the next backoff or until ctx is done and reports whether the backoff elapsed
+func (b *backoff) wait(ctx context.Context) bool {
+	// Full jitter keeps the retries of concurrent calls apart
+	d := time.Duration(rand.Int63n(int64(b.next)) + 1)
+	if b.next *= 2; b.next > b.max {
+		b.next = b.max
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// retryRepository is a Repository which retries the reads of the wrapped repository failing with a transient error
+// and the writes which certainly weren't applied. Transactions are retried as a whole, so the calls in them
+// are not retried one by one.
+type retryRepository struct {
+	Repository
+	config RetryConfig
+}
+
+// withRetries returns Repository which retries the calls to r as configured
+func withRetries(r Repository, c *RetryConfig) Repository {
+	if c == nil || c.MaxAttempts <= 1 {
+		return r
+	}
+
+	return &retryRepository{
+		Repository: r,
+		config:     *c,
+	}
+}
+
+func (r *retryRepository) GetAllCryptos(ctx context.Context) (cryptos []crypto.Cryptocurrency, err error) {
+	err = r.retry(ctx, "GetAllCryptos", isTransient, func() error {
+		cryptos, err = r.Repository.GetAllCryptos(ctx)
+		return err
+	})
+	return cryptos, err
+}
+
+func (r *retryRepository) GetSingleCrypto(ctx context.Context, cryptoID string) (c crypto.Cryptocurrency, err error) {
+	err = r.retry(ctx, "GetSingleCrypto", isTransient, func() error {
+		c, err = r.Repository.GetSingleCrypto(ctx, cryptoID)
+		return err
+	})
+	return c, err
+}
+
+func (r *retryRepository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	return r.retry(ctx, "AddCrypto", isRetryableWrite, func() error {
+		return r.Repository.AddCrypto(ctx, c)
+	})
+}
+
+func (r *retryRepository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	return r.retry(ctx, "UpdateCrypto", isRetryableWrite, func() error {
+		return r.Repository.UpdateCrypto(ctx, oldCryptoID, c)
+	})
+}
+
+func (r *retryRepository) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	return r.retry(ctx, "RemoveCrypto", isRetryableWrite, func() error {
+		return r.Repository.RemoveCrypto(ctx, cryptoID)
+	})
+}
+
+// WithTx retries the whole transaction, so fn may be called more than once
+func (r *retryRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.retry(ctx, "WithTx", isRetryableWrite, func() error {
+		return r.Repository.WithTx(ctx, fn)
+	})
+}
+
+// retry calls fn until it succeeds, fails with an error which is not retryable, ctx is done or the attempts run out
+func (r *retryRepository) retry(ctx context.Context, method string, retryable func(error) bool, fn func() error) error {
+	b := &backoff{next: r.config.InitialBackoff, max: r.config.MaxBackoff}
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if attempt >= r.config.MaxAttempts || !retryable(err) {
+			return err
+		}
+
+		log.C(ctx).WithError(err).Warnf("Retrying %s after a transient error, attempt %d of %d", method, attempt+1, r.config.MaxAttempts)
+		if !b.wait(ctx) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+
+	"github.com/lib/pq"
+)
+
+// flakyRepository is a Repository whose calls fail with the errors in order and succeed once they run out
+type flakyRepository struct {
+	Repository
+
+	errs  []error
+	calls int
+}
+
+func (r *flakyRepository) next() error {
+	r.calls++
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func (r *flakyRepository) RemoveCrypto(_ context.Context, _ string) error {
+	return r.next()
+}
+
+func (r *flakyRepository) WithTx(_ context.Context, fn func(Repository) error) error {
+	if err := r.next(); err != nil {
+		return err
+	}
+	return fn(r)
+}
+
+func testRetryConfig() *RetryConfig {
+	return &RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+}
+
+func TestRetryableErrors(t *testing.T) {
+	busy, missingTable := sqliteErrors(t)
+
+	tests := []struct {
+		name      string
+		err       error
+		transient bool
+		write     bool
+	}{
+		{name: "nil"},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline exceeded", err: fmt.Errorf("query: %w", context.DeadlineExceeded)},
+		{name: "not found", err: ErrCryptoNotFound},
+		{name: "serialization failure", err: &pq.Error{Code: pqSerializationFailure}, transient: true, write: true},
+		{name: "deadlock", err: &pq.Error{Code: pqDeadlockDetected}, transient: true, write: true},
+		{name: "cannot connect now", err: &pq.Error{Code: pqCannotConnectNow}, transient: true, write: true},
+		{name: "connection failed", err: &pq.Error{Code: pqConnectionFailed}, transient: true, write: true},
+		{name: "connection failure", err: &pq.Error{Code: "08006"}, transient: true},
+		{name: "admin shutdown", err: &pq.Error{Code: pqAdminShutdown}, transient: true},
+		{name: "too many connections", err: &pq.Error{Code: "53300"}, transient: true},
+		{name: "unique violation", err: &pq.Error{Code: pqUniqueViolation}},
+		{name: "wrapped serialization failure", err: fmt.Errorf("an error occurred while committing transaction: %w", &pq.Error{Code: pqSerializationFailure}), transient: true, write: true},
+		{name: "sqlite busy", err: busy, transient: true, write: true},
+		{name: "sqlite missing table", err: missingTable},
+		{name: "dial refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, transient: true, write: true},
+		{name: "dial timeout", err: &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}, transient: true, write: true},
+		{name: "read timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, transient: true},
+		{name: "connection reset", err: &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}, transient: true},
+		{name: "eof", err: io.EOF, transient: true},
+		{name: "commit eof", err: fmt.Errorf("an error occurred while committing transaction: %w", io.ErrUnexpectedEOF), transient: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.transient {
+				t.Errorf("expected isTransient %t, got %t", tt.transient, got)
+			}
+			if got := isRetryableWrite(tt.err); got != tt.write {
+				t.Errorf("expected isRetryableWrite %t, got %t", tt.write, got)
+			}
+		})
+	}
+}
+
+func TestRetryRepository(t *testing.T) {
+	errTransient := &pq.Error{Code: "40001"}
+	errPermanent := &pq.Error{Code: "23505"}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantErr   error
+		wantCalls int
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "transient error", errs: []error{errTransient, errTransient}, wantCalls: 3},
+		{name: "attempts run out", errs: []error{errTransient, errTransient, errTransient, errTransient}, wantErr: errTransient, wantCalls: 3},
+		{name: "permanent error", errs: []error{errTransient, errPermanent}, wantErr: errPermanent, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaky := &flakyRepository{errs: tt.errs}
+			r := withRetries(flaky, testRetryConfig())
+
+			if err := r.RemoveCrypto(context.Background(), "BTC"); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if flaky.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, flaky.calls)
+			}
+		})
+	}
+}
+
+func TestRetryRepositoryWrites(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		call         func(r Repository) error
+		wantAttempts int
+	}{
+		{
+			name:         "read retried on a dropped connection",
+			err:          io.ErrUnexpectedEOF,
+			call:         func(r Repository) error { _, err := r.GetAllCryptos(context.Background()); return err },
+			wantAttempts: 3,
+		},
+		{
+			name:         "write not retried on a dropped connection",
+			err:          io.ErrUnexpectedEOF,
+			call:         func(r Repository) error { return r.AddCrypto(context.Background(), testCrypto("BTC", "1")) },
+			wantAttempts: 1,
+		},
+		{
+			name:         "write retried on a serialization failure",
+			err:          &pq.Error{Code: pqSerializationFailure},
+			call:         func(r Repository) error { return r.RemoveCrypto(context.Background(), "BTC") },
+			wantAttempts: 3,
+		},
+		{
+			name: "transaction not retried on a failed commit",
+			err:  fmt.Errorf("an error occurred while committing transaction: %w", syscall.ECONNRESET),
+			call: func(r Repository) error {
+				return r.WithTx(context.Background(), func(Repository) error { return nil })
+			},
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			failing := &failingRepository{err: tt.err}
+			r := withRetries(failing, &RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+			if err := tt.call(r); !errors.Is(err, tt.err) {
+				t.Errorf("expected error %v, got %v", tt.err, err)
+			}
+			if failing.attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, failing.attempts)
+			}
+		})
+	}
+}
+
+func TestRetryRepositoryStopsWhenDone(t *testing.T) {
+	errTransient := &pq.Error{Code: pqSerializationFailure}
+	flaky := &flakyRepository{errs: []error{errTransient, errTransient}}
+	r := withRetries(flaky, &RetryConfig{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := r.RemoveCrypto(ctx, "BTC"); !errors.Is(err, errTransient) {
+		t.Fatalf("expected the last error, got %v", err)
+	}
+	if flaky.calls != 1 {
+		t.Errorf("expected no retry after the context is done, got %d calls", flaky.calls)
+	}
+}
+
+func TestRetryRepositoryWithTx(t *testing.T) {
+	flaky := &flakyRepository{errs: []error{nil, &pq.Error{Code: pqSerializationFailure}}}
+	r := withRetries(flaky, testRetryConfig())
+
+	attempts := 0
+	err := r.WithTx(context.Background(), func(tx Repository) error {
+		attempts++
+		// The call in the transaction is not retried on its own
+		return tx.RemoveCrypto(context.Background(), "BTC")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the transaction to be retried as a whole, got %d attempts", attempts)
+	}
+}
+
+func TestWithRetriesDisabled(t *testing.T) {
+	flaky := &flakyRepository{}
+	if r := withRetries(flaky, &RetryConfig{MaxAttempts: 1}); r != Repository(flaky) {
+		t.Errorf("expected the repository not to be wrapped, got %T", r)
+	}
+}
+
+func TestRetryConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *RetryConfig)
+		wantErr bool
+	}{
+		{name: "default", modify: func(c *RetryConfig) {}},
+		{name: "no attempts", modify: func(c *RetryConfig) { c.MaxAttempts = 0 }, wantErr: true},
+		{name: "no backoff", modify: func(c *RetryConfig) { c.InitialBackoff = 0 }, wantErr: true},
+		{name: "max backoff less than initial", modify: func(c *RetryConfig) { c.MaxBackoff = c.InitialBackoff / 2 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultRetryConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// failingRepository is a Repository whose calls fail with err
+type failingRepository struct {
+	Repository
+	err      error
+	attempts int
+}
+
+func (r *failingRepository) GetAllCryptos(context.Context) ([]crypto.Cryptocurrency, error) {
+	r.attempts++
+	return nil, r.err
+}
+
+func (r *failingRepository) AddCrypto(context.Context, crypto.Cryptocurrency) error {
+	r.attempts++
+	return r.err
+}
+
+func (r *failingRepository) RemoveCrypto(context.Context, string) error {
+	r.attempts++
+	return r.err
+}
+
+func (r *failingRepository) WithTx(context.Context, func(Repository) error) error {
+	r.attempts++
+	return r.err
+}
+
+// sqliteErrors returns the error of writing to a SQLite database locked by another connection
+// and the error of querying a missing table
+func sqliteErrors(t *testing.T) (busy, missingTable error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "locked.db")
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		db.SetMaxOpenConns(1)
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+
+	locking, locked := open(), open()
+	if _, err := locking.Exec("BEGIN EXCLUSIVE"); err != nil {
+		t.Fatalf("lock sqlite: %v", err)
+	}
+	_, busy = locked.Exec("CREATE TABLE T (ID INTEGER)")
+	_, missingTable = locking.Exec("SELECT * FROM Missing")
+	if busy == nil || missingTable == nil {
+		t.Fatalf("expected the sqlite statements to fail, got %v and %v", busy, missingTable)
+	}
+	return busy, missingTable
+}
@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Schema is the structure of the Cryptos schema in postgres. Every element is qualified with its table
+// and the names are lower case as postgres folds them, e.g. "authors.pk_authors".
+type Schema struct {
+	// Columns have their type and whether they are nullable, e.g. "authors.firstname character varying(20) not null"
+	Columns     []string `json:"columns"`
+	Constraints []string `json:"constraints"`
+	Triggers    []string `json:"triggers"`
+	// Policies are the row level security policies, e.g. "authors.authors_tenant_isolation"
+	Policies []string `json:"policies"`
+	// Dropped are the constraints a migration drops, they are never inspected
+	Dropped []string `json:"-"`
+}
+
+// schemaMigrations are the changes of the Cryptos schema made by the migrations in migrations/postgres,
+// in order of their versions. They must be kept in sync with the migrations.
+var schemaMigrations = []Schema{
+	{
+		Columns: []string{
+			"cryptocurrencies.name character varying(20) not null",
+			"cryptocurrencies.cryptoid character varying(10) not null",
+			"cryptocurrencies.price numeric(10,2) not null",
+			"authors.cryptoid character varying(10) not null",
+			"authors.firstname character varying(20) not null",
+			"authors.lastname character varying(20) not null",
+			"cryptocurrencies_audit.name character varying(20) not null",
+			"cryptocurrencies_audit.cryptoid character varying(10) not null",
+			"cryptocurrencies_audit.price numeric(10,2) not null",
+			"cryptocurrencies_audit.doer character varying(20) not null",
+			"cryptocurrencies_audit.cryptoadditiontime date",
+		},
+		Constraints: []string{
+			"cryptocurrencies.pk_cryptocurrencies_cryptoid",
+			"cryptocurrencies.ck_cryptocurrencies_price_must_be_positive",
+			"authors.fk_authors_cryptoid",
+			"authors.pk_authors",
+			"cryptocurrencies_audit.pk_cryptocurrencies_audit_cryptoid",
+			"cryptocurrencies_audit.ck_cryptocurrencies_audit_price_must_be_positive",
+		},
+		Triggers: []string{
+			"cryptocurrencies.cryptocurrencies_insert_delete_update_trigger",
+		},
+	},
+	{
+		Triggers: []string{
+			"cryptocurrencies.cryptocurrencies_notify_change_trigger",
+			"authors.authors_notify_change_trigger",
+		},
+	},
+	{
+		Columns: []string{
+			"cryptocurrencies.price numeric(38,18) not null",
+			"cryptocurrencies_audit.price numeric(38,18) not null",
+		},
+	},
+	{
+		Columns: []string{
+			"tenants.tenantid character varying(36) not null",
+			"tenants.name character varying(50) not null",
+			"tenants.settings jsonb not null",
+			"cryptocurrencies.tenantid character varying(36) not null",
+			"authors.tenantid character varying(36) not null",
+			"cryptocurrencies_audit.tenantid character varying(36) not null",
+		},
+		Constraints: []string{
+			"tenants.pk_tenants_tenantid",
+			"cryptocurrencies.pk_cryptocurrencies",
+			"cryptocurrencies.fk_cryptocurrencies_tenantid",
+			"authors.pk_authors",
+			"authors.fk_authors_cryptoid",
+			"cryptocurrencies_audit.pk_cryptocurrencies_audit",
+		},
+		Policies: []string{
+			"cryptocurrencies.cryptocurrencies_reads",
+			"cryptocurrencies.cryptocurrencies_tenant_isolation",
+			"authors.authors_reads",
+			"authors.authors_tenant_isolation",
+			"cryptocurrencies_audit.cryptocurrencies_audit_reads",
+			"cryptocurrencies_audit.cryptocurrencies_audit_tenant_isolation",
+		},
+		Dropped: []string{
+			"cryptocurrencies.pk_cryptocurrencies_cryptoid",
+			"authors.fk_authors_cryptoid",
+			"authors.pk_authors",
+			"cryptocurrencies_audit.pk_cryptocurrencies_audit_cryptoid",
+		},
+	},
+}
+
+// ExpectedSchema returns the Cryptos schema after the migrations up to the version.
+// The columns of a migration replace the ones of the previous migrations with the same name
+// and its dropped constraints are removed before its new ones are added.
+func ExpectedSchema(version uint) Schema {
+	var schema Schema
+	for _, migration := range schemaMigrations[:min(int(version), len(schemaMigrations))] {
+		schema.Columns = slices.DeleteFunc(schema.Columns, func(column string) bool {
+			return slices.ContainsFunc(migration.Columns, func(changed string) bool {
+				return columnName(changed) == columnName(column)
+			})
+		})
+		schema.Columns = append(schema.Columns, migration.Columns...)
+		schema.Constraints = slices.DeleteFunc(schema.Constraints, func(constraint string) bool {
+			return slices.Contains(migration.Dropped, constraint)
+		})
+		schema.Constraints = append(schema.Constraints, migration.Constraints...)
+		schema.Triggers = append(schema.Triggers, migration.Triggers...)
+		schema.Policies = append(schema.Policies, migration.Policies...)
+	}
+	return schema
+}
+
+// columnName returns the qualified name of the column, e.g. "authors.firstname"
+func columnName(column string) string {
+	name, _, _ := strings.Cut(column, " ")
+	return name
+}
+
+// Diff returns the elements of the schema which are missing in live and the elements of live which are not in it
+func (s Schema) Diff(live Schema) (missing, unexpected []string) {
+	kinds := []struct {
+		kind           string
+		expected, live []string
+	}{
+		{"column", s.Columns, live.Columns},
+		{"constraint", s.Constraints, live.Constraints},
+		{"trigger", s.Triggers, live.Triggers},
+		{"policy", s.Policies, live.Policies},
+	}
+	for _, k := range kinds {
+		for _, element := range difference(k.expected, k.live) {
+			missing = append(missing, k.kind+" "+element)
+		}
+		for _, element := range difference(k.live, k.expected) {
+			unexpected = append(unexpected, k.kind+" "+element)
+		}
+	}
+	return missing, unexpected
+}
+
+// difference returns the sorted elements of a which are not in b
+func difference(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, element := range b {
+		in[element] = true
+	}
+
+	var result []string
+	for _, element := range a {
+		if !in[element] {
+			result = append(result, element)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// InspectSchema returns the live Cryptos schema of the postgres database db
+func InspectSchema(ctx context.Context, db *sql.DB) (Schema, error) {
+	var schema Schema
+	schemaName := strings.ToLower(dialects[TypePostgres].schema)
+
+	rows, err := db.QueryContext(ctx, `SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, CHARACTER_MAXIMUM_LENGTH, NUMERIC_PRECISION, NUMERIC_SCALE, IS_NULLABLE
+		FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = $1`, schemaName)
+	if err != nil {
+		return schema, fmt.Errorf("an error occurred while querying columns from DB: %v", err)
+	}
+	if err := scanAll(rows, func() error {
+		var table, column, dataType, nullable string
+		var length, precision, scale sql.NullInt64
+		if err := rows.Scan(&table, &column, &dataType, &length, &precision, &scale, &nullable); err != nil {
+			return err
+		}
+
+		switch {
+		case length.Valid:
+			dataType = fmt.Sprintf("%s(%d)", dataType, length.Int64)
+		case dataType == "numeric" && precision.Valid:
+			dataType = fmt.Sprintf("%s(%d,%d)", dataType, precision.Int64, scale.Int64)
+		}
+		column = fmt.Sprintf("%s.%s %s", table, column, dataType)
+		if nullable == "NO" {
+			column += " not null"
+		}
+		schema.Columns = append(schema.Columns, column)
+		return nil
+	}); err != nil {
+		return schema, fmt.Errorf("an error occurred while querying columns from DB: %v", err)
+	}
+
+	// The not-null constraints and the internal triggers of the foreign keys are left out
+	elements := []struct {
+		elements *[]string
+		query    string
+	}{
+		{&schema.Constraints, `SELECT CL.RELNAME, CO.CONNAME FROM PG_CATALOG.PG_CONSTRAINT CO
+			JOIN PG_CATALOG.PG_CLASS CL ON CL.OID = CO.CONRELID
+			JOIN PG_CATALOG.PG_NAMESPACE N ON N.OID = CL.RELNAMESPACE
+			WHERE N.NSPNAME = $1 AND CO.CONTYPE IN ('p', 'f', 'c', 'u')`},
+		{&schema.Triggers, `SELECT CL.RELNAME, T.TGNAME FROM PG_CATALOG.PG_TRIGGER T
+			JOIN PG_CATALOG.PG_CLASS CL ON CL.OID = T.TGRELID
+			JOIN PG_CATALOG.PG_NAMESPACE N ON N.OID = CL.RELNAMESPACE
+			WHERE N.NSPNAME = $1 AND NOT T.TGISINTERNAL`},
+		{&schema.Policies, `SELECT TABLENAME, POLICYNAME FROM PG_CATALOG.PG_POLICIES WHERE SCHEMANAME = $1`},
+	}
+	for _, e := range elements {
+		rows, err := db.QueryContext(ctx, e.query, schemaName)
+		if err != nil {
+			return schema, fmt.Errorf("an error occurred while querying schema from DB: %v", err)
+		}
+		if err := scanAll(rows, func() error {
+			var table, name string
+			if err := rows.Scan(&table, &name); err != nil {
+				return err
+			}
+			*e.elements = append(*e.elements, table+"."+name)
+			return nil
+		}); err != nil {
+			return schema, fmt.Errorf("an error occurred while querying schema from DB: %v", err)
+		}
+	}
+
+	return schema, nil
+}
+
+// tablePrivileges are the privileges the server needs on the tables of the Cryptos schema.
+// The audit table is written by the triggers of the cryptos with the privileges of the user.
+var tablePrivileges = map[string][]string{
+	cryptocurrenciesTable:    {"SELECT", "INSERT", "UPDATE", "DELETE"},
+	authorsTable:             {"SELECT", "INSERT", "UPDATE", "DELETE"},
+	"CRYPTOCURRENCIES_AUDIT": {"INSERT", "UPDATE", "DELETE"},
+	tenantsTable:             {"SELECT", "INSERT", "UPDATE", "DELETE"},
+}
+
+// MissingPrivileges returns the privileges on the Cryptos schema and its tables which the user of the postgres
+// database db lacks. The privilege to create objects in the schema is needed only to migrate it.
+// Missing tables are left out since they are reported by the schema comparison.
+func MissingPrivileges(ctx context.Context, db *sql.DB, migrate bool) ([]string, error) {
+	// The names are compared literally by HAS_SCHEMA_PRIVILEGE, so they are given folded
+	schema := strings.ToLower(dialects[TypePostgres].schema)
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, "SELECT TO_REGNAMESPACE($1) IS NOT NULL", schema).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("an error occurred while querying schema from DB: %v", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	schemaPrivileges := []string{"USAGE"}
+	if migrate {
+		schemaPrivileges = append(schemaPrivileges, "CREATE")
+	}
+
+	var missing []string
+	for _, privilege := range schemaPrivileges {
+		var granted bool
+		if err := db.QueryRowContext(ctx, "SELECT HAS_SCHEMA_PRIVILEGE($1, $2)", schema, privilege).Scan(&granted); err != nil {
+			return nil, fmt.Errorf("an error occurred while querying privileges from DB: %v", err)
+		}
+		if !granted {
+			missing = append(missing, fmt.Sprintf("%s on schema %s", privilege, schema))
+		}
+	}
+
+	tables := make([]string, 0, len(tablePrivileges))
+	for table := range tablePrivileges {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		qualified := strings.ToLower(dialects[TypePostgres].table(table))
+		if err := db.QueryRowContext(ctx, "SELECT TO_REGCLASS($1) IS NOT NULL", qualified).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("an error occurred while querying tables from DB: %v", err)
+		}
+		if !exists {
+			continue
+		}
+
+		for _, privilege := range tablePrivileges[table] {
+			var granted bool
+			if err := db.QueryRowContext(ctx, "SELECT HAS_TABLE_PRIVILEGE($1, $2)", qualified, privilege).Scan(&granted); err != nil {
+				return nil, fmt.Errorf("an error occurred while querying privileges from DB: %v", err)
+			}
+			if !granted {
+				missing = append(missing, fmt.Sprintf("%s on table %s", privilege, qualified))
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+// SchemaVersion returns the migration version applied to db of the storage type, 0 if none,
+// and whether its migration failed and left the schema dirty. Unlike the Migrator it doesn't create
+// the table of the versions when it is missing.
+func SchemaVersion(ctx context.Context, storageType string, db *sql.DB) (uint, bool, error) {
+	query := "SELECT TO_REGCLASS('schema_migrations') IS NOT NULL"
+	if storageType == TypeSQLite {
+		query = "SELECT COUNT(*) > 0 FROM SQLITE_MASTER WHERE TYPE = 'table' AND NAME = 'schema_migrations'"
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, query).Scan(&exists); err != nil {
+		return 0, false, fmt.Errorf("an error occurred while querying the schema version from DB: %v", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT VERSION, DIRTY FROM schema_migrations LIMIT 1").Scan(&version, &dirty)
+	if errors.Is(err, sql.ErrNoRows) || version < 0 {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("an error occurred while querying the schema version from DB: %v", err)
+	}
+	return uint(version), dirty, nil
+}
+
+// LatestSchemaVersion returns the version of the latest embedded migration of the storage type
+func LatestSchemaVersion(storageType string) (uint, error) {
+	source, err := iofs.New(migrations, "migrations/"+storageType)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		next, err := source.Next(version)
+		if errors.Is(err, os.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		version = next
+	}
+}
+
+// scanAll calls scan for every row and closes rows
+func scanAll(rows *sql.Rows, scan func() error) error {
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
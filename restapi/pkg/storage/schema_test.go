@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"context"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExpectedSchema(t *testing.T) {
+	if schema := ExpectedSchema(0); len(schema.Columns) != 0 || len(schema.Constraints) != 0 || len(schema.Triggers) != 0 {
+		t.Errorf("expected an empty schema before the migrations, got %+v", schema)
+	}
+
+	first, second := ExpectedSchema(1), ExpectedSchema(2)
+	if !slices.Contains(first.Columns, "authors.firstname character varying(20) not null") {
+		t.Errorf("expected the columns of the first migration, got %v", first.Columns)
+	}
+	if slices.Contains(first.Triggers, "authors.authors_notify_change_trigger") || !slices.Contains(second.Triggers, "authors.authors_notify_change_trigger") {
+		t.Errorf("expected the notify triggers only after the second migration, got %v and %v", first.Triggers, second.Triggers)
+	}
+	if later := ExpectedSchema(99); len(later.Triggers) != len(ExpectedSchema(uint(len(schemaMigrations))).Triggers) {
+		t.Errorf("expected the versions after the known migrations to have the latest schema, got %+v", later)
+	}
+}
+
+func TestExpectedSchemaReplacesColumns(t *testing.T) {
+	columns := ExpectedSchema(3).Columns
+	if !slices.Contains(columns, "cryptocurrencies.price numeric(38,18) not null") || slices.Contains(columns, "cryptocurrencies.price numeric(10,2) not null") {
+		t.Errorf("expected the widened price to replace the previous one, got %v", columns)
+	}
+	if len(columns) != len(ExpectedSchema(2).Columns) {
+		t.Errorf("expected the same number of columns after the price is widened, got %v", columns)
+	}
+}
+
+func TestSchemaMigrationsInSync(t *testing.T) {
+	latest, err := LatestSchemaVersion(TypePostgres)
+	if err != nil {
+		t.Fatalf("latest version: %v", err)
+	}
+	if int(latest) != len(schemaMigrations) {
+		t.Errorf("expected a schema change for each of the %d postgres migrations, got %d", latest, len(schemaMigrations))
+	}
+}
+
+func TestExpectedSchemaPolicies(t *testing.T) {
+	files, err := fs.Glob(migrations, "migrations/postgres/*.up.sql")
+	if err != nil {
+		t.Fatalf("glob migrations: %v", err)
+	}
+
+	policy := regexp.MustCompile(`(?i)CREATE POLICY (\w+) ON Cryptos\.(\w+)`)
+	var created []string
+	for _, file := range files {
+		b, err := fs.ReadFile(migrations, file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		for _, match := range policy.FindAllStringSubmatch(string(b), -1) {
+			created = append(created, strings.ToLower(match[2]+"."+match[1]))
+		}
+	}
+
+	expected := ExpectedSchema(uint(len(schemaMigrations))).Policies
+	slices.Sort(created)
+	slices.Sort(expected)
+	if !slices.Equal(created, expected) {
+		t.Errorf("expected the policies %v of the migrations, got %v", created, expected)
+	}
+}
+
+func TestSchemaDiff(t *testing.T) {
+	expected := Schema{
+		Columns:     []string{"authors.cryptoid character varying(10) not null", "authors.firstname character varying(20) not null"},
+		Constraints: []string{"authors.pk_authors"},
+		Triggers:    []string{"authors.authors_notify_change_trigger"},
+	}
+	live := Schema{
+		Columns:     []string{"authors.cryptoid character varying(10) not null", "authors.firstname character varying(30) not null"},
+		Constraints: []string{"authors.pk_authors", "authors.uq_authors"},
+	}
+
+	missing, unexpected := expected.Diff(live)
+	wantMissing := []string{"column authors.firstname character varying(20) not null", "trigger authors.authors_notify_change_trigger"}
+	wantUnexpected := []string{"column authors.firstname character varying(30) not null", "constraint authors.uq_authors"}
+	if !slices.Equal(missing, wantMissing) || !slices.Equal(unexpected, wantUnexpected) {
+		t.Errorf("expected missing %v and unexpected %v, got %v and %v", wantMissing, wantUnexpected, missing, unexpected)
+	}
+
+	if missing, unexpected := expected.Diff(expected); len(missing) != 0 || len(unexpected) != 0 {
+		t.Errorf("expected no difference, got %v and %v", missing, unexpected)
+	}
+}
+
+func TestLatestSchemaVersion(t *testing.T) {
+	for storageType, want := range map[string]uint{TypePostgres: 4, TypeSQLite: 3} {
+		if got, err := LatestSchemaVersion(storageType); err != nil || got != want {
+			t.Errorf("expected the latest %s version %d, got %d: %v", storageType, want, got, err)
+		}
+	}
+	if _, err := LatestSchemaVersion(TypeMemory); err == nil {
+		t.Error("expected error for the memory storage")
+	}
+}
+
+func TestSchemaVersion(t *testing.T) {
+	c := DefaultConfig()
+	c.Type = TypeSQLite
+	c.SQLite.Path = filepath.Join(t.TempDir(), "test.db")
+
+	db, err := Open(context.Background(), c)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer db.Close()
+
+	if version, dirty, err := SchemaVersion(context.Background(), TypeSQLite, db); err != nil || version != 0 || dirty {
+		t.Fatalf("expected no version before the migrations, got %d (dirty %t): %v", version, dirty, err)
+	}
+
+	m := newTestMigrator(t, c.SQLite.Path)
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if version, dirty, err := SchemaVersion(context.Background(), TypeSQLite, db); err != nil || version != 3 || dirty {
+		t.Errorf("expected version 3, got %d (dirty %t): %v", version, dirty, err)
+	}
+
+	if _, err := db.Exec("UPDATE schema_migrations SET DIRTY = 1"); err != nil {
+		t.Fatalf("mark dirty: %v", err)
+	}
+	if version, dirty, err := SchemaVersion(context.Background(), TypeSQLite, db); err != nil || version != 3 || !dirty {
+		t.Errorf("expected the dirty version 3, got %d (dirty %t): %v", version, dirty, err)
+	}
+}
+
+func TestOpenWithoutDatabase(t *testing.T) {
+	c := DefaultConfig()
+	c.Type = TypeMemory
+	if _, err := Open(context.Background(), c); err == nil {
+		t.Error("expected error for the memory storage")
+	}
+}
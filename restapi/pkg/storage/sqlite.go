@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+)
+
+// newSQLite returns Storage with the SQLite database in the configured file, which is created if missing
+func newSQLite(c *SQLiteConfig) (*Storage, error) {
+	db, err := openSQLite(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Storage{
+		DB:      db,
+		dialect: dialects[TypeSQLite],
+	}, nil
+}
+
+// openSQLite opens the SQLite database in the configured file
+func openSQLite(c *SQLiteConfig) (*sql.DB, error) {
+	// Foreign keys are disabled by default in SQLite and the cascades depend on them
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)",
+		url.PathEscape(c.Path), c.BusyTimeout.Milliseconds())
+
+	db, err := sql.Open(dialects[TypeSQLite].driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database %s: %s", c.Path, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+
+	"github.com/shopspring/decimal"
+)
+
+// newTestSQLiteStorage returns a SQLite storage in a new file with the cryptos of the migrations
+func newTestSQLiteStorage(t *testing.T, path string) *Storage {
+	t.Helper()
+
+	c := DefaultConfig()
+	c.Type = TypeSQLite
+	c.SQLite.Path = path
+
+	s, err := New(context.Background(), c)
+	if err != nil {
+		t.Fatalf("create sqlite storage: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteRepository(t *testing.T) {
+	s := newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "test.db"))
+	r := NewRepository(*s)
+
+	bitcoin, err := r.GetSingleCrypto(context.Background(), "BTC")
+	if err != nil {
+		t.Fatalf("get crypto: %v", err)
+	}
+	want := crypto.Cryptocurrency{Name: "Bitcoin", CryptoID: "BTC", Price: decimal.RequireFromString("45000.94"), Authors: []crypto.Author{{Firstname: "Satoshi", Lastname: "Nakamoto"}}}
+	if !equalCryptos([]crypto.Cryptocurrency{bitcoin}, []crypto.Cryptocurrency{want}) {
+		t.Errorf("expected the migrated %v, got %v", want, bitcoin)
+	}
+
+	hal := crypto.Author{Firstname: "Hal", Lastname: "Finney"}
+	if err := r.AddCrypto(context.Background(), testCrypto("TXA", "1", hal)); err != nil {
+		t.Fatalf("add crypto: %v", err)
+	}
+	if err := r.AddCrypto(context.Background(), testCrypto("TXA", "2")); !errors.Is(err, ErrCryptoAlreadyExists) {
+		t.Errorf("expected ErrCryptoAlreadyExists adding an existing crypto, got %v", err)
+	}
+	if err := r.UpdateCrypto(context.Background(), "TXA", testCrypto("BTC", "3")); !errors.Is(err, ErrCryptoAlreadyExists) {
+		t.Errorf("expected ErrCryptoAlreadyExists renaming to an existing crypto, got %v", err)
+	}
+	if err := r.UpdateCrypto(context.Background(), "TXA", testCrypto("TXB", "3", hal)); err != nil {
+		t.Fatalf("update crypto: %v", err)
+	}
+	if err := r.UpdateCrypto(context.Background(), "TXA", testCrypto("TXA", "3")); !errors.Is(err, ErrCryptoNotFound) {
+		t.Errorf("expected ErrCryptoNotFound updating a renamed crypto, got %v", err)
+	}
+
+	got, err := r.GetSingleCrypto(context.Background(), "TXB")
+	if err != nil {
+		t.Fatalf("get crypto: %v", err)
+	}
+	if want := testCrypto("TXB", "3", hal); !equalCryptos([]crypto.Cryptocurrency{got}, []crypto.Cryptocurrency{want}) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if err := r.RemoveCrypto(context.Background(), "TXB"); err != nil {
+		t.Fatalf("remove crypto: %v", err)
+	}
+	if err := r.RemoveCrypto(context.Background(), "TXB"); !errors.Is(err, ErrCryptoNotFound) {
+		t.Errorf("expected ErrCryptoNotFound removing a removed crypto, got %v", err)
+	}
+	if _, err := r.GetSingleCrypto(context.Background(), "TXB"); !errors.Is(err, ErrCryptoNotFound) {
+		t.Errorf("expected ErrCryptoNotFound, got %v", err)
+	}
+
+	var authors int
+	if err := s.DB.QueryRow("SELECT COUNT(*) FROM AUTHORS WHERE CRYPTOID = 'TXB'").Scan(&authors); err != nil {
+		t.Fatalf("count authors: %v", err)
+	}
+	if authors != 0 {
+		t.Errorf("expected the authors to be deleted with the crypto, got %d", authors)
+	}
+}
+
+func TestSQLiteRepositoryJoinsAuthors(t *testing.T) {
+	r := NewRepository(*newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "test.db")))
+
+	hal := crypto.Author{Firstname: "Hal", Lastname: "Finney"}
+	nick := crypto.Author{Firstname: "Nick", Lastname: "Szabo"}
+	added := []crypto.Cryptocurrency{testCrypto("TXA", "1", hal, nick), testCrypto("TXB", "2"), testCrypto("TXC", "3", nick)}
+	for _, c := range added {
+		if err := r.AddCrypto(context.Background(), c); err != nil {
+			t.Fatalf("add crypto: %v", err)
+		}
+	}
+
+	cryptos, err := r.GetAllCryptos(context.Background())
+	if err != nil {
+		t.Fatalf("get cryptos: %v", err)
+	}
+	if len(cryptos) != 6 {
+		t.Fatalf("expected every crypto once, got %v", cryptos)
+	}
+	if !equalCryptos(cryptos[3:], added) {
+		t.Errorf("expected %v, got %v", added, cryptos[3:])
+	}
+
+	for _, want := range added {
+		got, err := r.GetSingleCrypto(context.Background(), want.CryptoID)
+		if err != nil {
+			t.Fatalf("get crypto: %v", err)
+		}
+		if !equalCryptos([]crypto.Cryptocurrency{got}, []crypto.Cryptocurrency{want}) {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestSQLiteRepositoryExactPrices(t *testing.T) {
+	s := newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "test.db"))
+	s.prices = &PriceConfig{Decimals: maxPriceDecimals}
+	r := NewRepository(*s)
+
+	// The prices are beyond the precision of float64
+	prices := map[string]string{"TXA": "0.000000000000000001", "TXB": "12345678901234567.123456789"}
+	for cryptoID, price := range prices {
+		if err := r.AddCrypto(context.Background(), testCrypto(cryptoID, price)); err != nil {
+			t.Fatalf("add crypto: %v", err)
+		}
+	}
+
+	for cryptoID, price := range prices {
+		got, err := r.GetSingleCrypto(context.Background(), cryptoID)
+		if err != nil {
+			t.Fatalf("get crypto: %v", err)
+		}
+		if got.Price.String() != price {
+			t.Errorf("expected the exact price %s, got %s", price, got.Price)
+		}
+	}
+}
+
+func TestSQLiteRepositoryRejectsInvalid(t *testing.T) {
+	r := NewRepository(*newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "test.db")))
+
+	tests := []struct {
+		name   string
+		crypto crypto.Cryptocurrency
+	}{
+		{name: "name too long", crypto: crypto.Cryptocurrency{Name: "A name longer than 20", CryptoID: "TXA", Price: decimal.RequireFromString("1")}},
+		{name: "CryptoID too long", crypto: testCrypto("BTCBTCBTCBTC", "1")},
+		{name: "negative price", crypto: testCrypto("TXA", "-1")},
+		{name: "zero price", crypto: testCrypto("TXA", "0.000")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.AddCrypto(context.Background(), tt.crypto); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestSQLiteMigratesOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	if err := NewRepository(*newTestSQLiteStorage(t, path)).AddCrypto(context.Background(), testCrypto("TXA", "1")); err != nil {
+		t.Fatalf("add crypto: %v", err)
+	}
+
+	cryptos, err := NewRepository(*newTestSQLiteStorage(t, path)).GetAllCryptos(context.Background())
+	if err != nil {
+		t.Fatalf("get cryptos: %v", err)
+	}
+	if len(cryptos) != 4 {
+		t.Errorf("expected the migrated and the added cryptos after reopening, got %v", cryptos)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "postgres", modify: func(c *Config) {}},
+		{name: "memory", modify: func(c *Config) { c.Type = TypeMemory }},
+		{name: "sqlite", modify: func(c *Config) { c.Type = TypeSQLite }},
+		{name: "sqlite without path", modify: func(c *Config) { c.Type = TypeSQLite; c.SQLite.Path = "" }, wantErr: true},
+		{name: "sqlite with negative busy timeout", modify: func(c *Config) { c.Type = TypeSQLite; c.SQLite.BusyTimeout = -1 }, wantErr: true},
+		{name: "unknown type", modify: func(c *Config) { c.Type = "mysql" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
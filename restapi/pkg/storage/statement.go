@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/la4ezar/restapi/pkg/log"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/la4ezar/restapi/pkg/storage")
+
+// query executes the query in its own span and calls scan for every returned row
+func (r *RepositoryImpl) query(ctx context.Context, scan func(rows *sql.Rows) error, query string, args ...interface{}) (err error) {
+	ctx, span := r.startStatement(ctx, query)
+	defer func() { endStatement(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.C(ctx).WithError(err).Errorf("an error occurred while closing DB rows: %v", err)
+		}
+	}()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return fmt.Errorf("an error occurred while scanning row: %v", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("an error occurred while iterating rows: %w", err)
+	}
+
+	return rows.Close()
+}
+
+// queryRow executes the query in its own span and scans the first returned row in dest
+func (r *RepositoryImpl) queryRow(ctx context.Context, dest []interface{}, query string, args ...interface{}) (err error) {
+	ctx, span := r.startStatement(ctx, query)
+	defer func() { endStatement(span, err) }()
+
+	return r.db.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+// exec executes the statement in its own span. The session of ctx reads from the primary after it.
+func (r *RepositoryImpl) exec(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
+	if r.storage.replicas != nil {
+		defer r.storage.replicas.pin(ctx)
+	}
+
+	ctx, span := r.startStatement(ctx, query)
+	defer func() { endStatement(span, err) }()
+
+	return r.db.ExecContext(ctx, query, args...)
+}
+
+func (r *RepositoryImpl) startStatement(ctx context.Context, query string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, statementName(query),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			r.storage.dialect.system,
+			semconv.DBQueryText(query),
+		))
+}
+
+func endStatement(span trace.Span, err error) {
+	if err != nil && err != sql.ErrNoRows {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// statementName returns the operation and the table of the query, e.g. "SELECT CRYPTOS.AUTHORS"
+func statementName(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "SQL"
+	}
+
+	operation := strings.ToUpper(fields[0])
+	for i, field := range fields[:len(fields)-1] {
+		switch strings.ToUpper(field) {
+		case "FROM", "INTO", "UPDATE":
+			table := fields[i+1]
+			if j := strings.IndexByte(table, '('); j > 0 {
+				table = table[:j]
+			}
+			return operation + " " + table
+		}
+	}
+
+	return operation
+}
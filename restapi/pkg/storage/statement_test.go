@@ -0,0 +1,25 @@
+package storage
+
+import "testing"
+
+func TestStatementName(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "SELECT * FROM CRYPTOS.CRYPTOCURRENCIES WHERE CRYPTOID = $1", want: "SELECT CRYPTOS.CRYPTOCURRENCIES"},
+		{query: "select * from cryptos.authors", want: "SELECT cryptos.authors"},
+		{query: "INSERT INTO CRYPTOS.AUTHORS(CRYPTOID, FIRSTNAME, LASTNAME) VALUES ($1, $2, $3)", want: "INSERT CRYPTOS.AUTHORS"},
+		{query: "UPDATE CRYPTOS.CRYPTOCURRENCIES SET NAME = $1", want: "UPDATE CRYPTOS.CRYPTOCURRENCIES"},
+		{query: "DELETE FROM CRYPTOS.AUTHORS WHERE CRYPTOID = $1", want: "DELETE CRYPTOS.AUTHORS"},
+		{query: "\n\tSELECT 1", want: "SELECT"},
+		{query: "SELECT * FROM", want: "SELECT"},
+		{query: "", want: "SQL"},
+	}
+
+	for _, tt := range tests {
+		if got := statementName(tt.query); got != tt.want {
+			t.Errorf("statementName(%q): expected %q, got %q", tt.query, tt.want, got)
+		}
+	}
+}
@@ -1,35 +1,154 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"sync/atomic"
+
+	"github.com/la4ezar/restapi/pkg/log"
 
 	_ "github.com/lib/pq"
 )
 
+// Storage is either a database or, for the memory type, a MemoryRepository with the cryptos of the default tenant
 type Storage struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Memory *MemoryRepository
+
+	dialect   dialect
+	isolation sql.IsolationLevel
+	timeouts  *atomic.Pointer[QueryTimeouts]
+	retry     *RetryConfig
+	prices    *PriceConfig
+	pool      *poolMonitor
+	replicas  *replicaSet
+	snapshot  string
+	// tenants keeps the tenants of the memory type
+	tenants *memoryTenants
 }
 
+// Close closes the database or saves the memory repository to its snapshot file if configured
 func (s *Storage) Close() error {
+	if s.Memory != nil {
+		if len(s.snapshot) == 0 {
+			return nil
+		}
+		return s.Memory.Snapshot(context.Background(), s.snapshot)
+	}
+	if s.replicas != nil {
+		if err := s.replicas.close(); err != nil {
+			log.C(context.Background()).WithError(err).Error("an error occurred while closing the replicas")
+		}
+	}
 	return s.DB.Close()
 }
 
-func New(c *Config) (*Storage, error) {
-	db, err := sql.Open(c.Type, c.DataSource.String())
+// SetQueryTimeouts replaces the query timeouts of the repositories of s from their next calls on
+func (s *Storage) SetQueryTimeouts(timeouts *QueryTimeouts) {
+	s.timeouts.Store(timeouts)
+}
+
+// New returns the storage of the configured type. Databases are waited for until they answer a ping
+// or the startup timeout elapses.
+func New(ctx context.Context, c *Config) (*Storage, error) {
+	var s *Storage
+	var err error
+	switch c.Type {
+	case TypeMemory:
+		s, err = newMemory(c.Memory)
+	case TypeSQLite:
+		s, err = newSQLite(c.SQLite)
+	default:
+		s, err = newPostgres(c.DataSource)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.isolation = isolationLevels[c.IsolationLevel]
+	s.timeouts = &atomic.Pointer[QueryTimeouts]{}
+	s.timeouts.Store(c.QueryTimeouts)
+	s.retry = c.Retry
+	s.prices = c.Prices
+
+	if s.DB != nil {
+		configurePool(s.DB, c)
+		s.pool = &poolMonitor{}
+
+		if err := waitForDB(ctx, s.DB, c.StartupTimeout); err != nil {
+			s.DB.Close()
+			return nil, err
+		}
+
+		// The sqlite database is created on startup, so it is always migrated
+		if c.Type == TypeSQLite || c.AutoMigrate {
+			if err := s.migrate(ctx, c.Type, c); err != nil {
+				s.DB.Close()
+				return nil, err
+			}
+		}
+	}
+
+	if len(c.Replicas) != 0 {
+		if s.replicas, err = newReplicaSet(ctx, c.Replicas, c); err != nil {
+			s.DB.Close()
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Open opens the database of the configured SQL storage and waits for it like New, but doesn't migrate it
+func Open(ctx context.Context, c *Config) (*sql.DB, error) {
+	var db *sql.DB
+	var err error
+	switch c.Type {
+	case TypeSQLite:
+		db, err = openSQLite(c.SQLite)
+	case TypePostgres:
+		db, err = sql.Open(dialects[TypePostgres].driver, c.DataSource.DSN())
+	default:
+		return nil, fmt.Errorf("the %s storage has no database", c.Type)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("unable to open db connection: %s", err)
+		return nil, err
 	}
 
-	//err = db.Ping()
-	//if err != nil {
-	//	return nil, nil
-	//}
+	configurePool(db, c)
+	if err := waitForDB(ctx, db, c.StartupTimeout); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
 
-	log.Println("Database is up-to-date")
+// newPostgres returns Storage with the Postgres database of the data source
+func newPostgres(ds DataSource) (*Storage, error) {
+	db, err := sql.Open(dialects[TypePostgres].driver, ds.DSN())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open db connection to %s: %s", ds, err)
+	}
+
+	return &Storage{
+		DB:      db,
+		dialect: dialects[TypePostgres],
+	}, nil
+}
+
+// newMemory returns Storage with a MemoryRepository seeded from the fixture if configured
+func newMemory(c *MemoryConfig) (*Storage, error) {
+	memory := NewMemoryRepository()
+	if len(c.Fixture) != 0 {
+		if err := memory.Load(context.Background(), c.Fixture); err != nil {
+			return nil, err
+		}
+	}
 
 	return &Storage{
-		DB: db,
+		Memory:   memory,
+		snapshot: c.Snapshot,
+		tenants:  newMemoryTenants(memory),
 	}, nil
 }
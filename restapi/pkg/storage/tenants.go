@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/internal/tenant"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// ErrTenantNotFound is returned when there is no tenant with the requested ID
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// ErrTenantAlreadyExists is returned when there is already a tenant with the written ID
+var ErrTenantAlreadyExists = errors.New("tenant already exists")
+
+// ErrDefaultTenant is returned when removing the default tenant, which the calls without a tenant are made for
+var ErrDefaultTenant = errors.New("the default tenant can't be removed")
+
+// ErrTenantReadOnly is returned when writing the cryptos of a read-only tenant
+var ErrTenantReadOnly = errors.New("tenant is read-only")
+
+// tenantsTable is the table of the tenants in the Cryptos schema
+const tenantsTable = "TENANTS"
+
+// maxTenantNameLength is the length of the Name column of the tenants
+const maxTenantNameLength = 50
+
+// pqUniqueViolation is the postgres error code of a duplicate key
+const pqUniqueViolation = "23505"
+
+// TenantRepository stores the tenants. Removing a tenant removes its cryptos too.
+type TenantRepository interface {
+	GetAllTenants(ctx context.Context) ([]tenant.Tenant, error)
+	GetTenant(ctx context.Context, id string) (tenant.Tenant, error)
+	AddTenant(ctx context.Context, t tenant.Tenant) error
+	UpdateTenant(ctx context.Context, t tenant.Tenant) error
+	RemoveTenant(ctx context.Context, id string) error
+}
+
+// NewTenantRepository returns the TenantRepository of the storage s
+func NewTenantRepository(s Storage) TenantRepository {
+	if s.Memory != nil {
+		return s.tenants
+	}
+
+	return &tenantRepositoryImpl{
+		repository: &RepositoryImpl{storage: s, db: s.DB},
+	}
+}
+
+// tenantRepositoryImpl is the TenantRepository of the SQL storages
+type tenantRepositoryImpl struct {
+	repository *RepositoryImpl
+}
+
+// tenantColumns are the columns of the tenants in the order scanTenant scans them
+const tenantColumns = "TENANTID, NAME, SETTINGS"
+
+func (r *tenantRepositoryImpl) GetAllTenants(ctx context.Context) ([]tenant.Tenant, error) {
+	var tenants []tenant.Tenant
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY TENANTID", tenantColumns, r.repository.table(tenantsTable))
+	err := r.repository.query(ctx, func(rows *sql.Rows) error {
+		t, err := scanTenant(rows.Scan)
+		if err != nil {
+			return err
+		}
+		tenants = append(tenants, t)
+		return nil
+	}, query)
+	if err != nil {
+		return nil, fmt.Errorf("an error occurred while querying tenants from DB: %w", err)
+	}
+
+	return tenants, nil
+}
+
+func (r *tenantRepositoryImpl) GetTenant(ctx context.Context, id string) (tenant.Tenant, error) {
+	var t tenant.Tenant
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE TENANTID = $1", tenantColumns, r.repository.table(tenantsTable))
+	err := r.repository.query(ctx, func(rows *sql.Rows) (err error) {
+		t, err = scanTenant(rows.Scan)
+		return err
+	}, query, id)
+	if err != nil {
+		return t, fmt.Errorf("an error occurred while querying tenant from DB: %w", err)
+	}
+	if len(t.ID) == 0 {
+		return t, fmt.Errorf("an error occurred while querying tenant from DB: %w", ErrTenantNotFound)
+	}
+
+	return t, nil
+}
+
+func (r *tenantRepositoryImpl) AddTenant(ctx context.Context, t tenant.Tenant) error {
+	settings, err := encodeTenant(t)
+	if err != nil {
+		return fmt.Errorf("an error occurred while inserting tenant in DB: %w", err)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s(%s) VALUES ($1, $2, $3)", r.repository.table(tenantsTable), tenantColumns)
+	if _, err := r.repository.exec(ctx, query, t.ID, t.Name, settings); err != nil {
+		if isUniqueViolation(err) {
+			err = ErrTenantAlreadyExists
+		}
+		return fmt.Errorf("an error occurred while inserting tenant in DB: %w", err)
+	}
+
+	return nil
+}
+
+func (r *tenantRepositoryImpl) UpdateTenant(ctx context.Context, t tenant.Tenant) error {
+	settings, err := encodeTenant(t)
+	if err != nil {
+		return fmt.Errorf("an error occurred while updating tenant in DB: %w", err)
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET NAME = $1, SETTINGS = $2 WHERE TENANTID = $3", r.repository.table(tenantsTable))
+	result, err := r.repository.exec(ctx, query, t.Name, settings, t.ID)
+	if err == nil {
+		err = expectTenantAffected(result)
+	}
+	if err != nil {
+		return fmt.Errorf("an error occurred while updating tenant in DB: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveTenant deletes the tenant, whose cryptos are deleted by the cascade of their foreign key.
+// The transaction is scoped to the tenant, so the triggers of the cryptos see its rows.
+func (r *tenantRepositoryImpl) RemoveTenant(ctx context.Context, id string) error {
+	if id == tenant.DefaultID {
+		return ErrDefaultTenant
+	}
+
+	ctx = tenant.ContextWithTenant(ctx, tenant.Tenant{ID: id})
+	return r.repository.transaction(ctx, func(tx *RepositoryImpl) error {
+		result, err := tx.exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE TENANTID = $1", tx.table(tenantsTable)), id)
+		if err == nil {
+			err = expectTenantAffected(result)
+		}
+		if err != nil {
+			return fmt.Errorf("an error occurred while deleting tenant in DB: %w", err)
+		}
+		return nil
+	})
+}
+
+// scanTenant scans a row of tenantColumns
+func scanTenant(scan func(dest ...interface{}) error) (tenant.Tenant, error) {
+	var t tenant.Tenant
+	var settings []byte
+	if err := scan(&t.ID, &t.Name, &settings); err != nil {
+		return t, err
+	}
+	if err := json.Unmarshal(settings, &t.Settings); err != nil {
+		return t, fmt.Errorf("invalid settings of tenant %s: %v", t.ID, err)
+	}
+	return t, nil
+}
+
+// encodeTenant validates t and returns its settings as stored
+func encodeTenant(t tenant.Tenant) (string, error) {
+	if err := ValidateTenant(t); err != nil {
+		return "", err
+	}
+
+	settings, err := json.Marshal(t.Settings)
+	return string(settings), err
+}
+
+// ValidateTenant checks that the tenant fits in the storage and that its settings are valid
+func ValidateTenant(t tenant.Tenant) error {
+	if err := tenant.ValidateID(t.ID); err != nil {
+		return err
+	}
+	if len(t.Name) == 0 || len(t.Name) > maxTenantNameLength {
+		return fmt.Errorf("tenant name must be between 1 and %d characters", maxTenantNameLength)
+	}
+
+	if d := t.Settings.Decimals; d != nil && (*d < 0 || *d > maxPriceDecimals) {
+		return fmt.Errorf("decimals must be between 0 and %d", maxPriceDecimals)
+	}
+	for cryptoID, decimals := range t.Settings.Assets {
+		if decimals < 0 || decimals > maxPriceDecimals {
+			return fmt.Errorf("decimals of %s must be between 0 and %d", cryptoID, maxPriceDecimals)
+		}
+	}
+
+	return nil
+}
+
+// expectTenantAffected returns ErrTenantNotFound if the statement didn't change any row
+func expectTenantAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+// isUniqueViolation reports whether err is a violation of a primary key or a unique constraint
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == pqUniqueViolation
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code()
+		return code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || code == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+
+	return false
+}
+
+// settingsRepository is a Repository which applies the settings of the tenant of the calls
+type settingsRepository struct {
+	Repository
+}
+
+// withTenantSettings returns Repository which rejects the writes to r of the read-only tenants
+func withTenantSettings(r Repository) Repository {
+	return &settingsRepository{
+		Repository: r,
+	}
+}
+
+func (r *settingsRepository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	return r.Repository.AddCrypto(ctx, c)
+}
+
+func (r *settingsRepository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	return r.Repository.UpdateCrypto(ctx, oldCryptoID, c)
+}
+
+func (r *settingsRepository) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	if err := checkWritable(ctx); err != nil {
+		return err
+	}
+	return r.Repository.RemoveCrypto(ctx, cryptoID)
+}
+
+func (r *settingsRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(withTenantSettings(tx))
+	})
+}
+
+// checkWritable returns ErrTenantReadOnly if the tenant of ctx is read-only
+func checkWritable(ctx context.Context) error {
+	if t, ok := tenant.FromContext(ctx); ok && t.Settings.ReadOnly {
+		return fmt.Errorf("an error occurred while writing crypto of tenant %s: %w", t.ID, ErrTenantReadOnly)
+	}
+	return nil
+}
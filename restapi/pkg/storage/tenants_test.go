@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+)
+
+// newTestStorages returns a memory and a SQLite storage
+func newTestStorages(t *testing.T) map[string]Storage {
+	t.Helper()
+
+	storages := make(map[string]Storage)
+	for _, storageType := range []string{TypeMemory, TypeSQLite} {
+		c := DefaultConfig()
+		c.Type = storageType
+		c.SQLite.Path = filepath.Join(t.TempDir(), "test.db")
+
+		s, err := New(context.Background(), c)
+		if err != nil {
+			t.Fatalf("create %s storage: %v", storageType, err)
+		}
+		t.Cleanup(func() { s.Close() })
+
+		storages[storageType] = *s
+	}
+	return storages
+}
+
+func TestTenantRepository(t *testing.T) {
+	decimals := 2
+	acme := tenant.Tenant{ID: "acme", Name: "Acme", Settings: tenant.Settings{Decimals: &decimals}}
+
+	// The steps run in order against the same storage
+	steps := []struct {
+		name    string
+		call    func(r TenantRepository) error
+		wantErr error
+	}{
+		{name: "get missing", call: func(r TenantRepository) error { _, err := r.GetTenant(context.Background(), "acme"); return err }, wantErr: ErrTenantNotFound},
+		{name: "add", call: func(r TenantRepository) error { return r.AddTenant(context.Background(), acme) }},
+		{name: "add existing", call: func(r TenantRepository) error { return r.AddTenant(context.Background(), acme) }, wantErr: ErrTenantAlreadyExists},
+		{name: "update", call: func(r TenantRepository) error {
+			return r.UpdateTenant(context.Background(), tenant.Tenant{ID: "acme", Name: "Acme Corp", Settings: tenant.Settings{ReadOnly: true}})
+		}},
+		{name: "update missing", call: func(r TenantRepository) error {
+			return r.UpdateTenant(context.Background(), tenant.Tenant{ID: "globex", Name: "Globex"})
+		}, wantErr: ErrTenantNotFound},
+		{name: "remove default", call: func(r TenantRepository) error { return r.RemoveTenant(context.Background(), tenant.DefaultID) }, wantErr: ErrDefaultTenant},
+		{name: "remove", call: func(r TenantRepository) error { return r.RemoveTenant(context.Background(), "acme") }},
+		{name: "remove missing", call: func(r TenantRepository) error { return r.RemoveTenant(context.Background(), "acme") }, wantErr: ErrTenantNotFound},
+	}
+
+	for storageType, s := range newTestStorages(t) {
+		t.Run(storageType, func(t *testing.T) {
+			r := NewTenantRepository(s)
+			for _, step := range steps {
+				if err := step.call(r); !errors.Is(err, step.wantErr) {
+					t.Fatalf("%s: expected error %v, got %v", step.name, step.wantErr, err)
+				}
+			}
+
+			tenants, err := r.GetAllTenants(context.Background())
+			if err != nil {
+				t.Fatalf("get tenants: %v", err)
+			}
+			if len(tenants) != 1 || tenants[0].ID != tenant.DefaultID {
+				t.Errorf("expected only the default tenant, got %v", tenants)
+			}
+		})
+	}
+}
+
+func TestTenantRepositoryUpdateKeepsSettings(t *testing.T) {
+	decimals := 4
+	want := tenant.Tenant{ID: "acme", Name: "Acme", Settings: tenant.Settings{Decimals: &decimals, Assets: map[string]int{"BTC": 8}, ReadOnly: true}}
+
+	for storageType, s := range newTestStorages(t) {
+		t.Run(storageType, func(t *testing.T) {
+			r := NewTenantRepository(s)
+			if err := r.AddTenant(context.Background(), want); err != nil {
+				t.Fatalf("add tenant: %v", err)
+			}
+
+			got, err := r.GetTenant(context.Background(), "acme")
+			if err != nil {
+				t.Fatalf("get tenant: %v", err)
+			}
+			if got.Name != want.Name || got.Settings.Decimals == nil || *got.Settings.Decimals != decimals ||
+				got.Settings.Assets["BTC"] != 8 || !got.Settings.ReadOnly {
+				t.Errorf("expected tenant %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	for storageType, s := range newTestStorages(t) {
+		t.Run(storageType, func(t *testing.T) {
+			if err := NewTenantRepository(s).AddTenant(context.Background(), tenant.Tenant{ID: "acme", Name: "Acme"}); err != nil {
+				t.Fatalf("add tenant: %v", err)
+			}
+			acme := tenant.ContextWithTenant(context.Background(), tenant.Tenant{ID: "acme"})
+			r := NewRepository(s)
+
+			if err := r.AddCrypto(acme, testCrypto("TNA", "1")); err != nil {
+				t.Fatalf("add crypto: %v", err)
+			}
+			if _, err := r.GetSingleCrypto(acme, "TNA"); err != nil {
+				t.Errorf("expected the crypto of the tenant, got %v", err)
+			}
+			if _, err := r.GetSingleCrypto(context.Background(), "TNA"); !errors.Is(err, ErrCryptoNotFound) {
+				t.Errorf("expected the crypto to be hidden from the default tenant, got %v", err)
+			}
+			if err := r.RemoveCrypto(context.Background(), "TNA"); !errors.Is(err, ErrCryptoNotFound) {
+				t.Errorf("expected the default tenant not to remove the crypto, got %v", err)
+			}
+
+			if err := NewTenantRepository(s).RemoveTenant(context.Background(), "acme"); err != nil {
+				t.Fatalf("remove tenant: %v", err)
+			}
+			cryptos, _ := r.GetAllCryptos(acme)
+			if len(cryptos) != 0 {
+				t.Errorf("expected the cryptos of the removed tenant to be removed, got %v", cryptos)
+			}
+		})
+	}
+}
+
+func TestReadOnlyTenant(t *testing.T) {
+	readOnly := tenant.ContextWithTenant(context.Background(), tenant.Tenant{ID: tenant.DefaultID, Settings: tenant.Settings{ReadOnly: true}})
+
+	tests := []struct {
+		name string
+		call func(r Repository) error
+	}{
+		{name: "add", call: func(r Repository) error { return r.AddCrypto(readOnly, testCrypto("TNA", "1")) }},
+		{name: "update", call: func(r Repository) error { return r.UpdateCrypto(readOnly, "TNA", testCrypto("TNA", "2")) }},
+		{name: "remove", call: func(r Repository) error { return r.RemoveCrypto(readOnly, "TNA") }},
+		{name: "add in transaction", call: func(r Repository) error {
+			return r.WithTx(readOnly, func(tx Repository) error { return tx.AddCrypto(readOnly, testCrypto("TNB", "1")) })
+		}},
+	}
+
+	for storageType, s := range newTestStorages(t) {
+		r := NewRepository(s)
+		if err := r.AddCrypto(context.Background(), testCrypto("TNA", "1")); err != nil {
+			t.Fatalf("add crypto: %v", err)
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name+"/"+storageType, func(t *testing.T) {
+				if err := tt.call(r); !errors.Is(err, ErrTenantReadOnly) {
+					t.Errorf("expected ErrTenantReadOnly, got %v", err)
+				}
+			})
+		}
+
+		if _, err := r.GetSingleCrypto(readOnly, "TNA"); err != nil {
+			t.Errorf("expected the read-only tenant to read its cryptos, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+)
+
+// QueryTimeouts are the deadlines of the Repository calls
+type QueryTimeouts struct {
+	Default         time.Duration `mapstructure:"default" description:"deadline of the repository calls without their own, 0 for none"`
+	GetAllCryptos   time.Duration `mapstructure:"get_all_cryptos" description:"deadline of listing the cryptos, the default if 0"`
+	GetSingleCrypto time.Duration `mapstructure:"get_single_crypto" description:"deadline of getting a crypto, the default if 0"`
+	AddCrypto       time.Duration `mapstructure:"add_crypto" description:"deadline of adding a crypto, the default if 0"`
+	UpdateCrypto    time.Duration `mapstructure:"update_crypto" description:"deadline of updating a crypto, the default if 0"`
+	RemoveCrypto    time.Duration `mapstructure:"remove_crypto" description:"deadline of removing a crypto, the default if 0"`
+	Ping            time.Duration `mapstructure:"ping" description:"deadline of pinging the storage, the default if 0"`
+	Transaction     time.Duration `mapstructure:"transaction" description:"deadline of a whole transaction, the default if 0"`
+}
+
+func DefaultQueryTimeouts() *QueryTimeouts {
+	return &QueryTimeouts{
+		Default: 10 * time.Second,
+		Ping:    2 * time.Second,
+	}
+}
+
+func (t *QueryTimeouts) Validate() error {
+	timeouts := []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{"Default", t.Default},
+		{"GetAllCryptos", t.GetAllCryptos},
+		{"GetSingleCrypto", t.GetSingleCrypto},
+		{"AddCrypto", t.AddCrypto},
+		{"UpdateCrypto", t.UpdateCrypto},
+		{"RemoveCrypto", t.RemoveCrypto},
+		{"Ping", t.Ping},
+		{"Transaction", t.Transaction},
+	}
+
+	var errs []error
+	for _, timeout := range timeouts {
+		if timeout.timeout < 0 {
+			errs = append(errs, fmt.Errorf("validate QueryTimeouts settings: %s must not be negative", timeout.name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// timeoutRepository is a Repository which cancels the calls of the wrapped repository when they exceed their deadline.
+// The timeouts are loaded on every call, so that the reloaded ones apply to the next calls.
+type timeoutRepository struct {
+	Repository
+	timeouts *atomic.Pointer[QueryTimeouts]
+}
+
+// withTimeouts returns Repository which makes the calls to r with the current timeouts
+func withTimeouts(r Repository, timeouts *atomic.Pointer[QueryTimeouts]) Repository {
+	if timeouts == nil || timeouts.Load() == nil {
+		return r
+	}
+
+	return &timeoutRepository{
+		Repository: r,
+		timeouts:   timeouts,
+	}
+}
+
+func (r *timeoutRepository) GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().GetAllCryptos)
+	defer cancel()
+
+	cryptos, err := r.Repository.GetAllCryptos(ctx)
+	return cryptos, timedOut(ctx, "GetAllCryptos", timeout, err)
+}
+
+func (r *timeoutRepository) GetSingleCrypto(ctx context.Context, cryptoID string) (crypto.Cryptocurrency, error) {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().GetSingleCrypto)
+	defer cancel()
+
+	c, err := r.Repository.GetSingleCrypto(ctx, cryptoID)
+	return c, timedOut(ctx, "GetSingleCrypto", timeout, err)
+}
+
+func (r *timeoutRepository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) error {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().AddCrypto)
+	defer cancel()
+
+	return timedOut(ctx, "AddCrypto", timeout, r.Repository.AddCrypto(ctx, c))
+}
+
+func (r *timeoutRepository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) error {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().UpdateCrypto)
+	defer cancel()
+
+	return timedOut(ctx, "UpdateCrypto", timeout, r.Repository.UpdateCrypto(ctx, oldCryptoID, c))
+}
+
+func (r *timeoutRepository) RemoveCrypto(ctx context.Context, cryptoID string) error {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().RemoveCrypto)
+	defer cancel()
+
+	return timedOut(ctx, "RemoveCrypto", timeout, r.Repository.RemoveCrypto(ctx, cryptoID))
+}
+
+func (r *timeoutRepository) PingWithContext(ctx context.Context) error {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().Ping)
+	defer cancel()
+
+	return timedOut(ctx, "PingWithContext", timeout, r.Repository.PingWithContext(ctx))
+}
+
+// WithTx bounds the whole transaction with the transaction timeout and every call in it with its own timeout
+func (r *timeoutRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	ctx, cancel, timeout := r.withTimeout(ctx, r.timeouts.Load().Transaction)
+	defer cancel()
+
+	return timedOut(ctx, "WithTx", timeout, r.Repository.WithTx(ctx, func(tx Repository) error {
+		return fn(withTimeouts(tx, r.timeouts))
+	}))
+}
+
+// withTimeout returns ctx with the operation timeout or the default one if the operation has none
+func (r *timeoutRepository) withTimeout(ctx context.Context, operation time.Duration) (context.Context, context.CancelFunc, time.Duration) {
+	timeout := operation
+	if timeout == 0 {
+		timeout = r.timeouts.Load().Default
+	}
+	if timeout == 0 {
+		return ctx, func() {}, 0
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, timeout
+}
+
+// timedOut marks err with context.DeadlineExceeded if the call failed because it exceeded its timeout
+func timedOut(ctx context.Context, method string, timeout time.Duration, err error) error {
+	if err == nil || timeout == 0 || !errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s exceeded the query timeout of %s (%w): %w", method, timeout, context.DeadlineExceeded, err)
+}
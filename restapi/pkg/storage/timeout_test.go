@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+)
+
+// blockingRepository is a Repository whose calls block until their context is done
+type blockingRepository struct {
+	Repository
+}
+
+func (r *blockingRepository) GetAllCryptos(ctx context.Context) ([]crypto.Cryptocurrency, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (r *blockingRepository) GetSingleCrypto(ctx context.Context, _ string) (crypto.Cryptocurrency, error) {
+	<-ctx.Done()
+	// The drivers wrap the cancellation of the query in their own errors
+	return crypto.Cryptocurrency{}, errors.New("canceling statement due to user request")
+}
+
+func (r *blockingRepository) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return fn(r)
+}
+
+// currentTimeouts returns the timeouts as the Storage keeps them
+func currentTimeouts(timeouts QueryTimeouts) *atomic.Pointer[QueryTimeouts] {
+	current := &atomic.Pointer[QueryTimeouts]{}
+	current.Store(&timeouts)
+	return current
+}
+
+func TestTimeoutRepository(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeouts QueryTimeouts
+		call     func(ctx context.Context, r Repository) error
+		wantErr  string
+	}{
+		{
+			name:     "default",
+			timeouts: QueryTimeouts{Default: 10 * time.Millisecond},
+			call: func(ctx context.Context, r Repository) error {
+				_, err := r.GetAllCryptos(ctx)
+				return err
+			},
+		},
+		{
+			name:     "operation",
+			timeouts: QueryTimeouts{Default: time.Hour, GetSingleCrypto: 10 * time.Millisecond},
+			call: func(ctx context.Context, r Repository) error {
+				_, err := r.GetSingleCrypto(ctx, "BTC")
+				return err
+			},
+			wantErr: "GetSingleCrypto exceeded the query timeout of 10ms",
+		},
+		{
+			name:     "call in a transaction",
+			timeouts: QueryTimeouts{Default: 10 * time.Millisecond, Transaction: time.Hour},
+			call: func(ctx context.Context, r Repository) error {
+				return r.WithTx(ctx, func(tx Repository) error {
+					_, err := tx.GetSingleCrypto(ctx, "BTC")
+					return err
+				})
+			},
+			wantErr: "GetSingleCrypto exceeded the query timeout of 10ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := withTimeouts(&blockingRepository{}, currentTimeouts(tt.timeouts))
+
+			err := tt.call(context.Background(), r)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected the error to contain %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestTimeoutRepositoryKeepsShorterDeadline(t *testing.T) {
+	r := withTimeouts(&blockingRepository{}, currentTimeouts(QueryTimeouts{Default: time.Hour}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := r.GetAllCryptos(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the deadline of the caller to apply, took %s", elapsed)
+	}
+}
+
+func TestTimeoutRepositoryWithoutTimeout(t *testing.T) {
+	r := withTimeouts(&blockingRepository{}, currentTimeouts(QueryTimeouts{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := r.GetAllCryptos(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the cancellation of the caller, got %v", err)
+	}
+}
+
+func TestTimeoutRepositoryReload(t *testing.T) {
+	s := &Storage{timeouts: currentTimeouts(QueryTimeouts{Default: time.Hour})}
+	r := withTimeouts(&blockingRepository{}, s.timeouts)
+
+	s.SetQueryTimeouts(&QueryTimeouts{Default: 10 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := r.GetAllCryptos(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the reloaded timeout to apply, took %s", elapsed)
+	}
+}
+
+func TestQueryTimeoutsValidate(t *testing.T) {
+	c := DefaultConfig()
+	c.QueryTimeouts.RemoveCrypto = -time.Second
+	if err := c.Validate(); err == nil {
+		t.Error("expected error for a negative timeout")
+	}
+
+	c.QueryTimeouts = nil
+	if err := c.Validate(); err == nil {
+		t.Error("expected error without QueryTimeouts")
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+)
+
+func (r *RepositoryImpl) WithTx(ctx context.Context, fn func(Repository) error) error {
+	return r.transaction(ctx, func(tx *RepositoryImpl) error {
+		return fn(tx)
+	})
+}
+
+// transaction calls fn with a repository in a transaction with the configured isolation level, which is scoped
+// to the tenant of ctx. Inside a transaction fn is called in a savepoint instead, so a failed nested call
+// is rolled back without aborting the outer transaction.
+func (r *RepositoryImpl) transaction(ctx context.Context, fn func(tx *RepositoryImpl) error) error {
+	if r.tx != nil {
+		return r.savepoint(ctx, fn)
+	}
+
+	tx, err := r.storage.DB.BeginTx(ctx, &sql.TxOptions{Isolation: r.storage.isolation})
+	if err != nil {
+		return fmt.Errorf("an error occurred while beginning transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback(ctx, tx)
+			panic(p)
+		}
+	}()
+
+	scoped := &RepositoryImpl{storage: r.storage, db: tx, tx: tx, tenantID: tenant.IDFromContext(ctx)}
+	if r.storage.dialect.rowSecurity {
+		// The row-level security policies of postgres limit the writes to the tenant in a setting which is local
+		// to the transaction. Unlike exec, queryRow doesn't pin the session to the primary, so a transaction
+		// which only reads doesn't pin it.
+		var value string
+		if err := scoped.queryRow(ctx, []interface{}{&value}, "SELECT SET_CONFIG($1, $2, TRUE)", tenantSetting, scoped.tenantID); err != nil {
+			rollback(ctx, tx)
+			return fmt.Errorf("an error occurred while setting the tenant of transaction: %w", err)
+		}
+	}
+
+	if err := fn(scoped); err != nil {
+		rollback(ctx, tx)
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("an error occurred while committing transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *RepositoryImpl) savepoint(ctx context.Context, fn func(tx *RepositoryImpl) error) error {
+	nested := &RepositoryImpl{storage: r.storage, db: r.tx, tx: r.tx, savepoints: r.savepoints + 1, tenantID: r.tenantID}
+	name := fmt.Sprintf("SP_%d", nested.savepoints)
+
+	if _, err := r.exec(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("an error occurred while creating savepoint: %w", err)
+	}
+
+	if err := fn(nested); err != nil {
+		if _, rollbackErr := r.exec(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			log.C(ctx).WithError(rollbackErr).Error("an error occurred while rolling back to savepoint")
+		}
+		return err
+	}
+
+	if _, err := r.exec(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("an error occurred while releasing savepoint: %w", err)
+	}
+	return nil
+}
+
+func rollback(ctx context.Context, tx *sql.Tx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		log.C(ctx).WithError(err).Error("an error occurred while rolling back transaction")
+	}
+}
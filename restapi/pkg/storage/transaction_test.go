@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+)
+
+// newTestRepositories returns the Repository of a memory and of a SQLite storage. The SQLite one has the cryptos
+// of the migrations.
+func newTestRepositories(t *testing.T) map[string]Repository {
+	t.Helper()
+
+	return map[string]Repository{
+		TypeMemory: NewMemoryRepository(),
+		TypeSQLite: NewRepository(*newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "test.db"))),
+	}
+}
+
+func TestWithTxSavepoints(t *testing.T) {
+	errRollback := errors.New("rollback")
+	add := func(cryptoID string) func(tx Repository) error {
+		return func(tx Repository) error {
+			return tx.AddCrypto(context.Background(), testCrypto(cryptoID, "1"))
+		}
+	}
+
+	tests := []struct {
+		name    string
+		fn      func(tx Repository) error
+		wantErr error
+		want    []string
+	}{
+		{
+			name: "nested commit",
+			fn: func(tx Repository) error {
+				if err := add("TXA")(tx); err != nil {
+					return err
+				}
+				return tx.WithTx(context.Background(), add("TXB"))
+			},
+			want: []string{"TXA", "TXB"},
+		},
+		{
+			name: "nested rollback keeps the outer transaction",
+			fn: func(tx Repository) error {
+				if err := add("TXA")(tx); err != nil {
+					return err
+				}
+				err := tx.WithTx(context.Background(), func(nested Repository) error {
+					if err := add("TXB")(nested); err != nil {
+						return err
+					}
+					return errRollback
+				})
+				if !errors.Is(err, errRollback) {
+					return err
+				}
+				return add("TXC")(tx)
+			},
+			want: []string{"TXA", "TXC"},
+		},
+		{
+			name: "failed nested call keeps the outer transaction",
+			fn: func(tx Repository) error {
+				if err := add("TXA")(tx); err != nil {
+					return err
+				}
+				if err := tx.WithTx(context.Background(), add("TXA")); err == nil {
+					return errors.New("expected the nested add to fail")
+				}
+				return add("TXB")(tx)
+			},
+			want: []string{"TXA", "TXB"},
+		},
+		{
+			name: "deeply nested rollback",
+			fn: func(tx Repository) error {
+				return tx.WithTx(context.Background(), func(nested Repository) error {
+					if err := add("TXA")(nested); err != nil {
+						return err
+					}
+					err := nested.WithTx(context.Background(), func(deeper Repository) error {
+						if err := add("TXB")(deeper); err != nil {
+							return err
+						}
+						return deeper.WithTx(context.Background(), add("TXC"))
+					})
+					if err != nil {
+						return err
+					}
+					return errRollback
+				})
+			},
+			wantErr: errRollback,
+		},
+		{
+			name: "outer rollback discards the released savepoints",
+			fn: func(tx Repository) error {
+				if err := tx.WithTx(context.Background(), add("TXA")); err != nil {
+					return err
+				}
+				return errRollback
+			},
+			wantErr: errRollback,
+		},
+	}
+
+	for _, tt := range tests {
+		for storageType, r := range newTestRepositories(t) {
+			t.Run(tt.name+"/"+storageType, func(t *testing.T) {
+				initial, err := r.GetAllCryptos(context.Background())
+				if err != nil {
+					t.Fatalf("get cryptos: %v", err)
+				}
+				want := append(cryptoIDs(initial), tt.want...)
+
+				err = r.WithTx(context.Background(), tt.fn)
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+
+				cryptos, err := r.GetAllCryptos(context.Background())
+				if err != nil {
+					t.Fatalf("get cryptos: %v", err)
+				}
+				if got := cryptoIDs(cryptos); !slices.Equal(got, want) {
+					t.Errorf("expected cryptos %v, got %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestWithTxPanic(t *testing.T) {
+	for storageType, r := range newTestRepositories(t) {
+		t.Run(storageType, func(t *testing.T) {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Fatal("expected the panic to be propagated")
+					}
+				}()
+				_ = r.WithTx(context.Background(), func(tx Repository) error {
+					if err := tx.AddCrypto(context.Background(), testCrypto("TXA", "1")); err != nil {
+						return err
+					}
+					panic("boom")
+				})
+			}()
+
+			if _, err := r.GetSingleCrypto(context.Background(), "TXA"); !errors.Is(err, ErrCryptoNotFound) {
+				t.Errorf("expected the transaction to be rolled back, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAddCryptoIsAtomic(t *testing.T) {
+	r := NewRepository(*newTestSQLiteStorage(t, filepath.Join(t.TempDir(), "test.db")))
+
+	// The second author violates the primary key of the authors after the crypto is inserted
+	hal := crypto.Author{Firstname: "Hal", Lastname: "Finney"}
+	if err := r.AddCrypto(context.Background(), testCrypto("TXA", "1", hal, hal)); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if _, err := r.GetSingleCrypto(context.Background(), "TXA"); !errors.Is(err, ErrCryptoNotFound) {
+		t.Errorf("expected the crypto to be rolled back with its authors, got %v", err)
+	}
+}
+
+func TestIsolationLevelValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "postgres serializable", modify: func(c *Config) { c.IsolationLevel = "serializable" }},
+		{name: "unknown", modify: func(c *Config) { c.IsolationLevel = "snapshot" }, wantErr: true},
+		{name: "sqlite serializable", modify: func(c *Config) { c.Type = TypeSQLite; c.IsolationLevel = "serializable" }},
+		{name: "sqlite read committed", modify: func(c *Config) { c.Type = TypeSQLite; c.IsolationLevel = "read_committed" }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func cryptoIDs(cryptos []crypto.Cryptocurrency) []string {
+	var ids []string
+	for _, c := range cryptos {
+		ids = append(ids, c.CryptoID)
+	}
+	return ids
+}
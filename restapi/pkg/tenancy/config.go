@@ -0,0 +1,84 @@
+// Package tenancy identifies the tenants of the requests to our API
+package tenancy // import "github.com/la4ezar/restapi/pkg/tenancy
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Sources the tenant of a request is identified by
+const (
+	SourceHeader = "header"
+	SourceToken  = "token"
+	SourcePath   = "path"
+)
+
+// Config contains tenancy settings
+type Config struct {
+	Enabled    bool          `mapstructure:"enabled" description:"whether to identify the tenant of every request, otherwise all the requests are for the default tenant"`
+	Sources    []string      `mapstructure:"sources" description:"what identifies the tenant of a request: header, token or path. The present ones must agree and with token a valid token is required"`
+	Header     string        `mapstructure:"header" description:"header, or gRPC metadata, with the ID of the tenant"`
+	Claim      string        `mapstructure:"claim" description:"claim of the bearer token with the ID of the tenant"`
+	TokenKey   string        `mapstructure:"token_key" description:"HMAC key the HS256 bearer tokens are signed with"`
+	PathPrefix string        `mapstructure:"path_prefix" description:"prefix of the paths which start with the ID of the tenant, e.g. /tenants/acme/api/cryptos"`
+	Required   bool          `mapstructure:"required" description:"whether to reject the requests without a tenant instead of serving them for the default tenant"`
+	CacheTTL   time.Duration `mapstructure:"cache_ttl" description:"time the looked up tenants are cached for, 0 to look them up on every request"`
+}
+
+// DefaultConfig returns the default values for configuring the tenancy
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:    false,
+		Sources:    []string{SourceHeader},
+		Header:     "X-Tenant-ID",
+		Claim:      "tenant_id",
+		PathPrefix: "/tenants",
+		CacheTTL:   10 * time.Second,
+	}
+}
+
+// Validate validates the tenancy settings
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if len(c.Sources) == 0 {
+		errs = append(errs, fmt.Errorf("validate Tenancy settings: Sources missing"))
+	}
+	for _, source := range c.Sources {
+		switch source {
+		case SourceHeader:
+			if len(c.Header) == 0 {
+				errs = append(errs, fmt.Errorf("validate Tenancy settings: Header missing"))
+			}
+		case SourceToken:
+			if len(c.Claim) == 0 {
+				errs = append(errs, fmt.Errorf("validate Tenancy settings: Claim missing"))
+			}
+			if len(c.TokenKey) == 0 {
+				errs = append(errs, fmt.Errorf("validate Tenancy settings: TokenKey missing"))
+			}
+		case SourcePath:
+			if !strings.HasPrefix(c.PathPrefix, "/") || strings.HasSuffix(c.PathPrefix, "/") {
+				errs = append(errs, fmt.Errorf("validate Tenancy settings: PathPrefix must start and not end with /"))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("validate Tenancy settings: unknown source %s, must be %s, %s or %s", source, SourceHeader, SourceToken, SourcePath))
+		}
+	}
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("validate Tenancy settings: CacheTTL must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// uses reports whether the tenant is identified by source
+func (c *Config) uses(source string) bool {
+	return slices.Contains(c.Sources, source)
+}
@@ -0,0 +1,94 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// exemptServices are the prefixes of the gRPC methods which are served without a tenant even if one is required
+var exemptServices = []string{"/grpc.health.v1.Health/", "/grpc.reflection."}
+
+// UnaryInterceptor returns gRPC interceptor which serves every unary call for the tenant in its metadata
+func (r *Resolver) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := r.resolveCall(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor returns gRPC interceptor which serves every stream for the tenant in its metadata
+func (r *Resolver) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := r.resolveCall(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// resolveCall returns ctx of the call to the method with its tenant. The path source doesn't apply to gRPC.
+func (r *Resolver) resolveCall(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) != 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	exempt := slices.ContainsFunc(exemptServices, func(prefix string) bool {
+		return strings.HasPrefix(method, prefix)
+	})
+	ctx, err := r.resolve(ctx, identity{
+		header:        first(r.config.Header),
+		authorization: first("authorization"),
+	}, exempt)
+	if err != nil {
+		code := grpcCode(err)
+		if code == codes.Internal {
+			log.C(ctx).WithError(err).Error("an error occurred while resolving the tenant of the call")
+		}
+		return ctx, status.Error(code, err.Error())
+	}
+	return ctx, nil
+}
+
+// grpcCode returns the code of the calls the tenant of which couldn't be resolved
+func grpcCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrTenantMissing), errors.Is(err, ErrInvalidTenant):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrInvalidToken):
+		return codes.Unauthenticated
+	case errors.Is(err, ErrTenantMismatch):
+		return codes.PermissionDenied
+	case errors.Is(err, storage.ErrTenantNotFound):
+		return codes.NotFound
+	default:
+		return codes.Internal
+	}
+}
+
+// serverStream is grpc.ServerStream with the context of its tenant
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
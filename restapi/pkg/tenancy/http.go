@@ -0,0 +1,73 @@
+package tenancy
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/la4ezar/restapi/pkg/controller"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// Middleware returns http middleware which serves every request for its tenant. It must be called before
+// the request is matched to a route, since the prefix of the path with the tenant is removed.
+// The exempt paths, e.g. the health checks, are served without a tenant even if one is required.
+func (r *Resolver) Middleware(exempt ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			request := identity{
+				header:        req.Header.Get(r.config.Header),
+				authorization: req.Header.Get("Authorization"),
+			}
+			if r.config.uses(SourcePath) {
+				var path string
+				if request.path, path = r.cutPath(req.URL.Path); len(request.path) != 0 {
+					req.URL.Path, req.URL.RawPath = path, ""
+				}
+			}
+
+			ctx, err := r.resolve(req.Context(), request, slices.Contains(exempt, req.URL.Path))
+			if err != nil {
+				status := httpStatus(err)
+				if status == http.StatusInternalServerError {
+					log.C(ctx).WithError(err).Error("an error occurred while resolving the tenant of the request")
+				} else {
+					log.C(ctx).WithError(err).Warnf("Rejecting request %s %s", req.Method, req.RequestURI)
+				}
+				controller.WriteError(w, status, err.Error())
+				return
+			}
+
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	}
+}
+
+// cutPath returns the tenant in the path and the path without the prefix with the tenant,
+// e.g. acme and /api/cryptos for /tenants/acme/api/cryptos
+func (r *Resolver) cutPath(path string) (string, string) {
+	rest, ok := strings.CutPrefix(path, r.config.PathPrefix+"/")
+	if !ok {
+		return "", path
+	}
+	id, rest, _ := strings.Cut(rest, "/")
+	return id, "/" + rest
+}
+
+// httpStatus returns the status of the responses to the requests the tenant of which couldn't be resolved
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrTenantMissing), errors.Is(err, ErrInvalidTenant):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrInvalidToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrTenantMismatch):
+		return http.StatusForbidden
+	case errors.Is(err, storage.ErrTenantNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,182 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/log"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+// tenantField is the log field with the ID of the tenant of a request
+const tenantField = "tenant_id"
+
+var (
+	// ErrTenantMissing is returned when a request has no tenant and one is required
+	ErrTenantMissing = errors.New("tenant missing")
+	// ErrTenantMismatch is returned when the sources of a request identify different tenants
+	ErrTenantMismatch = errors.New("the tenants identified by the request don't match")
+	// ErrInvalidToken is returned when the bearer token of a request is not valid
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrInvalidTenant is returned when the ID of the tenant of a request is not valid
+	ErrInvalidTenant = errors.New("invalid tenant")
+)
+
+// Resolver identifies the tenants of the requests and looks them up in the storage.
+// It is a storage.TenantRepository which caches the looked up tenants and forgets the ones it writes,
+// so the writes of other instances are seen once the cached tenants expire.
+type Resolver struct {
+	storage.TenantRepository
+
+	config *Config
+	now    func() time.Time
+
+	mutex  sync.Mutex
+	cached map[string]cachedTenant
+}
+
+type cachedTenant struct {
+	tenant  tenant.Tenant
+	expires time.Time
+}
+
+// NewResolver returns Resolver of the tenants of the requests which are stored in repository
+func NewResolver(c *Config, repository storage.TenantRepository) *Resolver {
+	return &Resolver{
+		TenantRepository: repository,
+		config:           c,
+		now:              time.Now,
+		cached:           make(map[string]cachedTenant),
+	}
+}
+
+// GetTenant returns the tenant from the cache or looks it up in the storage
+func (r *Resolver) GetTenant(ctx context.Context, id string) (tenant.Tenant, error) {
+	r.mutex.Lock()
+	cached, ok := r.cached[id]
+	r.mutex.Unlock()
+	if ok && r.now().Before(cached.expires) {
+		return cached.tenant, nil
+	}
+
+	t, err := r.TenantRepository.GetTenant(ctx, id)
+	if err != nil {
+		return t, err
+	}
+
+	if r.config.CacheTTL > 0 {
+		r.mutex.Lock()
+		r.cached[id] = cachedTenant{tenant: t, expires: r.now().Add(r.config.CacheTTL)}
+		r.mutex.Unlock()
+	}
+	return t, nil
+}
+
+func (r *Resolver) UpdateTenant(ctx context.Context, t tenant.Tenant) error {
+	defer r.forget(t.ID)
+	return r.TenantRepository.UpdateTenant(ctx, t)
+}
+
+func (r *Resolver) RemoveTenant(ctx context.Context, id string) error {
+	defer r.forget(id)
+	return r.TenantRepository.RemoveTenant(ctx, id)
+}
+
+// forget removes the tenant from the cache
+func (r *Resolver) forget(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	delete(r.cached, id)
+}
+
+// identity is what identifies the tenant of a request, the sources which are not configured are ignored
+type identity struct {
+	header        string
+	authorization string
+	path          string
+}
+
+// resolve returns ctx with the tenant identified by the request and its logger with the ID of the tenant.
+// Without a tenant ctx is returned as it is, unless one is required and the request is not exempt.
+func (r *Resolver) resolve(ctx context.Context, request identity, exempt bool) (context.Context, error) {
+	id, err := r.identify(request)
+	if err != nil {
+		return ctx, err
+	}
+	if len(id) == 0 {
+		if r.config.Required && !exempt {
+			return ctx, ErrTenantMissing
+		}
+		return ctx, nil
+	}
+
+	if err := tenant.ValidateID(id); err != nil {
+		return ctx, fmt.Errorf("%w: %v", ErrInvalidTenant, err)
+	}
+	t, err := r.GetTenant(ctx, id)
+	if err != nil {
+		return ctx, err
+	}
+
+	ctx = tenant.ContextWithTenant(ctx, t)
+	return log.ContextWithLogger(ctx, log.C(ctx).WithField(tenantField, t.ID)), nil
+}
+
+// identify returns the ID of the tenant of the request, empty if it has none. With the token source the token
+// is required, so that a request can't pick any tenant by the other sources, which may only confirm it.
+func (r *Resolver) identify(request identity) (string, error) {
+	var ids []string
+	if r.config.uses(SourceHeader) && len(request.header) != 0 {
+		ids = append(ids, request.header)
+	}
+	if r.config.uses(SourcePath) && len(request.path) != 0 {
+		ids = append(ids, request.path)
+	}
+	if r.config.uses(SourceToken) {
+		if len(request.authorization) == 0 {
+			if len(ids) != 0 {
+				return "", fmt.Errorf("%w: bearer token required", ErrInvalidToken)
+			}
+			return "", nil
+		}
+		id, err := r.tokenTenant(request.authorization)
+		if err != nil {
+			return "", err
+		}
+		ids = append([]string{id}, ids...)
+	}
+
+	if len(ids) == 0 {
+		return "", nil
+	}
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			return "", ErrTenantMismatch
+		}
+	}
+	return ids[0], nil
+}
+
+// tokenTenant returns the tenant claimed by the bearer token in the authorization
+func (r *Resolver) tokenTenant(authorization string) (string, error) {
+	scheme, token, ok := strings.Cut(authorization, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", fmt.Errorf("%w: bearer token expected", ErrInvalidToken)
+	}
+
+	claims, err := tokenClaims(strings.TrimSpace(token), []byte(r.config.TokenKey), r.now())
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	id, ok := claims[r.config.Claim].(string)
+	if !ok || len(id) == 0 {
+		return "", fmt.Errorf("%w: claim %s missing", ErrInvalidToken, r.config.Claim)
+	}
+	return id, nil
+}
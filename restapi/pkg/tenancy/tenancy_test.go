@@ -0,0 +1,212 @@
+package tenancy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/la4ezar/restapi/internal/tenant"
+	"github.com/la4ezar/restapi/pkg/storage"
+)
+
+const testTokenKey = "secret"
+
+// signToken returns an HS256 token with the claims signed with key
+func signToken(t *testing.T, key string, claims map[string]interface{}) string {
+	t.Helper()
+
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("encode token: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+
+	unsigned := encode(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encode(claims)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// unsignedToken returns a token with the none algorithm and the tenant acme
+func unsignedToken(t *testing.T) string {
+	t.Helper()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"tenant_id":"acme"}`))
+	return header + "." + claims + "."
+}
+
+func bearer(t *testing.T, tenantID string) string {
+	return "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": tenantID})
+}
+
+func newTestResolver(sources ...string) *Resolver {
+	c := DefaultConfig()
+	c.Enabled = true
+	c.Sources = sources
+	c.TokenKey = testTokenKey
+	return NewResolver(c, nil)
+}
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		request identity
+		want    string
+		wantErr error
+	}{
+		{name: "none", sources: []string{SourceHeader, SourcePath, SourceToken}},
+		{name: "header", sources: []string{SourceHeader}, request: identity{header: "acme"}, want: "acme"},
+		{name: "path", sources: []string{SourcePath}, request: identity{path: "acme"}, want: "acme"},
+		{name: "header and path agree", sources: []string{SourceHeader, SourcePath}, request: identity{header: "acme", path: "acme"}, want: "acme"},
+		{name: "header and path disagree", sources: []string{SourceHeader, SourcePath}, request: identity{header: "acme", path: "globex"}, wantErr: ErrTenantMismatch},
+		{name: "unconfigured sources are ignored", sources: []string{SourcePath}, request: identity{header: "globex", path: "acme", authorization: "Bearer invalid"}, want: "acme"},
+		{name: "token", sources: []string{SourceToken}, request: identity{authorization: bearer(t, "acme")}, want: "acme"},
+		{name: "header confirms token", sources: []string{SourceHeader, SourceToken}, request: identity{header: "acme", authorization: bearer(t, "acme")}, want: "acme"},
+		{name: "path confirms token", sources: []string{SourcePath, SourceToken}, request: identity{path: "acme", authorization: bearer(t, "acme")}, want: "acme"},
+		{name: "header can't override token", sources: []string{SourceHeader, SourceToken}, request: identity{header: "globex", authorization: bearer(t, "acme")}, wantErr: ErrTenantMismatch},
+		{name: "path can't override token", sources: []string{SourcePath, SourceToken}, request: identity{path: "globex", authorization: bearer(t, "acme")}, wantErr: ErrTenantMismatch},
+		{name: "header without token", sources: []string{SourceHeader, SourceToken}, request: identity{header: "acme"}, wantErr: ErrInvalidToken},
+		{name: "path without token", sources: []string{SourcePath, SourceToken}, request: identity{path: "acme"}, wantErr: ErrInvalidToken},
+		{name: "header with invalid token", sources: []string{SourceHeader, SourceToken}, request: identity{header: "acme", authorization: "Bearer invalid"}, wantErr: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newTestResolver(tt.sources...).identify(tt.request)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected tenant %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTokenTenant(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          string
+		wantErr       bool
+	}{
+		{name: "valid", authorization: bearer(t, "acme"), want: "acme"},
+		{name: "lower case scheme", authorization: "bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": "acme"}), want: "acme"},
+		{name: "not expired", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": "acme", "exp": now.Unix() + 1}), want: "acme"},
+		{name: "expired", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": "acme", "exp": now.Unix()}), wantErr: true},
+		{name: "valid since now", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": "acme", "nbf": now.Unix()}), want: "acme"},
+		{name: "not valid yet", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": "acme", "nbf": now.Unix() + 1}), wantErr: true},
+		{name: "another key", authorization: "Bearer " + signToken(t, "another", map[string]interface{}{"tenant_id": "acme"}), wantErr: true},
+		{name: "claim missing", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"sub": "acme"}), wantErr: true},
+		{name: "claim not a string", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": 1}), wantErr: true},
+		{name: "basic scheme", authorization: "Basic YWNtZTpzZWNyZXQ=", wantErr: true},
+		{name: "malformed", authorization: "Bearer a.b", wantErr: true},
+		{name: "algorithm none", authorization: "Bearer " + unsignedToken(t), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestResolver(SourceToken)
+			r.now = func() time.Time { return now }
+
+			got, err := r.tokenTenant(tt.authorization)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("expected ErrInvalidToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected tenant %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// tenantsRepository is a storage.TenantRepository with the tenants in a map
+type tenantsRepository struct {
+	storage.TenantRepository
+
+	tenants map[string]tenant.Tenant
+}
+
+func (r *tenantsRepository) GetTenant(_ context.Context, id string) (tenant.Tenant, error) {
+	t, ok := r.tenants[id]
+	if !ok {
+		return tenant.Tenant{}, storage.ErrTenantNotFound
+	}
+	return t, nil
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		header        string
+		authorization string
+		wantStatus    int
+		wantTenant    string
+		wantPath      string
+	}{
+		{name: "token", path: "/api/cryptos", authorization: bearer(t, "acme"), wantStatus: http.StatusOK, wantTenant: "acme", wantPath: "/api/cryptos"},
+		{name: "path confirms token", path: "/tenants/acme/api/cryptos", authorization: bearer(t, "acme"), wantStatus: http.StatusOK, wantTenant: "acme", wantPath: "/api/cryptos"},
+		{name: "header without token", path: "/api/cryptos", header: "acme", wantStatus: http.StatusUnauthorized},
+		{name: "path without token", path: "/tenants/acme/api/cryptos", wantStatus: http.StatusUnauthorized},
+		{name: "expired token", path: "/api/cryptos", authorization: "Bearer " + signToken(t, testTokenKey, map[string]interface{}{"tenant_id": "acme", "exp": 1}), wantStatus: http.StatusUnauthorized},
+		{name: "header mismatch", path: "/api/cryptos", header: "globex", authorization: bearer(t, "acme"), wantStatus: http.StatusForbidden},
+		{name: "path mismatch", path: "/tenants/globex/api/cryptos", authorization: bearer(t, "acme"), wantStatus: http.StatusForbidden},
+		{name: "unknown tenant", path: "/api/cryptos", authorization: bearer(t, "initech"), wantStatus: http.StatusNotFound},
+		{name: "invalid tenant", path: "/api/cryptos", authorization: bearer(t, "a/b"), wantStatus: http.StatusBadRequest},
+		{name: "missing", path: "/api/cryptos", wantStatus: http.StatusBadRequest},
+		{name: "exempt", path: "/healthz", wantStatus: http.StatusOK, wantPath: "/healthz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestResolver(SourceHeader, SourcePath, SourceToken)
+			r.config.Required = true
+			r.TenantRepository = &tenantsRepository{tenants: map[string]tenant.Tenant{"acme": {ID: "acme"}}}
+
+			var gotTenant, gotPath string
+			handler := r.Middleware("/healthz")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if t, ok := tenant.FromContext(req.Context()); ok {
+					gotTenant = t.ID
+				}
+				gotPath = req.URL.Path
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if len(tt.header) != 0 {
+				req.Header.Set(r.config.Header, tt.header)
+			}
+			if len(tt.authorization) != 0 {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body)
+			}
+			if gotTenant != tt.wantTenant || gotPath != tt.wantPath {
+				t.Errorf("expected tenant %q and path %q, got %q and %q", tt.wantTenant, tt.wantPath, gotTenant, gotPath)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tenancy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// tokenClaims returns the claims of the HS256 JSON Web Token if it is signed with key and valid at now
+func tokenClaims(token string, key []byte, now time.Time) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed token header: %v", err)
+	}
+	// The algorithm is fixed, so that the tokens can't pick a weaker one, e.g. none
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %s", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature: %v", err)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errors.New("invalid token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %v", err)
+	}
+	if exp, ok := claims["exp"].(float64); ok && now.Unix() >= int64(exp) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Unix() < int64(nbf) {
+		return nil, errors.New("token not valid yet")
+	}
+
+	return claims, nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of a token in v
+func decodeSegment(segment string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
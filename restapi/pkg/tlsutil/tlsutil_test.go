@@ -0,0 +1,64 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	if got, err := ParseVersion("1.3"); err != nil || got != tls.VersionTLS13 {
+		t.Errorf("expected TLS 1.3, got %d and error %v", got, err)
+	}
+	if _, err := ParseVersion("1.4"); err == nil {
+		t.Error("expected error for unknown version")
+	}
+}
+
+func TestParseCipherSuites(t *testing.T) {
+	ids, err := ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(ids) != 2 || ids[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 || ids[1] != tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384 {
+		t.Errorf("expected the IDs of the suites in order, got %v", ids)
+	}
+
+	if ids, err := ParseCipherSuites(nil); err != nil || ids != nil {
+		t.Errorf("expected the Go defaults, got %v and error %v", ids, err)
+	}
+	if _, err := ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"}); err == nil {
+		t.Error("expected error for insecure cipher suite")
+	}
+}
+
+func TestLoadCertPool(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := LoadCertPool(filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if _, err := LoadCertPool(empty); err == nil {
+		t.Error("expected error for file without certificates")
+	}
+}
+
+func TestClientConfig(t *testing.T) {
+	cfg, err := ClientConfig("", "", "", "restapi", false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cfg.MinVersion != tls.VersionTLS12 || cfg.ServerName != "restapi" || cfg.RootCAs != nil || len(cfg.Certificates) != 0 {
+		t.Errorf("expected TLS 1.2 with the system CAs and no client certificate, got %+v", cfg)
+	}
+
+	if _, err := ClientConfig("", filepath.Join(t.TempDir(), "client.pem"), "", "", false); err == nil {
+		t.Error("expected error for client certificate without key")
+	}
+}
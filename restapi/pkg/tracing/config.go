@@ -0,0 +1,53 @@
+// Package tracing contains the OpenTelemetry tracing of our API
+package tracing // import "github.com/la4ezar/restapi/pkg/tracing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config contains tracing settings
+type Config struct {
+	Enabled     bool          `mapstructure:"enabled" description:"whether to export traces"`
+	ServiceName string        `mapstructure:"service_name" description:"name of the service in the exported traces"`
+	Endpoint    string        `mapstructure:"endpoint" description:"host:port of the OTLP gRPC collector"`
+	Insecure    bool          `mapstructure:"insecure" description:"whether to connect to the collector without TLS"`
+	SampleRatio float64       `mapstructure:"sample_ratio" description:"ratio of the new traces which are sampled"`
+	Timeout     time.Duration `mapstructure:"timeout" description:"timeout for exporting a batch of spans"`
+}
+
+// DefaultConfig returns the default values for configuring the tracing
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:     false,
+		ServiceName: "restapi",
+		Endpoint:    "localhost:4317",
+		Insecure:    true,
+		SampleRatio: 1,
+		Timeout:     10 * time.Second,
+	}
+}
+
+// Validate validates the tracing settings
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if len(c.ServiceName) == 0 {
+		errs = append(errs, fmt.Errorf("validate Tracing settings: ServiceName missing"))
+	}
+	if len(c.Endpoint) == 0 {
+		errs = append(errs, fmt.Errorf("validate Tracing settings: Endpoint missing"))
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("validate Tracing settings: SampleRatio must be between 0 and 1"))
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("validate Tracing settings: Timeout missing"))
+	}
+
+	return errors.Join(errs...)
+}
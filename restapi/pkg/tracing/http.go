@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware returns http middleware which continues the trace from the traceparent header of the request
+// and handles the request in a server span named after the matched route
+func Middleware() func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			name := r.Method
+			attributes := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)}
+			if current := mux.CurrentRoute(r); current != nil {
+				if template, err := current.GetPathTemplate(); err == nil {
+					name = r.Method + " " + template
+					attributes = append(attributes, semconv.HTTPRoute(template))
+				}
+			}
+
+			ctx, span := tracer.Start(ctx, name,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attributes...))
+			defer span.End()
+
+			rw := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPResponseStatusCode(rw.statusCode))
+			if rw.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+			}
+		})
+	}
+}
+
+// Transport is http.RoundTripper which sends every request in a client span
+// and propagates the trace to the server with the traceparent header
+type Transport struct {
+	Base http.RoundTripper
+}
+
+// NewTransport returns Transport which sends the requests with base
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{
+		Base: base,
+	}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := tracer.Start(r.Context(), "HTTP "+r.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLFull(r.URL.String())))
+	defer span.End()
+
+	r = r.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	response, err := t.Base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(response.StatusCode))
+	if response.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", response.StatusCode))
+	}
+
+	return response, nil
+}
+
+// statusRecorder records the status code of the response
+type statusRecorder struct {
+	http.ResponseWriter
+
+	statusCode int
+}
+
+func (rw *statusRecorder) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	testTraceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testParentID = "00f067aa0ba902b7"
+)
+
+var exporter = tracetest.NewInMemoryExporter()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	os.Exit(m.Run())
+}
+
+// attributeValue returns the value of the attribute with the given key of span
+func attributeValue(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		status     int
+		wantName   string
+		wantStatus codes.Code
+	}{
+		{name: "route", path: "/api/cryptos/BTC", status: http.StatusOK, wantName: "GET /api/cryptos/{crypto_id}", wantStatus: codes.Unset},
+		{name: "client error", path: "/api/cryptos/BTC", status: http.StatusNotFound, wantName: "GET /api/cryptos/{crypto_id}", wantStatus: codes.Unset},
+		{name: "server error", path: "/api/cryptos/BTC", status: http.StatusInternalServerError, wantName: "GET /api/cryptos/{crypto_id}", wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+
+			var handlerSpan trace.SpanContext
+			r := mux.NewRouter()
+			r.Use(Middleware())
+			r.Path("/api/cryptos/{crypto_id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				handlerSpan = trace.SpanContextFromContext(r.Context())
+				w.WriteHeader(tt.status)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("traceparent", "00-"+testTraceID+"-"+testParentID+"-01")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := exporter.GetSpans()
+			if len(spans) != 1 {
+				t.Fatalf("expected 1 span, got %d", len(spans))
+			}
+			span := spans[0]
+
+			if span.Name != tt.wantName || span.SpanKind != trace.SpanKindServer {
+				t.Errorf("expected server span %q, got %s span %q", tt.wantName, span.SpanKind, span.Name)
+			}
+			if span.SpanContext.TraceID().String() != testTraceID || span.Parent.SpanID().String() != testParentID {
+				t.Errorf("expected the trace of the traceparent header, got trace %s and parent %s", span.SpanContext.TraceID(), span.Parent.SpanID())
+			}
+			if !handlerSpan.Equal(span.SpanContext) {
+				t.Error("expected the handler to run in the server span")
+			}
+			if got := attributeValue(span, semconv.HTTPResponseStatusCodeKey).AsInt64(); got != int64(tt.status) {
+				t.Errorf("expected status code attribute %d, got %d", tt.status, got)
+			}
+			if span.Status.Code != tt.wantStatus {
+				t.Errorf("expected span status %s, got %s", tt.wantStatus, span.Status.Code)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exporter.Reset()
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	response, err := (&http.Client{Transport: NewTransport(http.DefaultTransport)}).Do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	_ = response.Body.Close()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected the client and the parent spans, got %d spans", len(spans))
+	}
+	client := spans[0]
+
+	if client.Name != "HTTP GET" || client.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected HTTP GET span in the parent span, got %q in %s", client.Name, client.Parent.SpanID())
+	}
+	if want := "00-" + client.SpanContext.TraceID().String() + "-" + client.SpanContext.SpanID().String() + "-01"; traceparent != want {
+		t.Errorf("expected traceparent %q, got %q", want, traceparent)
+	}
+	if client.Status.Code != codes.Error {
+		t.Errorf("expected error status for HTTP 404, got %s", client.Status.Code)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "default", modify: func(c *Config) {}},
+		{name: "disabled without endpoint", modify: func(c *Config) { c.Endpoint = "" }},
+		{name: "enabled", modify: func(c *Config) { c.Enabled = true }},
+		{name: "without endpoint", modify: func(c *Config) { c.Enabled = true; c.Endpoint = "" }, wantErr: true},
+		{name: "without service name", modify: func(c *Config) { c.Enabled = true; c.ServiceName = "" }, wantErr: true},
+		{name: "sample ratio above 1", modify: func(c *Config) { c.Enabled = true; c.SampleRatio = 1.5 }, wantErr: true},
+		{name: "negative sample ratio", modify: func(c *Config) { c.Enabled = true; c.SampleRatio = -0.1 }, wantErr: true},
+		{name: "without timeout", modify: func(c *Config) { c.Enabled = true; c.Timeout = 0 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultConfig()
+			tt.modify(c)
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
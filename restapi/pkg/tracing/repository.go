@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// repository is a storage.Repository which makes every call of the wrapped repository in its own span
+type repository struct {
+	storage.Repository
+}
+
+// NewRepository returns storage.Repository which traces the calls to r
+func NewRepository(r storage.Repository) storage.Repository {
+	return &repository{
+		Repository: r,
+	}
+}
+
+func (r *repository) GetAllCryptos(ctx context.Context) (cryptos []crypto.Cryptocurrency, err error) {
+	ctx, span := start(ctx, "GetAllCryptos")
+	defer func() { end(span, err) }()
+
+	cryptos, err = r.Repository.GetAllCryptos(ctx)
+	span.SetAttributes(attribute.Int("cryptos.count", len(cryptos)))
+	return cryptos, err
+}
+
+func (r *repository) GetSingleCrypto(ctx context.Context, cryptoID string) (c crypto.Cryptocurrency, err error) {
+	ctx, span := start(ctx, "GetSingleCrypto", attribute.String("crypto.id", cryptoID))
+	defer func() { end(span, err) }()
+
+	return r.Repository.GetSingleCrypto(ctx, cryptoID)
+}
+
+func (r *repository) AddCrypto(ctx context.Context, c crypto.Cryptocurrency) (err error) {
+	ctx, span := start(ctx, "AddCrypto", attribute.String("crypto.id", c.CryptoID))
+	defer func() { end(span, err) }()
+
+	return r.Repository.AddCrypto(ctx, c)
+}
+
+func (r *repository) UpdateCrypto(ctx context.Context, oldCryptoID string, c crypto.Cryptocurrency) (err error) {
+	ctx, span := start(ctx, "UpdateCrypto", attribute.String("crypto.id", oldCryptoID), attribute.String("crypto.new_id", c.CryptoID))
+	defer func() { end(span, err) }()
+
+	return r.Repository.UpdateCrypto(ctx, oldCryptoID, c)
+}
+
+func (r *repository) RemoveCrypto(ctx context.Context, cryptoID string) (err error) {
+	ctx, span := start(ctx, "RemoveCrypto", attribute.String("crypto.id", cryptoID))
+	defer func() { end(span, err) }()
+
+	return r.Repository.RemoveCrypto(ctx, cryptoID)
+}
+
+func (r *repository) PingWithContext(ctx context.Context) (err error) {
+	ctx, span := start(ctx, "PingWithContext")
+	defer func() { end(span, err) }()
+
+	return r.Repository.PingWithContext(ctx)
+}
+
+// WithTx traces the transaction and the calls made in it
+func (r *repository) WithTx(ctx context.Context, fn func(storage.Repository) error) (err error) {
+	ctx, span := start(ctx, "WithTx")
+	defer func() { end(span, err) }()
+
+	return r.Repository.WithTx(ctx, func(tx storage.Repository) error {
+		return fn(NewRepository(tx))
+	})
+}
+
+func start(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "Repository."+method, trace.WithAttributes(attributes...))
+}
+
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/la4ezar/restapi/internal/crypto"
+	"github.com/la4ezar/restapi/pkg/storage"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// stubRepository is a storage.Repository which fails the reads of single cryptos with err
+type stubRepository struct {
+	storage.Repository
+
+	err error
+}
+
+func (r *stubRepository) GetAllCryptos(_ context.Context) ([]crypto.Cryptocurrency, error) {
+	return []crypto.Cryptocurrency{{CryptoID: "BTC"}, {CryptoID: "ETH"}}, nil
+}
+
+func (r *stubRepository) GetSingleCrypto(_ context.Context, _ string) (crypto.Cryptocurrency, error) {
+	return crypto.Cryptocurrency{}, r.err
+}
+
+func (r *stubRepository) WithTx(ctx context.Context, fn func(storage.Repository) error) error {
+	return fn(r)
+}
+
+func TestRepository(t *testing.T) {
+	exporter.Reset()
+	errRead := errors.New("connection refused")
+	r := NewRepository(&stubRepository{err: errRead})
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	if _, err := r.GetAllCryptos(ctx); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := r.GetSingleCrypto(ctx, "BTC"); !errors.Is(err, errRead) {
+		t.Fatalf("expected error %v, got %v", errRead, err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	for i, want := range []string{"Repository.GetAllCryptos", "Repository.GetSingleCrypto"} {
+		if spans[i].Name != want || spans[i].Parent.SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("expected span %q in the request span, got %q in %s", want, spans[i].Name, spans[i].Parent.SpanID())
+		}
+	}
+	if got := attributeValue(spans[0], "cryptos.count").AsInt64(); got != 2 {
+		t.Errorf("expected cryptos.count 2, got %d", got)
+	}
+	if got := attributeValue(spans[1], "crypto.id").AsString(); got != "BTC" {
+		t.Errorf("expected crypto.id BTC, got %s", got)
+	}
+	if spans[0].Status.Code != codes.Unset || spans[1].Status.Code != codes.Error {
+		t.Errorf("expected only the failed call to have the error status, got %v and %v", spans[0].Status.Code, spans[1].Status.Code)
+	}
+}
+
+func TestRepositoryWithTx(t *testing.T) {
+	exporter.Reset()
+	r := NewRepository(&stubRepository{})
+
+	err := r.WithTx(context.Background(), func(tx storage.Repository) error {
+		_, err := tx.GetAllCryptos(context.Background())
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "Repository.GetAllCryptos" || spans[1].Name != "Repository.WithTx" {
+		t.Fatalf("expected the call and the transaction spans, got %v", spans)
+	}
+}